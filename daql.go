@@ -1,12 +1,16 @@
 package daql
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"xelf.org/daql/dom"
 	"xelf.org/daql/mig"
+	"xelf.org/daql/qry"
 	"xelf.org/xelf/bfr"
 	"xelf.org/xelf/lit"
 	"xelf.org/xelf/mod"
@@ -29,7 +33,12 @@ func LoadProject(dir string) (*Project, error) {
 	if err != nil && err != mig.ErrNoHistory {
 		return nil, fmt.Errorf("read history: %v", err)
 	}
-	return &Project{filepath.Dir(path), reg, h, h.Curr()}, nil
+	pr := &Project{filepath.Dir(path), reg, h, h.Curr()}
+	err = qry.ReslQueries(pr.Project)
+	if err != nil {
+		return nil, fmt.Errorf("resolve queries: %v", err)
+	}
+	return pr, nil
 }
 func LoadProjectSchemas(dir string, args []string) (pr *Project, ss []*dom.Schema, err error) {
 	pr, err = LoadProject(dir)
@@ -89,6 +98,9 @@ func (pr *Project) Status(p *bfr.P) {
 	if lv != nil && chg(changes, lv.Name) != ' ' {
 		p.Fmt("\nProject renamed from %s to %s\n", lv.Name, cv.Name)
 	}
+	if hasVers(lv) && cv.Vers != lv.Vers {
+		pr.statusBreaking(p, lv.Vers)
+	}
 	if len(changes) > 0 {
 		p.Fmt("\nDeletions:\n")
 		dels := make([]string, 0, len(changes))
@@ -115,6 +127,96 @@ func SchemaPath(pr *Project, s *dom.Schema) string {
 	return pr.Dir
 }
 
+// CatalogDir returns the localization catalog directory of the project. It defaults to
+// '$project/l10n' but can be changed with the project's l10n tag. Generators that render labels
+// use a copy of projects with a lang tag annotated with the catalog of that language.
+func CatalogDir(pr *Project) string {
+	rel := "l10n"
+	if v, err := pr.Extra.Key("l10n"); err == nil {
+		if c, err := lit.ToStr(v); err == nil {
+			rel = string(c)
+		}
+	}
+	return filepath.Join(pr.Dir, rel)
+}
+
+// CatalogLangs returns the languages of all catalog files in the project catalog directory.
+func CatalogLangs(pr *Project) ([]string, error) {
+	fs, err := filepath.Glob(filepath.Join(CatalogDir(pr), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(fs))
+	for _, f := range fs {
+		res = append(res, strings.TrimSuffix(filepath.Base(f), ".json"))
+	}
+	return res, nil
+}
+
+// ReadCatalog reads the catalog file for lang. A missing file results in an empty catalog.
+func (pr *Project) ReadCatalog(lang string) (dom.Catalog, error) {
+	c := make(dom.Catalog)
+	raw, err := os.ReadFile(filepath.Join(CatalogDir(pr), lang+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(raw, &c)
+	if err != nil {
+		return nil, fmt.Errorf("read catalog %s: %v", lang, err)
+	}
+	return c, nil
+}
+
+// WriteCatalog writes the catalog file for lang and returns the file path.
+func (pr *Project) WriteCatalog(lang string, c dom.Catalog) (string, error) {
+	raw, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	dir := CatalogDir(pr)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, lang+".json")
+	return path, os.WriteFile(path, append(raw, '\n'), 0644)
+}
+
+// Annotate reads the catalog for lang and returns a copy of the project with translated labels and
+// descriptions. The loaded project itself is left unchanged.
+func (pr *Project) Annotate(lang string) (*dom.Project, error) {
+	c, err := pr.ReadCatalog(lang)
+	if err != nil {
+		return nil, err
+	}
+	return c.Annotate(pr.Project), nil
+}
+
+// Labeled returns the project annotated with the catalog of the project's lang tag and the schemas
+// of that project matching ss. It returns the loaded project and ss if the project has no lang tag.
+func (pr *Project) Labeled(ss []*dom.Schema) (*dom.Project, []*dom.Schema, error) {
+	v, err := pr.Extra.Key("lang")
+	if err != nil {
+		return pr.Project, ss, nil
+	}
+	lang, err := lit.ToStr(v)
+	if err != nil || lang == "" {
+		return pr.Project, ss, nil
+	}
+	p, err := pr.Annotate(string(lang))
+	if err != nil {
+		return nil, nil, err
+	}
+	res := make([]*dom.Schema, 0, len(ss))
+	for _, s := range ss {
+		res = append(res, p.Schema(s.Name))
+	}
+	return p, res, nil
+}
+
 func chg(cm map[string]byte, name string) byte {
 	if b, ok := cm[name]; ok {
 		delete(cm, name)
@@ -123,6 +225,23 @@ func chg(cm map[string]byte, name string) byte {
 	return ' '
 }
 
+// statusBreaking prints the breaking changes since the recorded version vers, that explain a
+// major version bump.
+func (pr *Project) statusBreaking(p *bfr.P, vers string) {
+	r, err := pr.History.Record(vers)
+	if err != nil {
+		return
+	}
+	cs := mig.Breaking(mig.DiffProjects(r.Project, pr.Project))
+	if len(cs) == 0 {
+		return
+	}
+	p.Fmt("\nBreaking changes since %s:\n", vers)
+	for _, c := range cs {
+		p.Fmt("    %s\n", c)
+	}
+}
+
 func hasVers(v *mig.Version) bool {
 	if v == nil || v.Vers == "" {
 		return false
@@ -0,0 +1,293 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// App returns an app for key or nil.
+func (p *Project) App(key string) *App {
+	if p != nil {
+		for _, a := range p.Apps {
+			if a.Name == key {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns a copy of the project pro restricted by the app's include and exclude paths and
+// with the app's extra config merged into the project extra. The dom schema is always kept unless
+// explicitly excluded. Models with removed elems get a copy of their object data without the
+// indices, order keys, refs and triggers naming those elems. Resolve returns an error if a removed
+// model or primary key is still referenced by any of the remaining models.
+func (a *App) Resolve(pro *Project) (*Project, error) {
+	if a.Project != "" && a.Project != pro.Name {
+		return nil, fmt.Errorf("app %s is part of project %s not %s", a.Name, a.Project, pro.Name)
+	}
+	var inc pathSet
+	if len(a.Include) > 0 {
+		inc = make(pathSet)
+		for _, path := range a.Include {
+			if err := checkPath(pro, path); err != nil {
+				return nil, fmt.Errorf("app %s include: %v", a.Name, err)
+			}
+			inc.add(path)
+		}
+	}
+	exc := make(pathSet)
+	for _, path := range a.Exclude {
+		if err := checkPath(pro, path); err != nil {
+			return nil, fmt.Errorf("app %s exclude: %v", a.Name, err)
+		}
+		exc.add(path)
+	}
+	res := *pro
+	res.Extra = mergeExtra(pro.Extra, a.Extra)
+	res.Schemas = make([]*Schema, 0, len(pro.Schemas))
+	res.Apps = nil
+	kept := make(map[*Model]*Model)
+	for _, s := range pro.Schemas {
+		sinc, ok := inc.incl(s.Key())
+		if !ok && s != Dom {
+			continue
+		}
+		sexc, gone := exc.excl(s.Key())
+		if gone {
+			continue
+		}
+		cs := *s
+		cs.Models = make([]*Model, 0, len(s.Models))
+		for _, m := range s.Models {
+			minc, ok := sinc.incl(m.Key())
+			if !ok {
+				continue
+			}
+			mexc, gone := sexc.excl(m.Key())
+			if gone {
+				continue
+			}
+			cm := m
+			if minc != nil || mexc != nil {
+				c := *m
+				c.Elems = make([]*Elem, 0, len(m.Elems))
+				gone := make(map[string]bool)
+				for _, el := range m.Elems {
+					key := elemPathKey(el)
+					_, ok := minc.incl(key)
+					if _, exc := mexc.excl(key); !ok || exc {
+						gone[key] = true
+						continue
+					}
+					c.Elems = append(c.Elems, el)
+				}
+				if m.Object != nil {
+					c.Object = keptObject(m.Object, gone)
+				}
+				cm = &c
+			}
+			kept[m] = cm
+			cs.Models = append(cs.Models, cm)
+		}
+		res.Schemas = append(res.Schemas, &cs)
+	}
+	if err := a.checkRefs(pro, kept); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// keptObject returns a copy of obj without the indices, order keys, refs and triggers that name
+// one of the removed elem keys in gone.
+func keptObject(obj *Object, gone map[string]bool) *Object {
+	res := &Object{}
+	for _, idx := range obj.Indices {
+		if !anyGone(idx.Keys, gone) {
+			res.Indices = append(res.Indices, idx)
+		}
+	}
+	for _, k := range obj.OrderBy {
+		if !gone[strings.ToLower(strings.TrimLeft(k, "-+"))] {
+			res.OrderBy = append(res.OrderBy, k)
+		}
+	}
+	for _, r := range obj.Refs {
+		if !gone[strings.ToLower(r.Key)] {
+			res.Refs = append(res.Refs, r)
+		}
+	}
+	for _, t := range obj.Trigs {
+		x, err := exp.Read(strings.NewReader(t.Src), "")
+		if err != nil || !namesGone(x, gone) {
+			res.Trigs = append(res.Trigs, t)
+		}
+	}
+	return res
+}
+
+func anyGone(keys []string, gone map[string]bool) bool {
+	for _, k := range keys {
+		if gone[strings.ToLower(k)] {
+			return true
+		}
+	}
+	return false
+}
+
+// namesGone returns whether a symbol path in x, like .name or $arg.name, names a removed elem.
+func namesGone(x exp.Exp, gone map[string]bool) bool {
+	switch v := x.(type) {
+	case *exp.Sym:
+		parts := strings.Split(strings.ToLower(v.Sym), ".")
+		return anyGone(parts[1:], gone)
+	case *exp.Call:
+		for _, arg := range v.Args {
+			if namesGone(arg, gone) {
+				return true
+			}
+		}
+	case *exp.Tupl:
+		for _, el := range v.Els {
+			if namesGone(el, gone) {
+				return true
+			}
+		}
+	case *exp.Tag:
+		return v.Exp != nil && namesGone(v.Exp, gone)
+	}
+	return false
+}
+
+// checkRefs returns an error if a removed model or primary key is referenced by a kept model.
+func (a *App) checkRefs(pro *Project, kept map[*Model]*Model) error {
+	rels, err := Relate(pro)
+	if err != nil {
+		return err
+	}
+	for _, s := range pro.Schemas {
+		for _, m := range s.Models {
+			cm := kept[m]
+			if cm != nil && (!hasPK(m) || hasPK(cm)) {
+				continue
+			}
+			mr := rels[m.Qualified()]
+			if mr == nil {
+				continue
+			}
+			for _, r := range mr.In {
+				if r.Via.Model != nil || cm != nil && r.B.Key != "_" {
+					continue
+				}
+				ca := kept[r.A.Model]
+				if ca == nil || ca.elem(r.A.Key) == nil {
+					continue
+				}
+				if cm != nil {
+					return fmt.Errorf("app %s: primary key of model %s removed "+
+						"but still referenced by %s", a.Name, m.Qualified(), r.A)
+				}
+				return fmt.Errorf("app %s: model %s removed but still referenced by %s",
+					a.Name, m.Qualified(), r.A)
+			}
+		}
+	}
+	return nil
+}
+
+// pathSet is a tree of lower case schema, model and element keys. A nil set in a parent node
+// selects all children.
+type pathSet map[string]pathSet
+
+// add inserts the lower case keys of a dot separated path.
+func (ps pathSet) add(path string) {
+	keys := strings.Split(strings.ToLower(path), ".")
+	for i, k := range keys {
+		sub, ok := ps[k]
+		if ok && sub == nil {
+			break
+		}
+		if i == len(keys)-1 {
+			ps[k] = nil
+			break
+		}
+		if sub == nil {
+			sub = make(pathSet)
+			ps[k] = sub
+		}
+		ps = sub
+	}
+}
+
+// checkPath returns an error if path does not point to a schema, model or elem of pro.
+func checkPath(pro *Project, path string) error {
+	keys := strings.Split(strings.ToLower(path), ".")
+	if len(keys) > 3 {
+		return fmt.Errorf("invalid path %s", path)
+	}
+	s := pro.Schema(keys[0])
+	if s == nil {
+		return fmt.Errorf("schema not found for path %s", path)
+	}
+	if len(keys) > 1 {
+		m := s.Model(keys[1])
+		if m == nil {
+			return fmt.Errorf("model not found for path %s", path)
+		}
+		if len(keys) > 2 && m.elem(keys[2]) == nil {
+			return fmt.Errorf("elem not found for path %s", path)
+		}
+	}
+	return nil
+}
+
+// incl reports whether an include set selects key and returns the sub set for its children.
+func (ps pathSet) incl(key string) (pathSet, bool) {
+	if ps == nil {
+		return nil, true
+	}
+	sub, ok := ps[key]
+	return sub, ok
+}
+
+// excl reports whether an exclude set removes key and returns the sub set for its children.
+func (ps pathSet) excl(key string) (pathSet, bool) {
+	sub, ok := ps[key]
+	return sub, ok && sub == nil
+}
+
+func (m *Model) elem(key string) *Elem {
+	for _, el := range m.Elems {
+		if el.Key() == key || elemPathKey(el) == key {
+			return el
+		}
+	}
+	return nil
+}
+
+func elemPathKey(el *Elem) string { return strings.TrimSuffix(el.Key(), "?") }
+
+func hasPK(m *Model) bool { return len(m.PK()) > 0 }
+
+// mergeExtra returns a new dict with the keys of b added to a copy of a. The file and pos keys are
+// ignored.
+func mergeExtra(a, b *lit.Dict) *lit.Dict {
+	if b == nil || len(b.Keyed) == 0 {
+		return a
+	}
+	res := &lit.Dict{Typ: typ.Dict}
+	if a != nil {
+		res.Typ = a.Typ
+		res.Keyed = append(res.Keyed, a.Keyed...)
+	}
+	for _, kv := range b.Keyed {
+		if kv.Key != "file" && kv.Key != "pos" {
+			res.SetKey(kv.Key, kv.Val)
+		}
+	}
+	return res
+}
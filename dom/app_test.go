@@ -0,0 +1,137 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"xelf.org/xelf/lit"
+)
+
+const appRaw = `(import 'auth' 'blog')
+(project site auth.dom blog.dom
+	(schema shop
+		(Addr; Street:str City:str)
+		(Cust; ID:int Name:str Addr:@Addr)
+	)
+	(app pos exclude:['blog.Tag' 'blog.Tagged'] port:8080)
+	(app office include:['auth.Role' 'auth.Acct' 'blog.Status'] exclude:'auth.Acct.Created')
+	(app nosess exclude:'auth.Sess')
+	(app noaddr exclude:'shop.Addr')
+	(app nocust exclude:['shop.Cust' 'auth' 'blog'])
+	(app typo exclude:'blog.Nope')
+)`
+
+func TestApp(t *testing.T) {
+	tests := []struct {
+		app  string
+		want string
+		err  string
+	}{
+		{"pos", "auth[Role Acct Cred Sess] blog[Status Entry] shop[Addr Cust]", ""},
+		{"office", "auth[Role Acct(ID Name Role Rev)] blog[Status]", ""},
+		{"nosess", "auth[Role Acct Cred] blog[Status Tag Entry Tagged] shop[Addr Cust]", ""},
+		{"noaddr", "", "model shop.Addr removed but still referenced by shop.Cust.addr"},
+		{"nocust", "shop[Addr]", ""},
+		{"typo", "", "model not found for path blog.Nope"},
+	}
+	pro, err := ReadProject(lit.NewRegs(), strings.NewReader(appRaw), "testdata/site.xelf")
+	if err != nil {
+		t.Fatalf("read project: %v", err)
+	}
+	if len(pro.Apps) != len(tests) {
+		t.Fatalf("want %d apps got %d", len(tests), len(pro.Apps))
+	}
+	for _, test := range tests {
+		a := pro.App(test.app)
+		if a == nil || a.Project != "site" {
+			t.Errorf("app %s not found in project", test.app)
+			continue
+		}
+		res, err := a.Resolve(pro)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("app %s want error %q got %v", test.app, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("app %s resolve: %v", test.app, err)
+			continue
+		}
+		if got := appSummary(pro, res); got != test.want {
+			t.Errorf("app %s want %s got %s", test.app, test.want, got)
+		}
+	}
+	res, err := pro.App("pos").Resolve(pro)
+	if err != nil {
+		t.Fatalf("resolve pos: %v", err)
+	}
+	if v, err := res.Extra.Key("port"); err != nil || v.String() != "8080" {
+		t.Errorf("want port extra got %v %v", v, err)
+	}
+	if _, err := pro.Extra.Key("port"); err == nil {
+		t.Errorf("resolve must not change project extra")
+	}
+}
+
+func TestAppObject(t *testing.T) {
+	raw := `(project site
+		(schema shop
+			(Cust; ID:int Name:str)
+			(Ord; ID:int (Cust?:@Cust.ID ondel:setnull) Note:str Code:str
+				idx:['note' 'code'] uniq:['code'])
+		)
+		(app lean exclude:['shop.Ord.Cust' 'shop.Ord.Note'])
+	)`
+	pro, err := ReadProject(lit.NewRegs(), strings.NewReader(raw), "testdata/site.xelf")
+	if err != nil {
+		t.Fatalf("read project: %v", err)
+	}
+	res, err := pro.App("lean").Resolve(pro)
+	if err != nil {
+		t.Fatalf("resolve lean: %v", err)
+	}
+	m := res.Model("shop.ord")
+	if m == nil || m.Object == nil {
+		t.Fatalf("want ord model with object got %v", m)
+	}
+	if len(m.Object.Indices) != 1 || !m.Object.Indices[0].Unique || len(m.Object.Refs) != 0 {
+		t.Errorf("want only uniq code index and no refs got %v %v",
+			m.Object.Indices, m.Object.Refs)
+	}
+	src := pro.Model("shop.ord")
+	if len(src.Object.Indices) != 2 || len(src.Object.Refs) != 1 {
+		t.Errorf("resolve must not change the source object got %v %v",
+			src.Object.Indices, src.Object.Refs)
+	}
+}
+
+func appSummary(pro, res *Project) string {
+	var b strings.Builder
+	for _, s := range res.Schemas {
+		if s == Dom || s.Name == "dom" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s.Name)
+		b.WriteByte('[')
+		for i, m := range s.Models {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(m.Name)
+			if m != pro.Model(m.Schema+"."+m.Key()) {
+				names := make([]string, 0, len(m.Elems))
+				for _, el := range m.Elems {
+					names = append(names, el.Name)
+				}
+				fmt.Fprintf(&b, "(%s)", strings.Join(names, " "))
+			}
+		}
+		b.WriteByte(']')
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/typ"
+)
+
+// CalcSrc returns the expression source of a computed elem or an empty string.
+//
+// Computed elems are declared either with a plain expression as in FullName:(cat .first ' ' .last)
+// or with a calc tag as in (FullName:str calc:(cat .first ' ' .last)). The expression can refer to
+// other elems of the same model. Computed values are evaluated by the backend and never stored.
+func (e *Elem) CalcSrc() string { return extraStr(e.Extra, "calc", "") }
+
+// CalcExp returns the unresolved expression of a computed elem or an error.
+func (e *Elem) CalcExp() (exp.Exp, error) {
+	src := e.CalcSrc()
+	if src == "" {
+		return nil, fmt.Errorf("elem %s is not computed", e.Name)
+	}
+	x, err := exp.Read(strings.NewReader(src), e.Name)
+	if err != nil {
+		return nil, fmt.Errorf("read calc %s: %v", e.Name, err)
+	}
+	return x, nil
+}
+
+// Calcs returns the computed elems of model m.
+func (m *Model) Calcs() (res []*Elem) {
+	for _, el := range m.Elems {
+		if el.CalcSrc() != "" {
+			res = append(res, el)
+		}
+	}
+	return res
+}
+
+// calcType returns the result type of simple computed elem expressions or any. Concatenations
+// result in a str and arithmetic calls in the type of the first argument.
+func calcType(m *Model, x exp.Exp) typ.Type {
+	switch v := x.(type) {
+	case *exp.Lit:
+		return v.Val.Type()
+	case *exp.Sym:
+		if strings.HasPrefix(v.Sym, ".") && !strings.HasPrefix(v.Sym, "..") {
+			if el := m.elem(strings.ToLower(v.Sym[1:])); el != nil {
+				return typ.Deopt(el.Type)
+			}
+		}
+	case *exp.Call:
+		if len(v.Args) < 2 {
+			break
+		}
+		if s, ok := v.Args[0].(*exp.Sym); ok {
+			switch s.Sym {
+			case "cat":
+				return typ.Str
+			case "add", "sub", "mul", "div":
+				if t := calcType(m, v.Args[1]); t.Kind&knd.Num != 0 {
+					return t
+				}
+			}
+		}
+	}
+	return typ.Any
+}
@@ -0,0 +1,38 @@
+package dom
+
+import (
+	"testing"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+)
+
+func TestCalcs(t *testing.T) {
+	v, err := exp.NewProg(NewEnv()).RunStr(`(schema test (Person; ID:int
+		First:str Last:str Born:int
+		FullName:(cat .first ' ' .last)
+		(Age:int calc:(sub 2021 .born))
+		Next:(add .born 1)
+	))`, nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	m := mutPtr(v).(*Schema).Model("person")
+	calcs := m.Calcs()
+	want := []struct {
+		name string
+		kind knd.Kind
+	}{{"FullName", knd.Str}, {"Age", knd.Int}, {"Next", knd.Int}}
+	if len(calcs) != len(want) {
+		t.Fatalf("want %d calcs got %d", len(want), len(calcs))
+	}
+	for i, w := range want {
+		el := calcs[i]
+		if el.Name != w.name || el.Type.Kind&knd.Data != w.kind {
+			t.Errorf("calc %d want %s %s got %s %s", i, w.name, w.kind, el.Name, el.Type)
+		}
+		if _, err := el.CalcExp(); err != nil {
+			t.Errorf("calc %s exp: %v", el.Name, err)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package dom
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+)
+
+// Constr holds the value constraints declared as elem tags:
+//
+//	min, max:        inclusive bounds of int or real values, int bounds must be integers
+//	len:             maximum length of str or raw values in runes or of list values in elements
+//	pattern:         regular expression that str or raw values must match
+//	oneof:           list of allowed str, raw, int or real values of the elem type
+//	required-on-new: value must not be zero when created, req is a short alias
+//
+// Null values and zero values of optional elems are only checked by the required constraint.
+type Constr struct {
+	Min, Max *float64
+	Len      int
+	Pattern  *regexp.Regexp
+	OneOf    []lit.Val
+	Req      bool
+}
+
+// ElemConstr returns the constraints declared on elem el, nil if it has none or an error.
+// Constraints that do not apply to the elem type are reported as error.
+func ElemConstr(el *Elem) (*Constr, error) {
+	if el.Extra == nil {
+		return nil, nil
+	}
+	k := el.Type.Kind & knd.Data
+	num := k == knd.Int || k == knd.Real
+	char := k == knd.Str || k == knd.Raw
+	var c Constr
+	var has bool
+	for _, kv := range el.Extra.Keyed {
+		var err error
+		switch kv.Key {
+		case "min", "max":
+			if !num {
+				err = fmt.Errorf("expect int or real elem got %s", el.Type)
+				break
+			}
+			var f float64
+			f, err = constrNum(k, kv.Val)
+			if kv.Key == "min" {
+				c.Min = &f
+			} else {
+				c.Max = &f
+			}
+		case "len":
+			if !char && k != knd.List {
+				err = fmt.Errorf("expect str, raw or list elem got %s", el.Type)
+				break
+			}
+			var n lit.Int
+			n, err = lit.ToInt(kv.Val)
+			if err == nil && n <= 0 {
+				err = fmt.Errorf("expect positive length got %d", n)
+			}
+			c.Len = int(n)
+		case "pattern":
+			if !char {
+				err = fmt.Errorf("expect str or raw elem got %s", el.Type)
+				break
+			}
+			var s lit.Str
+			s, err = lit.ToStr(kv.Val)
+			if err == nil {
+				c.Pattern, err = regexp.Compile(string(s))
+			}
+		case "oneof":
+			if !char && !num {
+				err = fmt.Errorf("expect str, raw, int or real elem got %s", el.Type)
+				break
+			}
+			idx, ok := kv.Val.Value().(lit.Idxr)
+			if !ok {
+				err = fmt.Errorf("expect list got %s", kv.Val)
+				break
+			}
+			err = idx.IterIdx(func(_ int, v lit.Val) error {
+				if char {
+					if v.Type().Kind&knd.Data != knd.Str {
+						return fmt.Errorf("expect str value got %s", v)
+					}
+				} else if _, err := constrNum(k, v); err != nil {
+					return err
+				}
+				c.OneOf = append(c.OneOf, v)
+				return nil
+			})
+		case "required-on-new", "req":
+			c.Req = kv.Val != nil && !kv.Val.Zero()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("elem %s constraint %s: %v", el.Name, kv.Key, err)
+		}
+		has = true
+	}
+	if !has {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// Check returns an error if value v violates a constraint declared on the elems of model m.
+// Required elems are only checked for new values. Use a checker to check many values.
+func (m *Model) Check(v lit.Val, isNew bool) error {
+	c, err := NewChecker(m)
+	if err != nil {
+		return err
+	}
+	return c.Check(v, isNew)
+}
+
+// Checker checks values of one model against the elem constraints that are parsed only once.
+type Checker struct {
+	Model *Model
+	elems []elemConstr
+}
+
+type elemConstr struct {
+	el *Elem
+	c  *Constr
+}
+
+// NewChecker returns a checker for the elem constraints of model m or an error.
+func NewChecker(m *Model) (*Checker, error) {
+	res := &Checker{Model: m}
+	for _, el := range m.Elems {
+		if el.Name == "" || el.CalcSrc() != "" {
+			continue
+		}
+		c, err := ElemConstr(el)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %v", m.Qualified(), err)
+		}
+		if c != nil {
+			res.elems = append(res.elems, elemConstr{el, c})
+		}
+	}
+	return res, nil
+}
+
+// Check returns an error if value v violates a constraint of the checker model.
+// Required elems are only checked for new values.
+func (c *Checker) Check(v lit.Val, isNew bool) error {
+	m := c.Model
+	k, ok := v.Value().(lit.Keyr)
+	if !ok {
+		return fmt.Errorf("check %s: expect keyer got %T", m.Qualified(), v)
+	}
+	for _, ec := range c.elems {
+		ev, err := k.Key(elemPathKey(ec.el))
+		if err != nil {
+			return fmt.Errorf("check %s: %v", m.Qualified(), err)
+		}
+		err = ec.c.check(ElemLabelKey(m, ec.el), ec.el, ev, isNew)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Constr) check(node string, el *Elem, v lit.Val, isNew bool) error {
+	if v == nil || v.Zero() {
+		if c.Req && isNew {
+			return fmt.Errorf("%s is required", node)
+		}
+		if v == nil || v.Nil() || el.Bits&BitOpt != 0 {
+			return nil
+		}
+	}
+	if c.Min != nil || c.Max != nil {
+		r, err := lit.ToReal(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", node, err)
+		}
+		if f := float64(r); c.Min != nil && f < *c.Min {
+			return fmt.Errorf("%s below min %s", node, fmtNum(*c.Min))
+		} else if c.Max != nil && f > *c.Max {
+			return fmt.Errorf("%s above max %s", node, fmtNum(*c.Max))
+		}
+	}
+	if c.Len > 0 {
+		var n int
+		if el.Type.Kind&knd.Char != 0 {
+			s, err := lit.ToStr(v)
+			if err != nil {
+				return fmt.Errorf("%s: %v", node, err)
+			}
+			n = utf8.RuneCountInString(string(s))
+		} else if idx, ok := v.Value().(lit.Idxr); ok {
+			n = idx.Len()
+		}
+		if n > c.Len {
+			return fmt.Errorf("%s longer than %d", node, c.Len)
+		}
+	}
+	if c.Pattern != nil {
+		s, err := lit.ToStr(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", node, err)
+		}
+		if !c.Pattern.MatchString(string(s)) {
+			return fmt.Errorf("%s does not match pattern %s", node, c.Pattern)
+		}
+	}
+	if len(c.OneOf) > 0 {
+		str := constrStr(v)
+		for _, o := range c.OneOf {
+			if constrStr(o) == str {
+				return nil
+			}
+		}
+		strs := make([]string, 0, len(c.OneOf))
+		for _, o := range c.OneOf {
+			strs = append(strs, constrStr(o))
+		}
+		return fmt.Errorf("%s is not one of %s", node, strings.Join(strs, ", "))
+	}
+	return nil
+}
+
+// constrNum returns the number v or an error if v is no number or not an integer for int kind k.
+func constrNum(k knd.Kind, v lit.Val) (float64, error) {
+	if v.Type().Kind&knd.Num == 0 {
+		return 0, fmt.Errorf("expect number got %s", v)
+	}
+	r, err := lit.ToReal(v)
+	if err != nil {
+		return 0, err
+	}
+	f := float64(r)
+	if k == knd.Int && f != math.Trunc(f) {
+		return 0, fmt.Errorf("expect integer for int elem got %s", fmtNum(f))
+	}
+	return f, nil
+}
+
+func fmtNum(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+func constrStr(v lit.Val) string {
+	if s, err := lit.ToStr(v); err == nil {
+		return string(s)
+	}
+	return v.String()
+}
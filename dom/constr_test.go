@@ -0,0 +1,67 @@
+package dom
+
+import (
+	"testing"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lit"
+)
+
+func TestCheck(t *testing.T) {
+	v, err := exp.NewProg(NewEnv()).RunStr(`(schema test (Prod; ID:int
+		(Name:str req; len:5 pattern:'^[a-z]+$')
+		(Qty?:int min:1 max:9)
+		(Kind?:str oneof:['a' 'b'])
+	))`, nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	m := mutPtr(v).(*Schema).Model("prod")
+	reg := lit.NewRegs()
+	tests := []struct {
+		raw   string
+		isNew bool
+		want  string
+	}{
+		{`{id:1 name:'abc'}`, true, ""},
+		{`{id:1}`, true, "test.Prod.Name is required"},
+		{`{id:1}`, false, "test.Prod.Name does not match pattern ^[a-z]+$"},
+		{`{id:1 name:'abcdef'}`, false, "test.Prod.Name longer than 5"},
+		{`{id:1 name:'ab1'}`, false, "test.Prod.Name does not match pattern ^[a-z]+$"},
+		{`{id:1 name:'ab' qty:0}`, false, ""},
+		{`{id:1 name:'ab' qty:10}`, false, "test.Prod.Qty above max 9"},
+		{`{id:1 name:'ab' kind:'b'}`, false, ""},
+		{`{id:1 name:'ab' kind:'c'}`, false, "test.Prod.Kind is not one of a, b"},
+	}
+	for _, test := range tests {
+		mut := reg.Zero(m.Type()).(lit.Keyr)
+		err := lit.ParseInto(test.raw, mut)
+		if err != nil {
+			t.Errorf("parse %s: %v", test.raw, err)
+			continue
+		}
+		var got string
+		if err := m.Check(mut, test.isNew); err != nil {
+			got = err.Error()
+		}
+		if got != test.want {
+			t.Errorf("check %s want %q got %q", test.raw, test.want, got)
+		}
+	}
+}
+
+func TestElemConstrReq(t *testing.T) {
+	for _, key := range []string{"required-on-new", "req"} {
+		el := &Elem{Name: "Name", Extra: &lit.Dict{Keyed: []lit.KeyVal{
+			{Key: key, Val: lit.Bool(true)},
+		}}}
+		c, err := ElemConstr(el)
+		if err != nil {
+			t.Errorf("constr %s: %v", key, err)
+			continue
+		}
+		if c == nil || !c.Req {
+			t.Errorf("constr %s want required got %+v", key, c)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"xelf.org/xelf/cor"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+// DefaultSrc returns the default expression source of elem e or an empty string.
+func (e *Elem) DefaultSrc() string { return extraStr(e.Extra, "default", "") }
+
+// Default returns the evaluated default expression of elem e, nil if it has none, or an error.
+// The symbol now resolves to the given time.
+func (e *Elem) Default(now time.Time) (lit.Val, error) {
+	src := e.DefaultSrc()
+	if src == "" {
+		return nil, nil
+	}
+	x, err := exp.Read(strings.NewReader(src), e.Name)
+	if err != nil {
+		return nil, fmt.Errorf("read default %s: %v", e.Name, err)
+	}
+	a, err := exp.NewProg(&defaultEnv{extlib.Std, now}).Run(x, nil)
+	if err != nil {
+		return nil, fmt.Errorf("eval default %s: %v", e.Name, err)
+	}
+	return a, nil
+}
+
+// Defaults returns a map of elem keys to the evaluated default values of model m or an error.
+func (m *Model) Defaults(now time.Time) (map[string]lit.Val, error) {
+	var res map[string]lit.Val
+	for _, el := range m.Elems {
+		v, err := el.Default(now)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %v", m.Qualified(), err)
+		}
+		if v == nil {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]lit.Val)
+		}
+		res[elemPathKey(el)] = v
+	}
+	return res, nil
+}
+
+type defaultEnv struct {
+	par exp.Env
+	now time.Time
+}
+
+func (e *defaultEnv) Parent() exp.Env { return e.par }
+func (e *defaultEnv) Lookup(s *exp.Sym, p cor.Path, eval bool) (lit.Val, error) {
+	if p.Plain() == "now" {
+		return lit.Time(e.now), nil
+	}
+	return e.par.Lookup(s, p, eval)
+}
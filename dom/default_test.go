@@ -0,0 +1,42 @@
+package dom
+
+import (
+	"testing"
+	"time"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lit"
+)
+
+func TestDefaults(t *testing.T) {
+	v, err := exp.NewProg(NewEnv()).RunStr(`(schema test (Prod; ID:int
+		(Status:str default:'draft')
+		(Created:time default:now)
+		(Qty:int default:(add 1 2))
+		Name:str
+	))`, nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	m := mutPtr(v).(*Schema).Model("prod")
+	if src := m.Elems[2].DefaultSrc(); src != "now" {
+		t.Errorf("default src want now got %q", src)
+	}
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	defs, err := m.Defaults(now)
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	if len(defs) != 3 {
+		t.Errorf("defaults want 3 got %v", defs)
+	}
+	if s, err := lit.ToStr(defs["status"]); err != nil || s != "draft" {
+		t.Errorf("default status want draft got %v", defs["status"])
+	}
+	if got, want := defs["created"].String(), lit.Time(now).String(); got != want {
+		t.Errorf("default created want %s got %s", want, got)
+	}
+	if got := defs["qty"].String(); got != "3" {
+		t.Errorf("default qty want 3 got %s", got)
+	}
+}
@@ -11,6 +11,7 @@ import (
 func (s *Schema) Key() string { return strings.ToLower(s.Name) }
 func (m *Model) Key() string  { return strings.ToLower(m.Name) }
 func (e *Elem) Key() string   { return strings.ToLower(e.Name) }
+func (q *Query) Key() string  { return strings.ToLower(q.Name) }
 func (m *Model) Params() []typ.Param {
 	res := make([]typ.Param, 0, len(m.Elems))
 	for _, el := range m.Elems {
@@ -66,6 +67,9 @@ type Node interface {
 func (m *Model) Qual() string      { return m.Schema }
 func (m *Model) Qualified() string { return fmt.Sprintf("%s.%s", m.Schema, m.Name) }
 
+func (q *Query) Qual() string      { return q.Schema }
+func (q *Query) Qualified() string { return fmt.Sprintf("%s.%s", q.Schema, q.Name) }
+
 func (s *Schema) Qualified() string { return s.Key() }
 
 func (p *Project) Qualified() string { return fmt.Sprintf("_%s", p.Name) }
@@ -110,6 +114,35 @@ func (s *Schema) Model(key string) *Model {
 	return nil
 }
 
+// Query returns a named query for the qualified key or nil. Keys are matched case-insensitive.
+func (p *Project) Query(key string) *Query {
+	if p != nil {
+		split := strings.SplitN(key, ".", 2)
+		if len(split) == 2 {
+			return p.Schema(split[0]).Query(split[1])
+		}
+		for _, s := range p.Schemas {
+			if q := s.Query(key); q != nil {
+				return q
+			}
+		}
+	}
+	return nil
+}
+
+// Query returns a named query for key or nil. Keys are matched case-insensitive.
+func (s *Schema) Query(key string) *Query {
+	if s != nil {
+		key = strings.ToLower(key)
+		for _, q := range s.Queries {
+			if q.Key() == key {
+				return q
+			}
+		}
+	}
+	return nil
+}
+
 var bitConsts = []typ.Const{
 	typ.C("Opt", int64(BitOpt)),
 	typ.C("PK", int64(BitPK)),
@@ -120,3 +153,22 @@ var bitConsts = []typ.Const{
 	typ.C("Auto", int64(BitAuto)),
 	typ.C("RO", int64(BitRO)),
 }
+
+// ContainsStr returns whether list contains s.
+func ContainsStr(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// BitNames returns the lowercase elem bit tag names. The name at index i is the name of bit 1<<i.
+func BitNames() []string {
+	res := make([]string, 0, len(bitConsts))
+	for _, c := range bitConsts {
+		res = append(res, strings.ToLower(c.Name))
+	}
+	return res
+}
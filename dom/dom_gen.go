@@ -44,10 +44,36 @@ type Index struct {
 	Unique bool     `json:"unique,omitempty"`
 }
 
+// RefAct is a referential action applied to referencing objects of a deleted object.
+type RefAct string
+
+const (
+	RefActRestrict RefAct = "restrict"
+	RefActCascade  RefAct = "cascade"
+	RefActSetNull  RefAct = "setnull"
+)
+
+// Ref declares the referential action for a reference elem of an object model.
+type Ref struct {
+	Key   string `json:"key"`
+	OnDel RefAct `json:"ondel"`
+}
+
+// Trigger emits extra actions when an event command is applied to an object of the model.
+//
+// The expression source is evaluated with the event top, key, cmd and arg as parameter and must
+// return a list of actions, that are published in the same transaction.
+type Trigger struct {
+	Cmd string `json:"cmd"`
+	Src string `json:"src"`
+}
+
 // Object holds data specific to object types for grouping.
 type Object struct {
-	Indices []*Index `json:"indices,omitempty"`
-	OrderBy []string `json:"orderby,omitempty"`
+	Indices []*Index   `json:"indices,omitempty"`
+	OrderBy []string   `json:"orderby,omitempty"`
+	Refs    []*Ref     `json:"refs,omitempty"`
+	Trigs   []*Trigger `json:"trigs,omitempty"`
 }
 
 // Model represents either a bits, enum or obj type and has extra domain information.
@@ -60,13 +86,52 @@ type Model struct {
 	Object *Object   `json:"object,omitempty"`
 }
 
+// Query is a named query declared in a schema that can be used like a view model.
+//
+// The query source is resolved by the qry package when the project is loaded. The resolved result type
+// is then available for code generation. Named queries can be used as query subjects like models.
+type Query struct {
+	Name   string    `json:"name"`
+	Schema string    `json:"schema,omitempty"`
+	Extra  *lit.Dict `json:"extra,omitempty"`
+	Src    string    `json:"src"`
+	Res    typ.Type  `json:"res,omitempty"`
+}
+
 // Schema is a namespace for models.
 type Schema struct {
+	Name    string    `json:"name"`
+	Extra   *lit.Dict `json:"extra,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Use     []string  `json:"use,omitempty"`
+	Models  []*Model  `json:"models"`
+	Queries []*Query  `json:"queries,omitempty"`
+}
+
+// Overlay patches a schema of the same name that was added to a project before.
+//
+// Overlays can drop models or model elements, listed as 'Model' or 'Model.Elem' in drop. The overlay
+// extra tags are merged into the schema extra. Models of the overlay are either added to the schema
+// or their extra and elements are merged into an existing model of the same name. Elements can be
+// marked private with the priv tag. This allows projects to filter and extend library schemas.
+type Overlay struct {
 	Name   string    `json:"name"`
 	Extra  *lit.Dict `json:"extra,omitempty"`
-	Path   string    `json:"path,omitempty"`
-	Use    []string  `json:"use,omitempty"`
-	Models []*Model  `json:"models"`
+	Drop   []string  `json:"drop,omitempty"`
+	Models []*Model  `json:"models,omitempty"`
+}
+
+// App specializes a project for one deployed application.
+//
+// Include and exclude hold schema, model or field paths like 'auth', 'auth.Acct' or 'auth.Acct.Name'
+// that restrict the parent project. Extra holds the app specific configuration that extends the
+// project configuration.
+type App struct {
+	Name    string    `json:"name"`
+	Project string    `json:"project,omitempty"`
+	Extra   *lit.Dict `json:"extra,omitempty"`
+	Include []string  `json:"include,omitempty"`
+	Exclude []string  `json:"exclude,omitempty"`
 }
 
 // Project is a collection of schemas and project specific extra configuration.
@@ -80,4 +145,5 @@ type Project struct {
 	Name    string    `json:"name,omitempty"`
 	Extra   *lit.Dict `json:"extra,omitempty"`
 	Schemas []*Schema `json:"schemas"`
+	Apps    []*App    `json:"apps,omitempty"`
 }
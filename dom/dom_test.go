@@ -18,6 +18,14 @@ func TestDom(t *testing.T) {
 		{`(project app)`, `{name:'app' schemas:[]}`},
 		{`(schema test)`, `{name:'test' models:[]}`},
 		{`(project app (schema test))`, `{name:'app' schemas:[` + domSchema + ` {name:'test' models:[]}]}`},
+		{`(app pos exclude:['auth.Sess' 'blog'])`, `{name:'pos' exclude:['auth.Sess' 'blog']}`},
+		{`(project app (app pos include:'auth' port:1))`,
+			`{name:'app' schemas:[] apps:[{name:'pos' project:'app' extra:{port:1} include:['auth']}]}`,
+		},
+		{`(schema test (query Top doc:'top nodes' (*test.node lim:3)))`,
+			`{name:'test' models:[] queries:[` +
+				`{name:'Top' schema:'test' extra:{doc:'top nodes'} src:'(*test.node lim:3)'}]}`,
+		},
 		{`(schema test label:'Test Schema')`,
 			`{name:'test' extra:{label:'Test Schema'} models:[]}`,
 		},
@@ -0,0 +1,510 @@
+// Package domfmt formats project and schema xelf files in a canonical layout.
+//
+// Project, schema and module forms and the models they contain are written one element per line
+// and indented with tabs by nesting depth. Model flags and tags, except for indices and triggers,
+// follow the model name with the doc tag first. Elem flags are ordered like the dom bits followed
+// by other tags and the doc tag last. Elem forms without tags lose their parenthesis. All other
+// forms, like imports, are written on one line with single spaces. Line comments and single empty
+// lines are preserved.
+package domfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"xelf.org/daql/dom"
+)
+
+// Format returns the canonical layout of the xelf source src or an error.
+func Format(src []byte) ([]byte, error) {
+	p := &parser{src: string(src)}
+	nodes, end, err := p.list(0)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	w := &writer{Buffer: &b}
+	for i, n := range nodes {
+		if i > 0 && n.blank {
+			b.WriteByte('\n')
+		}
+		w.comments(n.pre, 0)
+		w.top(n)
+		w.trail(n)
+		b.WriteByte('\n')
+	}
+	w.comments(end, 0)
+	return b.Bytes(), nil
+}
+
+// node is a syntax node with its comments.
+type node struct {
+	kind  byte   // a for atoms, g for groups, t for tags and f for flags
+	text  string // atom text, group open bracket or tag and flag key
+	kids  []*node
+	val   *node    // tag value
+	end   []string // comments before the group closing bracket
+	pre   []string // comment lines before the node
+	post  string   // line comment after the node
+	blank bool     // node is preceded by an empty line
+	src   string   // original source text
+}
+
+func (n *node) key() string {
+	if n.kind == 't' || n.kind == 'f' {
+		return n.text
+	}
+	return ""
+}
+
+// head returns the leading atom of a group or an empty string.
+func (n *node) head() string {
+	if n.kind == 'g' && len(n.kids) > 0 && n.kids[0].kind == 'a' {
+		return n.kids[0].text
+	}
+	return ""
+}
+
+func (n *node) hasComments() bool { return len(n.pre) > 0 || n.post != "" || n.inner() }
+
+// inner returns whether n contains comments.
+func (n *node) inner() bool {
+	if len(n.end) > 0 {
+		return true
+	}
+	for _, k := range n.kids {
+		if k.hasComments() {
+			return true
+		}
+	}
+	return n.val != nil && n.val.hasComments()
+}
+
+var closing = map[byte]byte{'(': ')', '[': ']', '{': '}', '<': '>'}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// space skips whitespace and returns whether the skipped whitespace contains a newline and an
+// empty line.
+func (p *parser) space() (nl, blank bool) {
+	var lines int
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\n':
+			lines++
+		case ' ', '\t', '\r':
+		default:
+			return lines > 0, lines > 1
+		}
+		p.pos++
+	}
+	return lines > 0, lines > 1
+}
+
+func (p *parser) comment() (string, bool) {
+	if !strings.HasPrefix(p.src[p.pos:], "//") {
+		return "", false
+	}
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	c := strings.TrimRight(p.src[p.pos:p.pos+end], " \t\r")
+	p.pos += end
+	return c, true
+}
+
+// list parses nodes until the closing bracket cl or the end of input for zero. It returns the
+// nodes and the comments after the last node.
+func (p *parser) list(cl byte) (res []*node, _ []string, err error) {
+	var pre []string
+	var blank bool
+	for {
+		nl, bl := p.space()
+		blank = blank || bl
+		if p.pos >= len(p.src) {
+			if cl != 0 {
+				return nil, nil, fmt.Errorf("missing closing %c", cl)
+			}
+			return res, pre, nil
+		}
+		if c, ok := p.comment(); ok {
+			if !nl && len(pre) == 0 && len(res) > 0 && res[len(res)-1].post == "" {
+				res[len(res)-1].post = c
+			} else {
+				pre = append(pre, c)
+			}
+			continue
+		}
+		if p.src[p.pos] == cl {
+			p.pos++
+			return res, pre, nil
+		}
+		n, err := p.node()
+		if err != nil {
+			return nil, nil, err
+		}
+		n.pre, n.blank = pre, blank && len(res) > 0
+		pre, blank = nil, false
+		res = append(res, n)
+	}
+}
+
+func (p *parser) node() (*node, error) {
+	start := p.pos
+	n, err := p.elem()
+	if err != nil {
+		return nil, err
+	}
+	if n.kind == 'a' && p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ':':
+			p.pos++
+			n = &node{kind: 't', text: n.text}
+			// the tag value may follow after spaces on the same line
+			for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+				p.pos++
+			}
+			if p.pos < len(p.src) && !isSpace(p.src[p.pos]) && !isClose(p.src[p.pos]) &&
+				!strings.HasPrefix(p.src[p.pos:], "//") {
+				n.val, err = p.node()
+				if err != nil {
+					return nil, err
+				}
+			}
+		case ';':
+			p.pos++
+			n = &node{kind: 'f', text: n.text}
+		}
+	}
+	n.src = p.src[start:p.pos]
+	return n, nil
+}
+
+func (p *parser) elem() (*node, error) {
+	c := p.src[p.pos]
+	switch c {
+	case '(', '[', '{', '<':
+		p.pos++
+		kids, end, err := p.list(closing[c])
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: 'g', text: string(c), kids: kids, end: end}, nil
+	case ')', ']', '}', '>':
+		return nil, fmt.Errorf("unexpected %c at offset %d", c, p.pos)
+	case '\'', '"', '`':
+		end := p.pos + 1
+		for ; end < len(p.src); end++ {
+			if p.src[end] == '\\' && c != '`' {
+				end++
+			} else if p.src[end] == c {
+				break
+			}
+		}
+		if end >= len(p.src) {
+			return nil, fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		n := &node{kind: 'a', text: p.src[p.pos : end+1]}
+		p.pos = end + 1
+		return n, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		c = p.src[p.pos]
+		if isSpace(c) || isClose(c) || c == ':' || c == ';' || c == '(' || c == '[' ||
+			c == '{' || c == '<' || c == '\'' || c == '"' || c == '`' {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, fmt.Errorf("unexpected %c at offset %d", c, p.pos)
+	}
+	return &node{kind: 'a', text: p.src[start:p.pos]}, nil
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+func isClose(c byte) bool { return c == ')' || c == ']' || c == '}' || c == '>' }
+
+type writer struct {
+	*bytes.Buffer
+}
+
+func (w *writer) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		w.WriteByte('\t')
+	}
+}
+
+func (w *writer) comments(cs []string, depth int) {
+	for _, c := range cs {
+		w.indent(depth)
+		w.WriteString(c)
+		w.WriteByte('\n')
+	}
+}
+
+func (w *writer) trail(n *node) {
+	if n.post != "" {
+		w.WriteByte(' ')
+		w.WriteString(n.post)
+	}
+}
+
+// top writes a top level node.
+func (w *writer) top(n *node) {
+	switch n.head() {
+	case "schema", "project", "module":
+		w.container(n, 0)
+	default:
+		w.inline(n)
+	}
+}
+
+// container writes a project, schema or module form with each element on its own line.
+func (w *writer) container(n *node, depth int) {
+	w.WriteByte('(')
+	kids := n.kids
+	// the form name and header tags stay on the first line
+	var head, body []*node
+	for i, k := range kids {
+		if i < 2 && k.kind == 'a' || isHeadTag(k) {
+			head = append(head, k)
+		} else {
+			body = append(body, k)
+		}
+	}
+	w.header(docFirst(head), depth)
+	for _, k := range body {
+		w.line(k, depth+1, func() {
+			switch {
+			case isFunc(k):
+				w.inline(k)
+			case isModel(k):
+				w.model(k, depth+1)
+			case k.kind == 't' && k.val != nil && k.val.head() == "model":
+				w.WriteString(k.text)
+				w.WriteByte(':')
+				w.model(k.val, depth+1)
+			default:
+				w.inline(k)
+			}
+		})
+	}
+	w.close(n, len(body) > 0, depth)
+}
+
+// model writes a model form with each elem on its own line.
+func (w *writer) model(n *node, depth int) {
+	w.WriteByte('(')
+	var head, body, tail []*node
+	for i, k := range n.kids {
+		switch {
+		case i == 0 || k.kind == 'a' && len(body) == 0 && len(tail) == 0 && n.head() == "model":
+			head = append(head, k)
+		case isTailTag(k):
+			tail = append(tail, k)
+		case isHeadTag(k):
+			head = append(head, k)
+		default:
+			body = append(body, k)
+		}
+	}
+	w.header(docFirst(head), depth)
+	for _, k := range append(body, tail...) {
+		w.line(k, depth+1, func() { w.elem(k) })
+	}
+	w.close(n, len(body)+len(tail) > 0, depth)
+}
+
+// elem writes a model elem with canonical tag order.
+func (w *writer) elem(n *node) {
+	if n.kind != 'g' || n.text != "(" || len(n.kids) == 0 || n.inner() {
+		w.inline(n)
+		return
+	}
+	fst, rest := n.kids[0], n.kids[1:]
+	if len(rest) == 0 && fst.kind == 't' {
+		w.inline(fst)
+		return
+	}
+	var flags, tags, docs []*node
+	for _, k := range rest {
+		switch {
+		case k.kind == 'f':
+			flags = append(flags, k)
+		case isDoc(k):
+			docs = append(docs, k)
+		default:
+			tags = append(tags, k)
+		}
+	}
+	sortFlags(flags)
+	w.WriteByte('(')
+	w.inline(fst)
+	for _, k := range append(append(flags, tags...), docs...) {
+		w.WriteByte(' ')
+		w.inline(k)
+	}
+	w.WriteByte(')')
+}
+
+// header writes the head nodes on one line followed by their line comments. Comments before a
+// head node are written on their own lines at depth plus one and break the header line.
+func (w *writer) header(head []*node, depth int) {
+	for i, k := range head {
+		if len(k.pre) > 0 {
+			w.WriteByte('\n')
+			w.comments(k.pre, depth+1)
+			w.indent(depth + 1)
+		} else if i > 0 {
+			w.WriteByte(' ')
+		}
+		w.inline(k)
+	}
+	for _, k := range head {
+		w.trail(k)
+	}
+}
+
+// line writes node n on its own line using write. The first line also ends the header line.
+func (w *writer) line(n *node, depth int, write func()) {
+	if b := w.Bytes(); b[len(b)-1] != '\n' {
+		w.WriteByte('\n')
+	}
+	if n.blank {
+		w.WriteByte('\n')
+	}
+	w.comments(n.pre, depth)
+	w.indent(depth)
+	write()
+	w.trail(n)
+	w.WriteByte('\n')
+}
+
+// close writes the end comments and closing bracket of n on its own line if it has lines.
+func (w *writer) close(n *node, lines bool, depth int) {
+	if lines || len(n.end) > 0 {
+		if !lines {
+			w.WriteByte('\n')
+		}
+		w.comments(n.end, depth+1)
+		w.indent(depth)
+	}
+	w.WriteByte(closing[n.text[0]])
+}
+
+// inline writes n on one line or its original source if it contains comments.
+func (w *writer) inline(n *node) {
+	if n.inner() {
+		w.WriteString(n.src)
+		return
+	}
+	switch n.kind {
+	case 'a':
+		w.WriteString(n.text)
+	case 'f':
+		w.WriteString(n.text)
+		w.WriteByte(';')
+	case 't':
+		w.WriteString(n.text)
+		w.WriteByte(':')
+		if n.val != nil {
+			w.inline(n.val)
+		}
+	case 'g':
+		w.WriteString(n.text)
+		for i, k := range n.kids {
+			if i > 0 {
+				w.WriteByte(' ')
+			}
+			w.inline(k)
+		}
+		w.WriteByte(closing[n.text[0]])
+	}
+}
+
+// isModel returns whether n is a model form starting with a capitalized name tag or flag.
+func isModel(n *node) bool {
+	if n.kind != 'g' || n.text != "(" || len(n.kids) == 0 {
+		return false
+	}
+	k := n.kids[0].key()
+	return k != "" && k[0] >= 'A' && k[0] <= 'Z'
+}
+
+// isFunc returns whether n is a func model form, that is written on one line.
+func isFunc(n *node) bool {
+	if !isModel(n) {
+		return false
+	}
+	v := n.kids[0].val
+	return v != nil && v.kind == 'a' && v.text == "func"
+}
+
+// isHeadTag returns whether n is a lowercase model or schema tag that follows the name.
+// Tags preceded by comments stay on their own line.
+func isHeadTag(n *node) bool {
+	k := n.key()
+	return k != "" && (k[0] >= 'a' && k[0] <= 'z' || k[0] == '\'') && !isTailTag(n) &&
+		len(n.pre) == 0
+}
+
+// isTailTag returns whether n is a model index or trigger tag written after the elems.
+func isTailTag(n *node) bool {
+	switch n.key() {
+	case "idx", "uniq", "trignew", "trigmod", "trigdel":
+		return n.kind == 't'
+	}
+	return false
+}
+
+func isDoc(n *node) bool {
+	switch n.key() {
+	case "doc", "descr", "label":
+		return n.kind == 't'
+	}
+	return false
+}
+
+// docFirst moves doc tags after the leading name nodes.
+func docFirst(head []*node) []*node {
+	res := make([]*node, 0, len(head))
+	var docs, rest []*node
+	for i, k := range head {
+		switch {
+		case i == 0 || k.kind == 'a' && len(rest) == 0 && len(docs) == 0:
+			res = append(res, k)
+		case isDoc(k):
+			docs = append(docs, k)
+		default:
+			rest = append(rest, k)
+		}
+	}
+	return append(append(res, docs...), rest...)
+}
+
+var flagOrder = dom.BitNames()
+
+func flagRank(n *node) int {
+	for i, f := range flagOrder {
+		if n.text == f {
+			return i
+		}
+	}
+	return len(flagOrder)
+}
+
+// sortFlags sorts the flags by the dom bit order and keeps the order of other flags.
+func sortFlags(fs []*node) {
+	for i := 1; i < len(fs); i++ {
+		for j := i; j > 0 && flagRank(fs[j]) < flagRank(fs[j-1]); j-- {
+			fs[j], fs[j-1] = fs[j-1], fs[j]
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package domfmt
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"(schema s (Cat; ID:int   Name:str uniq:['name']))",
+			"(schema s\n\t(Cat;\n\t\tID:int\n\t\tName:str\n\t\tuniq:['name']\n\t)\n)\n"},
+		{"(import  'a'\n'b')\n(schema s doc:'S' (Role:enum None; Admin;))",
+			"(import 'a' 'b')\n(schema s doc:'S'\n\t(Role:enum\n\t\tNone;\n\t\tAdmin;\n\t)\n)\n"},
+		{"(schema s embed;\n(Node; backup; doc:`is a node.`\nidx:['name']\n(Name:str\n doc:'n' asc; uniq;)\n(ID:int)\n))",
+			"(schema s embed;\n\t(Node; doc:`is a node.` backup;\n\t\t(Name:str uniq; asc; doc:'n')\n" +
+				"\t\tID:int\n\t\tidx:['name']\n\t)\n)\n"},
+		{"// head\n(schema s // trail\n\n// before\n(Cat; ID:int // id\nName:str)\n\n\n(Dog;)\n// end\n)\n",
+			"// head\n(schema s // trail\n\n\t// before\n\t(Cat;\n\t\tID:int // id\n\t\tName:str\n\t)\n\n" +
+				"\t(Dog;)\n\t// end\n)\n"},
+		{"(schema s (Get:func  Key:str   bool) (Cat; (Name:str // name\n uniq;)))",
+			"(schema s\n\t(Get:func Key:str bool)\n\t(Cat;\n\t\t(Name:str // name\n uniq;)\n\t)\n)\n"},
+		{"(schema s\n// the doc\ndoc:'S' (Cat; // cat\nbackup; ID:int))",
+			"(schema s\n\t// the doc\n\tdoc:'S'\n\t(Cat; backup; // cat\n\t\tID:int\n\t)\n)\n"},
+		{"(schema s (// note\nCat; ID:int))",
+			"(schema s\n\t(\n\t\t// note\n\t\tCat;\n\t\tID:int\n\t)\n)\n"},
+		{"(schema\n// c\ns (Dog;))",
+			"(schema\n\t// c\n\ts\n\t(Dog;)\n)\n"},
+		{"(project p hist: '.hist' s.s\n t.t)",
+			"(project p hist:'.hist'\n\ts.s\n\tt.t\n)\n"},
+		{"(module m Status:(model Status enum Draft; Pub;))",
+			"(module m\n\tStatus:(model Status enum\n\t\tDraft;\n\t\tPub;\n\t)\n)\n"},
+	}
+	for _, test := range tests {
+		got, err := Format([]byte(test.raw))
+		if err != nil {
+			t.Errorf("format %s: %v", test.raw, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("format %s\nwant:\n%s\ngot:\n%s", test.raw, test.want, got)
+		}
+		again, err := Format(got)
+		if err != nil || string(again) != string(got) {
+			t.Errorf("format not idempotent for %s got:\n%s", test.raw, again)
+		}
+	}
+}
+
+func TestFormatFiles(t *testing.T) {
+	files := []string{
+		"../testdata/auth.xelf",
+		"../testdata/site.xelf",
+		"../testdata/blog/mod.xelf",
+		"../testdata/blog/entry.xelf",
+	}
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("read %s: %v", f, err)
+		}
+		got, err := Format(raw)
+		if err != nil {
+			t.Errorf("format %s: %v", f, err)
+			continue
+		}
+		if string(got) != string(raw) {
+			t.Errorf("format %s want unchanged got:\n%s", f, got)
+		}
+	}
+}
+
+func TestFormatErr(t *testing.T) {
+	tests := []string{
+		"(schema s",
+		"(schema s))",
+		"(schema s doc:'open)",
+	}
+	for _, raw := range tests {
+		if _, err := Format([]byte(raw)); err == nil {
+			t.Errorf("format %s want error", raw)
+		}
+	}
+}
@@ -26,8 +26,14 @@ func (e *Env) Lookup(s *exp.Sym, p cor.Path, eval bool) (lit.Val, error) {
 	switch p.Plain() {
 	case "project":
 		return exp.NewSpecRef(projectSpec), nil
+	case "app":
+		return exp.NewSpecRef(appSpec), nil
+	case "overlay":
+		return exp.NewSpecRef(overlaySpec), nil
 	case "schema":
 		return exp.NewSpecRef(schemaSpec), nil
+	case "query":
+		return exp.NewSpecRef(querySpec), nil
 	case "model":
 		return exp.NewSpecRef(modelSpec), nil
 	case "elem":
@@ -0,0 +1,68 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PK returns the primary key elems of model m in declaration order. Models with more than one
+// primary key elem have a composite primary key.
+func (m *Model) PK() (res []*Elem) {
+	for _, el := range m.Elems {
+		if el.Bits&BitPK != 0 {
+			res = append(res, el)
+		}
+	}
+	return res
+}
+
+// JoinKey returns the canonical key string for the primary key parts, as used for event keys.
+//
+// A single part is returned unchanged. The parts of composite keys are joined with commas, and
+// commas and backslashes in parts are escaped with a backslash.
+func JoinKey(parts ...string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	var b strings.Builder
+	for i, p := range parts {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		for j := 0; j < len(p); j++ {
+			if c := p[j]; c == ',' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(p[j])
+		}
+	}
+	return b.String()
+}
+
+// SplitKey returns the n primary key parts of the canonical key string or an error.
+func SplitKey(key string, n int) ([]string, error) {
+	if n == 1 {
+		return []string{key}, nil
+	}
+	res := make([]string, 0, n)
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '\\':
+			if i++; i == len(key) {
+				return nil, fmt.Errorf("key %q ends with escape", key)
+			}
+			b.WriteByte(key[i])
+		case ',':
+			res = append(res, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	res = append(res, b.String())
+	if len(res) != n {
+		return nil, fmt.Errorf("key %q has %d parts want %d", key, len(res), n)
+	}
+	return res, nil
+}
@@ -0,0 +1,37 @@
+package dom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"1"}, "1"},
+		{[]string{"a,b"}, "a,b"},
+		{[]string{"1", "2"}, "1,2"},
+		{[]string{"a,b", `c\d`, ""}, `a\,b,c\\d,`},
+	}
+	for _, test := range tests {
+		got := JoinKey(test.parts...)
+		if got != test.want {
+			t.Errorf("join %q want %s got %s", test.parts, test.want, got)
+		}
+		parts, err := SplitKey(got, len(test.parts))
+		if err != nil {
+			t.Errorf("split %s: %v", got, err)
+			continue
+		}
+		if !reflect.DeepEqual(parts, test.parts) {
+			t.Errorf("split %s want %q got %q", got, test.parts, parts)
+		}
+	}
+	for _, key := range []string{"1", "1,2,3", `1,2\`} {
+		if _, err := SplitKey(key, 2); err == nil {
+			t.Errorf("split %s want error", key)
+		}
+	}
+}
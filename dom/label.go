@@ -0,0 +1,146 @@
+package dom
+
+import (
+	"sort"
+	"strings"
+
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Label returns the label tag of the schema or its name.
+func (s *Schema) Label() string { return extraStr(s.Extra, "label", s.Name) }
+
+// Descr returns the descr tag of the schema or its doc tag.
+func (s *Schema) Descr() string { return extraStr(s.Extra, "descr", extraStr(s.Extra, "doc", "")) }
+
+// Label returns the label tag of the model or its name.
+func (m *Model) Label() string { return extraStr(m.Extra, "label", m.Name) }
+
+// Descr returns the descr tag of the model or its doc tag.
+func (m *Model) Descr() string { return extraStr(m.Extra, "descr", extraStr(m.Extra, "doc", "")) }
+
+// Label returns the label tag of the elem or its name without optional suffix.
+func (e *Elem) Label() string {
+	return extraStr(e.Extra, "label", strings.TrimSuffix(e.Name, "?"))
+}
+
+// Descr returns the descr tag of the elem or its doc tag.
+func (e *Elem) Descr() string { return extraStr(e.Extra, "descr", extraStr(e.Extra, "doc", "")) }
+
+// Label holds a label and description text of one schema, model or elem.
+type Label struct {
+	Label string `json:"label"`
+	Descr string `json:"descr,omitempty"`
+}
+
+// Catalog maps label keys to label texts. Label keys are the schema name, the qualified model
+// name and the qualified model name followed by a dot and the elem name without optional suffix.
+// Catalogs are usually stored as one JSON file per language and used to annotate a project.
+type Catalog map[string]Label
+
+// NewCatalog returns a catalog with all label keys of the project pro and their current labels.
+// The shared dom schema is not part of project catalogs.
+func NewCatalog(pro *Project) Catalog {
+	c := make(Catalog)
+	for _, s := range pro.Schemas {
+		if s == Dom {
+			continue
+		}
+		c[s.Name] = Label{s.Label(), s.Descr()}
+		for _, m := range s.Models {
+			key := m.Qualified()
+			c[key] = Label{m.Label(), m.Descr()}
+			for _, el := range m.Elems {
+				if el.Name != "" {
+					c[ElemLabelKey(m, el)] = Label{el.Label(), el.Descr()}
+				}
+			}
+		}
+	}
+	return c
+}
+
+// ElemLabelKey returns the label key for elem el of model m.
+func ElemLabelKey(m *Model, el *Elem) string {
+	return m.Qualified() + "." + strings.TrimSuffix(el.Name, "?")
+}
+
+// Sync adds missing entries from src and removes entries not in src and returns the sorted keys
+// of added and removed entries.
+func (c Catalog) Sync(src Catalog) (added, removed []string) {
+	for k, l := range src {
+		if _, ok := c[k]; !ok {
+			c[k] = l
+			added = append(added, k)
+		}
+	}
+	for k := range c {
+		if _, ok := src[k]; !ok {
+			delete(c, k)
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// Annotate returns a copy of pro with the label and descr tags of all nodes set to the non-empty
+// catalog entries. Schemas, models and elems are copied and pro is left unchanged. The shared dom
+// schema is never annotated.
+func (c Catalog) Annotate(pro *Project) *Project {
+	res := *pro
+	res.Schemas = make([]*Schema, 0, len(pro.Schemas))
+	for _, s := range pro.Schemas {
+		if s == Dom {
+			res.Schemas = append(res.Schemas, s)
+			continue
+		}
+		cs := *s
+		cs.Extra = c.annotate(s.Name, s.Extra)
+		cs.Models = make([]*Model, 0, len(s.Models))
+		for _, m := range s.Models {
+			cm := *m
+			cm.Extra = c.annotate(m.Qualified(), m.Extra)
+			cm.Elems = make([]*Elem, 0, len(m.Elems))
+			for _, el := range m.Elems {
+				if el.Name != "" {
+					ce := *el
+					ce.Extra = c.annotate(ElemLabelKey(m, el), el.Extra)
+					el = &ce
+				}
+				cm.Elems = append(cm.Elems, el)
+			}
+			cs.Models = append(cs.Models, &cm)
+		}
+		res.Schemas = append(res.Schemas, &cs)
+	}
+	return &res
+}
+
+func (c Catalog) annotate(key string, x *lit.Dict) *lit.Dict {
+	l, ok := c[key]
+	if !ok {
+		return x
+	}
+	d := &lit.Dict{Typ: typ.Dict}
+	if l.Label != "" {
+		d.SetKey("label", lit.Str(l.Label))
+	}
+	if l.Descr != "" {
+		d.SetKey("descr", lit.Str(l.Descr))
+	}
+	return mergeExtra(x, d)
+}
+
+func extraStr(x *lit.Dict, key, def string) string {
+	if x != nil {
+		if v, err := x.Key(key); err == nil {
+			if s, err := lit.ToStr(v); err == nil && s != "" {
+				return string(s)
+			}
+		}
+	}
+	return def
+}
@@ -0,0 +1,71 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/xelf/exp"
+)
+
+func TestLabels(t *testing.T) {
+	raw := `(schema shop label:'Shop' doc:'is a shop.'
+		(Cust; descr:'A customer'
+			ID:int
+			(Name?:str label:'Full name')
+		)
+	)`
+	v, err := exp.NewProg(NewEnv()).RunStr(raw, nil)
+	if err != nil {
+		t.Fatalf("run schema: %v", err)
+	}
+	s := mutPtr(v).(*Schema)
+	pro := &Project{Name: "test", Schemas: []*Schema{Dom, s}}
+	c := NewCatalog(pro)
+	want := Catalog{
+		"shop":           {"Shop", "is a shop."},
+		"shop.Cust":      {"Cust", "A customer"},
+		"shop.Cust.ID":   {"ID", ""},
+		"shop.Cust.Name": {"Full name", ""},
+	}
+	if len(c) != len(want) {
+		t.Errorf("want catalog %v got %v", want, c)
+	}
+	for k, l := range want {
+		if c[k] != l {
+			t.Errorf("catalog %s want %v got %v", k, l, c[k])
+		}
+	}
+	de := Catalog{
+		"shop":         {"Laden", ""},
+		"shop.Cust":    {"Kunde", "Ein Kunde"},
+		"shop.Old":     {"Alt", ""},
+		"shop.Cust.ID": {"", ""},
+	}
+	added, removed := de.Sync(c)
+	if got := strings.Join(added, " "); got != "shop.Cust.Name" {
+		t.Errorf("want added shop.Cust.Name got %s", got)
+	}
+	if got := strings.Join(removed, " "); got != "shop.Old" {
+		t.Errorf("want removed shop.Old got %s", got)
+	}
+	de["shop.Cust.Name"] = Label{Label: "Name"}
+	de["dom"] = Label{Label: "Domain"}
+	res := de.Annotate(pro)
+	if got := Dom.Label(); got == "Domain" {
+		t.Errorf("want shared dom schema unchanged got label %s", got)
+	}
+	if got := s.Label() + "|" + s.Model("cust").Label(); got != "Shop|Cust" {
+		t.Errorf("want source project unchanged got %s", got)
+	}
+	s = res.Schema("shop")
+	m := s.Model("cust")
+	if got := s.Label() + "|" + s.Descr(); got != "Laden|is a shop." {
+		t.Errorf("want schema label Laden got %s", got)
+	}
+	if got := m.Label() + "|" + m.Descr(); got != "Kunde|Ein Kunde" {
+		t.Errorf("want model label Kunde got %s", got)
+	}
+	if got := m.Elems[0].Label() + "|" + m.Elems[1].Label(); got != "ID|Name" {
+		t.Errorf("want elem labels ID|Name got %s", got)
+	}
+}
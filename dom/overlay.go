@@ -0,0 +1,113 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Apply returns a patched copy of schema s. Neither the schema nor its models are changed.
+func (o *Overlay) Apply(s *Schema) (*Schema, error) {
+	if o.Name != s.Name {
+		return nil, fmt.Errorf("overlay %s cannot patch schema %s", o.Name, s.Name)
+	}
+	drop := make(pathSet)
+	for _, path := range o.Drop {
+		keys := strings.Split(strings.ToLower(path), ".")
+		m := s.Model(keys[0])
+		if m == nil || len(keys) > 2 || len(keys) > 1 && m.elem(keys[1]) == nil {
+			return nil, fmt.Errorf("overlay %s: drop %s not found", o.Name, path)
+		}
+		drop.add(path)
+	}
+	res := *s
+	res.Extra = mergeExtra(s.Extra, o.Extra)
+	res.Models = make([]*Model, 0, len(s.Models)+len(o.Models))
+	for _, m := range s.Models {
+		sub, gone := drop.excl(m.Key())
+		if gone {
+			continue
+		}
+		if sub != nil {
+			c := *m
+			c.Elems = make([]*Elem, 0, len(m.Elems))
+			for _, el := range m.Elems {
+				if _, gone := sub.excl(elemPathKey(el)); !gone {
+					c.Elems = append(c.Elems, el)
+				}
+			}
+			m = &c
+		}
+		res.Models = append(res.Models, m)
+	}
+	for _, om := range o.Models {
+		i := modelIndex(res.Models, om.Key())
+		if i < 0 {
+			res.Models = append(res.Models, om)
+			continue
+		}
+		m := *res.Models[i]
+		if m.Kind.Kind != om.Kind.Kind && len(om.Elems) > 0 {
+			return nil, fmt.Errorf("overlay %s: model %s kind %s cannot add %s elems",
+				o.Name, m.Name, m.Kind, om.Kind)
+		}
+		m.Extra = mergeExtra(m.Extra, om.Extra)
+		m.Elems = append(make([]*Elem, 0, len(m.Elems)+len(om.Elems)), m.Elems...)
+		for _, el := range om.Elems {
+			if j := elemIndex(m.Elems, elemPathKey(el)); j >= 0 {
+				m.Elems[j] = el
+			} else {
+				m.Elems = append(m.Elems, el)
+			}
+		}
+		if om.Object != nil {
+			obj := Object{}
+			if m.Object != nil {
+				obj = *m.Object
+			}
+			obj.Indices = append(obj.Indices[:len(obj.Indices):len(obj.Indices)],
+				om.Object.Indices...)
+			if len(om.Object.OrderBy) > 0 {
+				obj.OrderBy = om.Object.OrderBy
+			}
+			obj.Refs = append(make([]*Ref, 0, len(obj.Refs)+len(om.Object.Refs)), obj.Refs...)
+			for _, r := range om.Object.Refs {
+				if j := refIndex(obj.Refs, r.Key); j >= 0 {
+					obj.Refs[j] = r
+				} else {
+					obj.Refs = append(obj.Refs, r)
+				}
+			}
+			obj.Trigs = append(obj.Trigs[:len(obj.Trigs):len(obj.Trigs)], om.Object.Trigs...)
+			m.Object = &obj
+		}
+		res.Models[i] = &m
+	}
+	return &res, nil
+}
+
+func modelIndex(ms []*Model, key string) int {
+	for i, m := range ms {
+		if m.Key() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func elemIndex(els []*Elem, key string) int {
+	for i, el := range els {
+		if elemPathKey(el) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func refIndex(refs []*Ref, key string) int {
+	for i, r := range refs {
+		if r.Key == key {
+			return i
+		}
+	}
+	return -1
+}
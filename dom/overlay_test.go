@@ -0,0 +1,62 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/xelf/lit"
+)
+
+const overlayRaw = `(import 'auth')
+(project site auth.dom
+	(overlay auth nogen; drop:['Sess' 'Acct.Created']
+		(Acct; label:'Account' (Cache?:str priv;))
+		(Note; ID:int Text:str)
+		(Tag; ID:int Note:@Note.ID)
+	)
+)`
+
+func TestOverlay(t *testing.T) {
+	pro, err := ReadProject(lit.NewRegs(), strings.NewReader(overlayRaw), "testdata/site.xelf")
+	if err != nil {
+		t.Fatalf("read project: %v", err)
+	}
+	s := pro.Schema("auth")
+	if s == nil {
+		t.Fatalf("schema auth not found")
+	}
+	if _, err := s.Extra.Key("nogen"); err != nil {
+		t.Errorf("want nogen schema extra got %v", err)
+	}
+	var names []string
+	for _, m := range s.Models {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, " "); got != "Role Acct Cred Note Tag" {
+		t.Errorf("want models Role Acct Cred Note Tag got %s", got)
+	}
+	acct := s.Model("acct")
+	names = names[:0]
+	for _, el := range acct.Elems {
+		names = append(names, strings.TrimSuffix(el.Name, "?"))
+	}
+	if got := strings.Join(names, " "); got != "ID Name Role Rev Cache" {
+		t.Errorf("want acct elems ID Name Role Rev Cache got %s", got)
+	}
+	if v, err := acct.Extra.Key("label"); err != nil {
+		t.Errorf("want acct label got %v", err)
+	} else if s, _ := lit.ToStr(v); s != "Account" {
+		t.Errorf("want acct label Account got %s", v)
+	}
+	if note := s.Model("note"); note == nil || note.Schema != "auth" {
+		t.Errorf("want note model in auth schema got %v", note)
+	}
+	if tag := s.Model("tag"); tag == nil || len(tag.Elems) != 2 ||
+		!strings.Contains(tag.Elems[1].Type.Ref, "Note") {
+		t.Errorf("want tag model with note ref got %v", tag)
+	}
+	o := &Overlay{Name: "auth", Drop: []string{"Acct.Nope"}}
+	if _, err := o.Apply(s); err == nil {
+		t.Errorf("want error for missing drop path")
+	}
+}
@@ -74,41 +74,66 @@ func (r ModelRels) String() string {
 type Relations map[string]*ModelRels
 
 // Relate collects and returns all relations between the models in the given project or an error.
+//
+// Reference elems have a primary key or unique elem type of another model. Relaxed references
+// use a plain type and a ref tag naming the model or elem and are resolved after all other models,
+// so they may point to models declared later or in other schemas, and are ignored if not found.
 func Relate(pro *Project) (Relations, error) {
 	res := make(Relations)
-	// TODO collect relaxed reference in the first iteration
+	var relax []relaxRef
 	for _, s := range pro.Schemas {
 		for _, m := range s.Models {
 			if m.Kind.Kind&knd.Obj == 0 {
 				continue
 			}
-			err := res.relate(pro, s, m)
+			err := res.relate(pro, s, m, &relax)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
+	for _, r := range relax {
+		rel, err := refRelation(pro, r.s, r.m, r.e, r.ref)
+		if err != nil {
+			return nil, err
+		}
+		if rel.B.Model != nil {
+			rel.Rel |= RelRelax
+			res.add(rel)
+		}
+	}
 	// next we check for intermediate model with at least two outgoing foreign key fields
-	// TODO also wire up relaxed relations
+	// and add a relation for every pairwise combination of the referenced models
 	bs := make([]ModelRef, 0, 8)
-	for _, rel := range res {
-		bs = bs[:0]
-		for _, r := range rel.Out {
-			if r.Via.Model == nil && r.B.Key == "_" && r.Rel&RelEmbed == 0 {
-				bs = append(bs, r.B)
+	for _, s := range pro.Schemas {
+		for _, m := range s.Models {
+			rel := res[m.Qualified()]
+			if rel == nil {
+				continue
+			}
+			bs = bs[:0]
+			for _, r := range rel.Out {
+				if r.Via.Model == nil && r.B.Key == "_" && r.Rel&RelEmbed == 0 {
+					bs = append(bs, r.B)
+				}
+			}
+			for i := 0; i < len(bs); i++ {
+				for j := i + 1; j < len(bs); j++ {
+					res.add(Relation{Rel: RelNN | RelInter, Via: ModelRef{m, ""}, A: bs[i], B: bs[j]})
+				}
 			}
 		}
-		if len(bs) < 2 {
-			continue
-		}
-		// TODO add intermediate relation for all combinations
-		rel := Relation{Via: ModelRef{rel.Model, ""}, A: bs[0], B: bs[1]}
-		res.add(rel)
-
 	}
 	return res, nil
 }
 
+type relaxRef struct {
+	s   *Schema
+	m   *Model
+	e   *Elem
+	ref string
+}
+
 func domRef(pro *Project, s *Schema, m *Model, ref string) *Model {
 	if ref == "" {
 		return nil
@@ -129,40 +154,137 @@ func domRef(pro *Project, s *Schema, m *Model, ref string) *Model {
 	return pro.Model(cor.Keyed(ref))
 }
 
-func (res *Relations) relate(pro *Project, s *Schema, m *Model) error {
+func (res Relations) relate(pro *Project, s *Schema, m *Model, relax *[]relaxRef) error {
 	for _, e := range m.Elems {
-		rel := Relation{A: ModelRef{m, e.Key()}}
-		ref := e.Type.Ref
-		if ref != "" && e.Type.Kind&knd.Ref != 0 {
-			rel.B.Model = domRef(pro, s, m, ref)
-			// TODO check field type if uuid or cont|uuid or other
-			rel.B.Key = "_" // signifies primary key
-			if e.Bits&BitUniq != 0 {
-				rel.Rel = Rel11
-			} else {
-				rel.Rel = RelN1
+		if ref := extraStr(e.Extra, "ref", ""); ref != "" {
+			*relax = append(*relax, relaxRef{s, m, e, ref})
+			continue
+		}
+		var rel Relation
+		if ref := fieldRef(pro, s, m, e); ref != "" {
+			var err error
+			rel, err = refRelation(pro, s, m, e, ref)
+			if err != nil {
+				return err
+			}
+			if rel.B.Model == nil {
+				return fmt.Errorf("model ref not found ref %s %s typ %s", m.Qualified(), ref, e.Type)
 			}
 		} else if embed, many := isEmbed(e.Type); embed {
 			// embedded schema type
 			lt := typ.Last(e.Type)
+			rel.A = ModelRef{m, e.Key()}
 			rel.B.Model = domRef(pro, s, m, lt.Ref)
-			ref = lt.Ref
 			if many {
 				rel.Rel = Rel1N | RelEmbed
 			} else {
 				rel.Rel = Rel11 | RelEmbed
 			}
+			if rel.B.Model == nil {
+				return fmt.Errorf("model ref not found ref %s %s typ %s", m.Qualified(), lt.Ref, lt)
+			}
 		} else {
 			continue
 		}
-		if rel.B.Model == nil {
-			return fmt.Errorf("model ref not found ref %s %s typ %s", m.Qualified(), ref, typ.Last(e.Type))
-		}
 		res.add(rel)
 	}
 	return nil
 }
 
+// fieldRef returns the reference name if elem e of model m in schema s refers to an elem of
+// another model. Field type refs to elems that are neither primary key nor unique only reuse the
+// type and are no reference. Use an explicit ref tag for those.
+func fieldRef(pro *Project, s *Schema, m *Model, e *Elem) string {
+	lt := typ.Last(e.Type)
+	if lt.Ref == "" {
+		return ""
+	}
+	if lt.Kind&knd.Ref != 0 {
+		return lt.Ref
+	}
+	if strings.Count(lt.Ref, ".") == 2 && !strings.EqualFold(lt.Ref, m.Qualified()+"."+elemPathKey(e)) {
+		if keyRef(pro, s, m, lt.Ref) {
+			return lt.Ref
+		}
+	}
+	return ""
+}
+
+// keyRef returns whether the elem ref of the form 'schema.Model.Elem' is a primary key or unique.
+// Unknown models and elems are reported as key refs to fail later with a helpful error.
+func keyRef(pro *Project, s *Schema, m *Model, ref string) bool {
+	idx := strings.LastIndexByte(ref, '.')
+	b := domRef(pro, s, m, ref[:idx])
+	if b == nil {
+		return true
+	}
+	f := b.elem(strings.ToLower(ref[idx+1:]))
+	return f == nil || f.Bits&(BitPK|BitUniq) != 0
+}
+
+// refRelation returns a relation for elem e referencing a model or model elem by ref. The returned
+// relation has no b model if it was not found. The referenced elem defaults to the primary key
+// and must have the same type as e.
+func refRelation(pro *Project, s *Schema, m *Model, e *Elem, ref string) (rel Relation, _ error) {
+	rel.A = ModelRef{m, e.Key()}
+	b, key := domRef(pro, s, m, ref), ""
+	if b == nil {
+		if idx := strings.LastIndexByte(ref, '.'); idx > 0 {
+			b, key = domRef(pro, s, m, ref[:idx]), strings.ToLower(ref[idx+1:])
+		}
+	}
+	if b == nil {
+		return rel, nil
+	}
+	var f *Elem
+	pks := b.PK()
+	if key == "" {
+		if len(pks) > 1 {
+			return rel, fmt.Errorf("ref %s.%s: model %s has a composite primary key, "+
+				"refer to one of its elems instead", m.Qualified(), e.Name, b.Qualified())
+		}
+		if len(pks) > 0 {
+			f = pks[0]
+		}
+	} else {
+		f = b.elem(key)
+	}
+	if f == nil {
+		return rel, fmt.Errorf("ref %s.%s: elem %s not found", m.Qualified(), e.Name, ref)
+	}
+	et, ft := typ.Last(e.Type), f.Type
+	if et.Kind&knd.Data != ft.Kind&knd.Data {
+		return rel, fmt.Errorf("ref %s.%s: type %s does not match %s.%s type %s",
+			m.Qualified(), e.Name, et, b.Qualified(), f.Name, ft)
+	}
+	rel.B.Model = b
+	if f.Bits&BitPK != 0 && len(pks) == 1 {
+		rel.B.Key = "_" // signifies primary key
+	} else {
+		rel.B.Key = f.Key()
+	}
+	if e.Type.Kind&knd.List != 0 {
+		rel.Rel = RelNN
+	} else if e.Bits&BitUniq != 0 {
+		rel.Rel = Rel11
+	} else {
+		rel.Rel = RelN1
+	}
+	return rel, nil
+}
+
+// Embedded returns whether model m is embedded in another model.
+func (rs Relations) Embedded(m *Model) bool {
+	if r := rs[m.Qualified()]; r != nil {
+		for _, in := range r.In {
+			if in.Rel&RelEmbed != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (rs Relations) add(r Relation) {
 	a := rs.upsert(r.A.Model)
 	a.Out = append(a.Out, r)
@@ -188,3 +310,16 @@ func isEmbed(t typ.Type) (yes, many bool) {
 	l := typ.Last(t)
 	return l.Ref != "" && l.Kind&(knd.Bits|knd.Obj) != 0, t.Kind&knd.List != 0
 }
+
+// OnDel returns the referential delete action declared on the reference elem with key or an empty
+// string if the reference has no action.
+func (m *Model) OnDel(key string) RefAct {
+	if m.Object != nil {
+		for _, r := range m.Object.Refs {
+			if r.Key == key {
+				return r.OnDel
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package dom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"xelf.org/xelf/exp"
+)
+
+func TestRelate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+		err  string
+	}{
+		{`(schema test
+			(Tag; ID:int Name:str)
+			(Post; ID:int Title:str (Owner:int ref:'User'))
+			(User; ID:int Name:str)
+			(Link; ID:int @Tag.ID @Post.ID @User.ID)
+			(Team; ID:int Members:list|@User.ID)
+		)`, []string{
+			fmt.Sprintf("test.Link.post>test.Post._ %d", RelN1),
+			fmt.Sprintf("test.Link.tag>test.Tag._ %d", RelN1),
+			fmt.Sprintf("test.Link.user>test.User._ %d", RelN1),
+			fmt.Sprintf("test.Post._>test.Link>test.User._ %d", RelNN|RelInter),
+			fmt.Sprintf("test.Post.owner>test.User._ %d", RelN1|RelRelax),
+			fmt.Sprintf("test.Tag._>test.Link>test.Post._ %d", RelNN|RelInter),
+			fmt.Sprintf("test.Tag._>test.Link>test.User._ %d", RelNN|RelInter),
+			fmt.Sprintf("test.Team.members>test.User._ %d", RelNN),
+		}, ""},
+		{`(schema test
+			(Tag; ID:int Name:str)
+			(Post; ID:int Title:str)
+			(Tagged; (Tag:@Tag.ID pk;) (Post:@Post.ID pk;))
+			(Note; ID:int (Tag:int ref:'Tagged.Tag'))
+		)`, []string{
+			fmt.Sprintf("test.Note.tag>test.Tagged.tag %d", RelN1|RelRelax),
+			fmt.Sprintf("test.Tag._>test.Tagged>test.Post._ %d", RelNN|RelInter),
+			fmt.Sprintf("test.Tagged.post>test.Post._ %d", RelN1),
+			fmt.Sprintf("test.Tagged.tag>test.Tag._ %d", RelN1),
+		}, ""},
+		{`(schema test
+			(Acct; ID:int (Mail:str uniq;) Name:str)
+			(Cred; ID:int Acct:@Acct.Mail Name:@Acct.Name)
+		)`, []string{
+			fmt.Sprintf("test.Cred.acct>test.Acct.mail %d", RelN1),
+		}, ""},
+		{`(schema test (Node; ID:int (Other:int ref:'Missing')))`, nil, ""},
+		{`(schema test (Pair; (A:int pk;) (B:int pk;)) (Node; ID:int (Par:int ref:'Pair')))`, nil,
+			"composite primary key"},
+		{`(schema test (Node; ID:int (Par:str ref:'Node')))`, nil, "does not match"},
+	}
+	for _, test := range tests {
+		v, err := exp.NewProg(NewEnv()).RunStr(test.raw, nil)
+		if err != nil {
+			t.Errorf("run %s: %v", test.raw, err)
+			continue
+		}
+		s := mutPtr(v).(*Schema)
+		rels, err := Relate(&Project{Name: "test", Schemas: []*Schema{s}})
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("relate %s want error %q got %v", test.raw, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("relate %s: %v", test.raw, err)
+			continue
+		}
+		var got []string
+		for _, mr := range rels {
+			for _, r := range mr.Out {
+				if r.Via.Model != nil {
+					got = append(got, fmt.Sprintf("%s>%s>%s %d", r.A, r.Via, r.B, r.Rel))
+				} else {
+					got = append(got, fmt.Sprintf("%s>%s %d", r.A, r.B, r.Rel))
+				}
+			}
+		}
+		sort.Strings(got)
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("relate %s want %q got %q", test.raw, test.want, got)
+		}
+	}
+}
+
+func TestRefActs(t *testing.T) {
+	raw := `(schema test
+		(User; ID:int Name:str)
+		(Post; ID:int
+			(Owner:@User.ID ondel:cascade)
+			(Editor?:@User.ID ondel:'setnull')
+			trignew:[{top:'test.user' key:'1' cmd:'mod' arg:{name:'poster'}}]
+		)
+	)`
+	v, err := exp.NewProg(NewEnv()).RunStr(raw, nil)
+	if err != nil {
+		t.Fatalf("run %s: %v", raw, err)
+	}
+	m := mutPtr(v).(*Schema).Model("post")
+	for key, want := range map[string]RefAct{
+		"owner":  RefActCascade,
+		"editor": RefActSetNull,
+		"id":     "",
+	} {
+		if got := m.OnDel(key); got != want {
+			t.Errorf("ondel %s want %q got %q", key, want, got)
+		}
+	}
+	if m.Object == nil || len(m.Object.Trigs) != 1 {
+		t.Fatalf("want one trigger got %v", m.Object)
+	}
+	if tr := m.Object.Trigs[0]; tr.Cmd != "new" || !strings.HasPrefix(tr.Src, "[{") {
+		t.Errorf("unexpected trigger %s %s", tr.Cmd, tr.Src)
+	}
+	_, err = exp.NewProg(NewEnv()).RunStr(`(schema test (User; ID:int)
+		(Post; ID:int (Owner:@User.ID ondel:drop)))`, nil)
+	if err == nil || !strings.Contains(err.Error(), "expects restrict, cascade or setnull") {
+		t.Errorf("want ondel error got %v", err)
+	}
+}
@@ -22,6 +22,8 @@ type domSpec struct {
 	modHook  func(*exp.Prog, *mod.ModEnv, ext.Node)
 	subSpec  exp.Spec
 	dotHook  dotLookup
+	// rawDecl leaves declarations unresolved for the decl rule
+	rawDecl bool
 }
 
 func (s *domSpec) Resl(p *exp.Prog, env exp.Env, c *exp.Call, h typ.Type) (_ exp.Exp, err error) {
@@ -32,6 +34,7 @@ func (s *domSpec) Resl(p *exp.Prog, env exp.Env, c *exp.Call, h typ.Type) (_ exp
 		if err != nil {
 			return nil, err
 		}
+		posExtra(p, n.Ptr(), c.Src)
 		ne = &NodeEnv{Node: n, Sub: s.subSpec, dot: s.dotHook}
 		if s.modHook != nil {
 			ne.ModEnv = mod.NewModEnv(env, &p.File)
@@ -93,6 +96,7 @@ func (s *domSpec) Resl(p *exp.Prog, env exp.Env, c *exp.Call, h typ.Type) (_ exp
 		} else if pa.Kind&knd.Tupl != 0 {
 			tup := a.(*exp.Tupl)
 			for j, d := range tup.Els {
+				ne.decl = d.Source()
 				var key string
 				if t, ok := d.(*exp.Tag); ok {
 					key = t.Tag
@@ -103,7 +107,7 @@ func (s *domSpec) Resl(p *exp.Prog, env exp.Env, c *exp.Call, h typ.Type) (_ exp
 				if istag {
 					r = s.Rules.Eval
 				}
-				if d != nil {
+				if d != nil && (istag || !s.rawDecl) {
 					d, err = p.Resl(c.Env, d, typ.Void)
 					if err != nil {
 						return nil, err
@@ -137,6 +141,8 @@ type NodeEnv struct {
 	ext.Node
 	dot dotLookup
 	Sub exp.Spec
+	// decl is the source of the declaration passed to the decl rule
+	decl ast.Src
 }
 
 func (e *NodeEnv) Lookup(s *exp.Sym, path cor.Path, eval bool) (lit.Val, error) {
@@ -160,6 +166,33 @@ func (e *NodeEnv) Lookup(s *exp.Sym, path cor.Path, eval bool) (lit.Val, error)
 	return e.ModEnv.Lookup(s, p, eval)
 }
 
+// posExtra sets the pos extra of a project, schema, model or elem node to the line and column of
+// src. Like the file extra it is only set for programs read from a file and never overwritten.
+func posExtra(p *exp.Prog, node any, src ast.Src) {
+	if p.File.URL == "" || src.Pos.Line <= 0 {
+		return
+	}
+	var x **lit.Dict
+	switch n := node.(type) {
+	case *Project:
+		x = &n.Extra
+	case *Schema:
+		x = &n.Extra
+	case *Model:
+		x = &n.Extra
+	case *Elem:
+		x = &n.Extra
+	default:
+		return
+	}
+	if *x == nil {
+		*x = &lit.Dict{Typ: typ.Dict}
+	} else if _, err := (*x).Key("pos"); err == nil {
+		return
+	}
+	(*x).SetKey("pos", lit.Str(fmt.Sprintf("%d:%d", src.Pos.Line, src.Pos.Col)))
+}
+
 type any = interface{}
 type dotLookup func(*NodeEnv, cor.Path) lit.Val
 
@@ -35,17 +35,76 @@ func schemaPrep(p *exp.Prog, env exp.Env, n ext.Node, k string, e exp.Exp) (lit.
 	if err != nil {
 		return nil, err
 	}
-	s, ok := mutPtr(a).(*Schema)
-	if !ok {
+	pro := n.Ptr().(*Project)
+	switch v := mutPtr(a).(type) {
+	case *App:
+		if v.Project != "" && v.Project != pro.Name {
+			return nil, fmt.Errorf("app %s already part of project %s", v.Name, v.Project)
+		}
+		v.Project = pro.Name
+		pro.Apps = append(pro.Apps, v)
+	case *Overlay:
+		for i, s := range pro.Schemas {
+			if s.Name == v.Name {
+				ps, err := v.Apply(s)
+				if err != nil {
+					return nil, err
+				}
+				pro.Schemas[i] = ps
+				return a, nil
+			}
+		}
+		return nil, fmt.Errorf("overlay %s: schema not found in project", v.Name)
+	case *Schema:
+		if len(pro.Schemas) == 0 {
+			// fresh project, we always want at least the dom schema we probably want to…
+			// TODO auto-add the mig schema too, but have a dependency problem
+			pro.Schemas = append(pro.Schemas, Dom)
+		}
+		pro.Schemas = append(pro.Schemas, v)
+	default:
 		return nil, fmt.Errorf("expected *Schema got %s", a.Value())
 	}
-	pro := n.Ptr().(*Project)
-	if len(pro.Schemas) == 0 {
-		// fresh project, we always want at least the dom schema we probably want to…
-		// TODO auto-add the mig schema too, but have a dependency problem
-		pro.Schemas = append(pro.Schemas, Dom)
+	return a, nil
+}
+
+var appSpec = prep("<form@app name:sym tags:tupl?|exp @>", &App{}, &domSpec{
+	Rules: ext.Rules{
+		Key: map[string]ext.Rule{
+			"include": pathRule,
+			"exclude": pathRule,
+		},
+		Default: ext.Rule{Setter: ext.ExtraSetter("extra")},
+	},
+	nodeProv: func(p *exp.Prog) any { return &App{Extra: fileExtra(p.File.URL)} },
+})
+
+var overlaySpec = prep("<form@overlay name:sym tags:tupl?|exp @>", &Overlay{}, &domSpec{
+	Rules: ext.Rules{
+		Key:     map[string]ext.Rule{"drop": pathRule},
+		Default: ext.Rule{Setter: ext.ExtraSetter("extra")},
+	},
+	declRule: overlayPrep,
+	subSpec:  modelSpec,
+})
+
+func overlayPrep(p *exp.Prog, env exp.Env, n ext.Node, key string, e exp.Exp) (lit.Val, error) {
+	a, err := p.Eval(env, e)
+	if err != nil {
+		return nil, err
 	}
-	pro.Schemas = append(pro.Schemas, s)
+	m, ok := mutPtr(a).(*Model)
+	if !ok {
+		return nil, fmt.Errorf("expected *Model got %s", a.Value())
+	}
+	o := n.Ptr().(*Overlay)
+	err = qualifyModel(m, o.Name)
+	if err != nil {
+		return nil, err
+	}
+	o.Models = append(o.Models, m)
+	ne := env.(*NodeEnv)
+	ne.AddDecl(m.Name, m.Type())
 	return a, nil
 }
 
@@ -62,11 +121,19 @@ func modelPrep(p *exp.Prog, env exp.Env, n ext.Node, key string, e exp.Exp) (lit
 	if err != nil {
 		return nil, err
 	}
+	s := n.Ptr().(*Schema)
+	if q, ok := mutPtr(a).(*Query); ok {
+		if q.Schema != "" {
+			return nil, fmt.Errorf("query %s already part of schema %s", q.Name, q.Schema)
+		}
+		q.Schema = s.Name
+		s.Queries = append(s.Queries, q)
+		return a, nil
+	}
 	m, ok := mutPtr(a).(*Model)
 	if !ok {
 		return nil, fmt.Errorf("expected *Model got %s", a.Value())
 	}
-	s := n.Ptr().(*Schema)
 	err = qualifyModel(m, s.Name)
 	if err != nil {
 		return nil, err
@@ -77,11 +144,32 @@ func modelPrep(p *exp.Prog, env exp.Env, n ext.Node, key string, e exp.Exp) (lit
 	return a, nil
 }
 
+var querySpec = prep("<form@query name:sym tags:tupl?|exp @>", &Query{}, &domSpec{
+	Rules:    ext.Rules{Default: ext.Rule{Setter: ext.ExtraSetter("extra")}},
+	declRule: queryPrep,
+	rawDecl:  true,
+})
+
+func queryPrep(p *exp.Prog, env exp.Env, n ext.Node, key string, e exp.Exp) (lit.Val, error) {
+	q := n.Ptr().(*Query)
+	if e == nil || key != "" {
+		return nil, fmt.Errorf("query %s expects a plain query expression", q.Name)
+	}
+	if q.Src != "" {
+		return nil, fmt.Errorf("query %s has more than one query expression", q.Name)
+	}
+	q.Src = e.String()
+	return nil, nil
+}
+
 var modelSpec = prep("<form@model name:sym kind:typ tags:tupl?|exp @dom.Model>", &Model{}, &domSpec{
 	Rules: ext.Rules{
 		Key: map[string]ext.Rule{
-			"idx":  idxRule,
-			"uniq": idxRule,
+			"idx":     idxRule,
+			"uniq":    idxRule,
+			"trignew": trigRule,
+			"trigmod": trigRule,
+			"trigdel": trigRule,
 		},
 		Default: ext.Rule{Setter: ext.ExtraSetter("extra")},
 	},
@@ -113,6 +201,10 @@ var elemSpec = prep("<form@elem name:sym type:typ tupl?|tag @>", &Elem{}, &domSp
 			"desc": bitRule,
 			"auto": bitRule,
 			"ro":   bitRule,
+
+			"default": srcRule,
+			"calc":    srcRule,
+			"ondel":   refActRule,
 		},
 		Default: ext.Rule{Setter: ext.ExtraSetter("extra")},
 	},
@@ -129,42 +221,117 @@ func fileExtra(url string) *lit.Dict {
 
 func idxAppender(p *exp.Prog, env exp.Env, n ext.Node, s string, arg exp.Exp) (_ lit.Val, err error) {
 	m := n.Ptr().(*Model)
-	switch a := arg.(type) {
-	case nil:
+	if arg == nil {
 		return nil, fmt.Errorf("index %s with nil arg", s)
-	case *exp.Lit:
-		switch av := a.Val.(type) {
-		case *lit.Vals:
-			if m.Object == nil {
-				m.Object = &Object{}
-			}
-			idx := &Index{Unique: s == "uniq"}
-			for _, v := range *av {
-				s, err := lit.ToStr(v)
-				if err != nil {
-					return nil, err
-				}
-				idx.Keys = append(idx.Keys, string(s))
-			}
-			m.Object.Indices = append(m.Object.Indices, idx)
-		case lit.Char:
-			if m.Object == nil {
-				m.Object = &Object{}
+	}
+	keys, err := argStrs(arg)
+	if err != nil {
+		return nil, fmt.Errorf("index %s %v", s, err)
+	}
+	if m.Object == nil {
+		m.Object = &Object{}
+	}
+	idx := &Index{Unique: s == "uniq", Keys: keys}
+	m.Object.Indices = append(m.Object.Indices, idx)
+	return nil, nil
+}
+
+func pathAppender(p *exp.Prog, env exp.Env, n ext.Node, s string, arg exp.Exp) (_ lit.Val, err error) {
+	if arg == nil {
+		return nil, fmt.Errorf("path %s with nil arg", s)
+	}
+	paths, err := argStrs(arg)
+	if err != nil {
+		return nil, fmt.Errorf("path %s %v", s, err)
+	}
+	switch x := n.Ptr().(type) {
+	case *App:
+		if s == "include" {
+			x.Include = append(x.Include, paths...)
+		} else {
+			x.Exclude = append(x.Exclude, paths...)
+		}
+	case *Overlay:
+		x.Drop = append(x.Drop, paths...)
+	}
+	return nil, nil
+}
+
+// argStrs returns the strings of a resolved literal list or char argument.
+func argStrs(arg exp.Exp) ([]string, error) {
+	a, ok := arg.(*exp.Lit)
+	if !ok {
+		return nil, fmt.Errorf("unexpected arg %T", arg)
+	}
+	switch av := a.Val.(type) {
+	case *lit.Vals:
+		res := make([]string, 0, len(*av))
+		for _, v := range *av {
+			s, err := lit.ToStr(v)
+			if err != nil {
+				return nil, err
 			}
-			idx := &Index{Unique: s == "uniq", Keys: []string{av.String()}}
-			m.Object.Indices = append(m.Object.Indices, idx)
-		default:
-			return nil, fmt.Errorf("index %s unexpected value %T", s, av)
+			res = append(res, string(s))
+		}
+		return res, nil
+	case lit.Char:
+		return []string{av.String()}, nil
+	}
+	return nil, fmt.Errorf("unexpected value %T", a.Val)
+}
+
+func trigAppender(p *exp.Prog, env exp.Env, n ext.Node, s string, arg exp.Exp) (lit.Val, error) {
+	m := n.Ptr().(*Model)
+	if arg == nil {
+		return nil, fmt.Errorf("trigger %s with nil arg", s)
+	}
+	if m.Object == nil {
+		m.Object = &Object{}
+	}
+	t := &Trigger{Cmd: strings.TrimPrefix(s, "trig"), Src: arg.String()}
+	m.Object.Trigs = append(m.Object.Trigs, t)
+	return nil, nil
+}
+
+func refActPrepper(p *exp.Prog, env exp.Env, n ext.Node, key string, arg exp.Exp) (lit.Val, error) {
+	var s string
+	switch a := arg.(type) {
+	case *exp.Sym:
+		s = a.Sym
+	case *exp.Lit:
+		str, err := lit.ToStr(a.Val)
+		if err != nil {
+			return nil, err
 		}
-		return nil, nil
+		s = string(str)
 	}
-	return nil, fmt.Errorf("index %s unexpected arg %T", s, arg)
+	switch act := RefAct(strings.ToLower(s)); act {
+	case RefActRestrict, RefActCascade, RefActSetNull:
+		return lit.Str(act), nil
+	}
+	return nil, fmt.Errorf("%s expects restrict, cascade or setnull got %s", key, s)
+}
+
+func srcPrepper(p *exp.Prog, env exp.Env, n ext.Node, key string, arg exp.Exp) (lit.Val, error) {
+	if arg == nil {
+		return nil, fmt.Errorf("%s with nil arg", key)
+	}
+	return lit.Str(arg.String()), nil
 }
 
 func noopSetter(p *exp.Prog, n ext.Node, key string, v lit.Val) error { return nil }
 
 var idxRule = ext.Rule{Prepper: idxAppender, Setter: noopSetter}
 
+var pathRule = ext.Rule{Prepper: pathAppender, Setter: noopSetter}
+
+var trigRule = ext.Rule{Prepper: trigAppender, Setter: noopSetter}
+
+var refActRule = ext.Rule{Prepper: refActPrepper, Setter: ext.ExtraSetter("extra")}
+
+// srcRule stores the unresolved argument expression as source string in the extra dict.
+var srcRule = ext.Rule{Prepper: srcPrepper, Setter: ext.ExtraSetter("extra")}
+
 var bitRule = ext.Rule{Prepper: ext.BitsPrepper(bitConsts), Setter: ext.BitsSetter("bits")}
 
 func elemsPrepper(p *exp.Prog, env exp.Env, n ext.Node, key string, arg exp.Exp) (_ lit.Val, err error) {
@@ -204,6 +371,16 @@ func elemsPrepper(p *exp.Prog, env exp.Env, n ext.Node, key string, arg exp.Exp)
 			}
 		}
 	case knd.Obj, knd.Func:
+		if c, ok := arg.(*exp.Call); ok && key != "" && k == knd.Obj {
+			// computed elem declared with a plain expression
+			el.Type = calcType(m, c)
+			el.Extra = &lit.Dict{Typ: typ.Dict}
+			el.Extra.SetKey("calc", lit.Str(c.String()))
+			if strings.HasSuffix(el.Name, "?") {
+				el.Bits |= BitOpt
+			}
+			break
+		}
 		if arg == nil {
 			t, fst, err := refElemType(p, env, key)
 			if err != nil {
@@ -259,6 +436,9 @@ func elemsPrepper(p *exp.Prog, env exp.Env, n ext.Node, key string, arg exp.Exp)
 			el.Bits |= BitOpt
 		}
 	}
+	if ne, ok := env.(*NodeEnv); ok {
+		posExtra(p, el, ne.decl)
+	}
 	m.Elems = append(m.Elems, el)
 	return nil, nil
 }
@@ -277,6 +457,12 @@ func qualifyModel(m *Model, sch string) error {
 				}
 				return e.Type, nil
 			})
+			if act := extraStr(el.Extra, "ondel", ""); act != "" {
+				if m.Object == nil {
+					m.Object = &Object{}
+				}
+				m.Object.Refs = append(m.Object.Refs, &Ref{Key: elemPathKey(el), OnDel: RefAct(act)})
+			}
 		}
 	}
 	return nil
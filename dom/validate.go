@@ -0,0 +1,229 @@
+package dom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Severity indicates whether a diagnostic is a warning or an error.
+type Severity uint8
+
+const (
+	SevWarn Severity = 1 + iota
+	SevError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SevWarn:
+		return "warning"
+	case SevError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Diagnostic describes a problem found in a project. Loc is the file url of the schema or project
+// declaring the node followed by the line and column of the node form, if known, or empty. Node is
+// the qualified name of the schema, model or elem.
+type Diagnostic struct {
+	Severity
+	Loc  string
+	Node string
+	Msg  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Loc == "" {
+		return fmt.Sprintf("%s %s: %s", d.Severity, d.Node, d.Msg)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", d.Loc, d.Severity, d.Node, d.Msg)
+}
+
+// HasErrors returns whether any of the diagnostics is an error.
+func HasErrors(ds []Diagnostic) bool {
+	for _, d := range ds {
+		if d.Severity == SevError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the project pro for common mistakes and returns a list of all diagnostics.
+//
+// It reports obj models without primary key, optional composite primary key elems, references to
+// unknown models or elems and to models without primary key, duplicate elem keys after flattening
+// embedded models, index and order keys naming unknown elems, invalid elem constraints or
+// defaults, referential actions on elems that are no primary key references, unreadable triggers,
+// and duplicate or colliding enum and bits constants.
+func Validate(pro *Project) []Diagnostic {
+	v := &validator{pro: pro, loc: extraStr(pro.Extra, "file", "")}
+	rels, err := Relate(pro)
+	if err != nil {
+		v.add(SevError, pro.Extra, pro.Qualified(), "%v", err)
+	}
+	for _, s := range pro.Schemas {
+		v.loc = extraStr(s.Extra, "file", extraStr(pro.Extra, "file", ""))
+		names := make(map[string]bool, len(s.Models))
+		for _, m := range s.Models {
+			if names[m.Key()] {
+				v.add(SevError, m.Extra, m.Qualified(), "duplicate model name")
+			}
+			names[m.Key()] = true
+			switch m.Kind.Kind {
+			case knd.Obj:
+				v.obj(s, m, rels)
+			case knd.Enum, knd.Bits:
+				v.consts(m)
+			}
+		}
+	}
+	return v.res
+}
+
+type validator struct {
+	pro *Project
+	loc string
+	res []Diagnostic
+}
+
+// add adds a diagnostic for node. The pos tag of the node extra x is appended to the location.
+func (v *validator) add(sev Severity, x *lit.Dict, node, msg string, args ...interface{}) {
+	loc := v.loc
+	if pos := extraStr(x, "pos", ""); pos != "" && loc != "" {
+		loc += ":" + pos
+	}
+	v.res = append(v.res, Diagnostic{sev, loc, node, fmt.Sprintf(msg, args...)})
+}
+
+func (v *validator) obj(s *Schema, m *Model, rels Relations) {
+	pks := m.PK()
+	if len(pks) == 0 && s != Dom && !rels.Embedded(m) {
+		v.add(SevWarn, m.Extra, m.Qualified(), "obj model without primary key")
+	}
+	for _, el := range pks {
+		if len(pks) > 1 && el.Bits&BitOpt != 0 {
+			v.add(SevError, el.Extra, m.Qualified()+"."+elemPathKey(el),
+				"optional composite primary key elem")
+		}
+	}
+	keys := make(map[string]bool, len(m.Elems))
+	b, _ := m.Type().Body.(*typ.ParamBody)
+	if b != nil {
+		for _, p := range b.Params {
+			if p.Key == "" {
+				continue
+			}
+			if keys[p.Key] {
+				v.add(SevError, m.Extra, m.Qualified(), "duplicate elem key %s", p.Key)
+			}
+			keys[p.Key] = true
+		}
+	}
+	for _, el := range m.Elems {
+		if el.Name != "" {
+			v.ref(s, m, el)
+			if _, err := ElemConstr(el); err != nil {
+				v.add(SevError, el.Extra, m.Qualified()+"."+el.Name, "%v", err)
+			}
+			if _, err := el.Default(time.Time{}); err != nil {
+				v.add(SevError, el.Extra, m.Qualified()+"."+el.Name, "%v", err)
+			}
+		}
+	}
+	if m.Object == nil {
+		return
+	}
+	for _, idx := range m.Object.Indices {
+		for _, k := range idx.Keys {
+			if !keys[strings.ToLower(k)] {
+				v.add(SevError, m.Extra, m.Qualified(), "index key %s not found", k)
+			}
+		}
+	}
+	for _, k := range m.Object.OrderBy {
+		if !keys[strings.ToLower(strings.TrimLeft(k, "-+"))] {
+			v.add(SevError, m.Extra, m.Qualified(), "order key %s not found", k)
+		}
+	}
+	for _, r := range m.Object.Refs {
+		el := m.elem(r.Key)
+		if el == nil {
+			v.add(SevError, m.Extra, m.Qualified(), "ondel key %s not found", r.Key)
+		} else if fieldRef(v.pro, s, m, el) == "" && extraStr(el.Extra, "ref", "") == "" {
+			v.add(SevError, el.Extra, m.Qualified()+"."+el.Name,
+				"ondel %s on elem that is no reference", r.OnDel)
+		} else if otherKeyRef(rels, m, el) {
+			v.add(SevError, el.Extra, m.Qualified()+"."+el.Name,
+				"ondel %s on reference that is not to a primary key", r.OnDel)
+		} else if r.OnDel == RefActSetNull && el.Bits&BitOpt == 0 && el.Type.Kind&knd.List == 0 {
+			v.add(SevWarn, el.Extra, m.Qualified()+"."+el.Name,
+				"ondel setnull on elem that is not optional")
+		}
+	}
+	for _, t := range m.Object.Trigs {
+		if _, err := exp.Read(strings.NewReader(t.Src), m.Qualified()); err != nil {
+			v.add(SevError, m.Extra, m.Qualified(), "trigger trig%s: %v", t.Cmd, err)
+		}
+	}
+}
+
+// ref checks elements with field reference types of the form 'schema.Model.Elem'.
+func (v *validator) ref(s *Schema, m *Model, el *Elem) {
+	ref := fieldRef(v.pro, s, m, el)
+	node := m.Qualified() + "." + el.Name
+	parts := strings.Split(ref, ".")
+	if len(parts) != 3 {
+		return
+	}
+	rm := v.pro.Schema(parts[0]).Model(strings.ToLower(parts[1]))
+	if rm == nil {
+		v.add(SevError, el.Extra, node, "reference to unknown model %s", ref)
+		return
+	}
+	re := rm.elem(strings.ToLower(parts[2]))
+	if re == nil {
+		v.add(SevError, el.Extra, node, "reference to unknown elem %s", ref)
+	} else if !hasPK(rm) {
+		v.add(SevError, el.Extra, node, "reference to model %s without primary key", rm.Qualified())
+	} else if re.Bits&BitUniq == 0 && (re.Bits&BitPK == 0 || len(rm.PK()) > 1) {
+		v.add(SevWarn, el.Extra, node, "reference to %s that is neither primary key nor unique", ref)
+	}
+}
+
+func (v *validator) consts(m *Model) {
+	names := make(map[string]bool, len(m.Elems))
+	vals := make(map[int64]string, len(m.Elems))
+	for _, el := range m.Elems {
+		if names[el.Key()] {
+			v.add(SevError, el.Extra, m.Qualified(), "duplicate constant name %s", el.Name)
+		}
+		names[el.Key()] = true
+		if o, ok := vals[el.Val]; ok {
+			v.add(SevError, el.Extra, m.Qualified(), "constant %s value %d collides with %s",
+				el.Name, el.Val, o)
+			continue
+		}
+		vals[el.Val] = el.Name
+	}
+}
+
+// otherKeyRef returns whether elem el of model m references a unique elem instead of the primary
+// key. Referential actions are only applied to primary key references.
+func otherKeyRef(rels Relations, m *Model, el *Elem) bool {
+	if r := rels[m.Qualified()]; r != nil {
+		for _, o := range r.Out {
+			if o.A.Key == el.Key() && o.Via.Model == nil && o.Rel&RelEmbed == 0 && o.B.Key != "_" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lit"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{`(schema test (Node; ID:int Name:str))`, nil},
+		{`(schema test (Node; Name:str))`, []string{
+			"warning test.Node: obj model without primary key",
+		}},
+		{`(schema test (Pos; X:int Y:int) (Node; ID:int @Pos))`, nil},
+		{`(schema test (Pos; X:int Name:str) (Node; ID:int Name:str @Pos))`, []string{
+			"error test.Node: duplicate elem key name",
+		}},
+		{`(schema test (Node; ID:int Name:str idx:['name' 'nope']))`, []string{
+			"error test.Node: index key nope not found",
+		}},
+		{`(schema test (Node; ID:int (Name:str len:0)))`, []string{
+			"error test.Node.Name: elem Name constraint len: expect positive length got 0",
+		}},
+		{`(schema test (Node; ID:int (Qty:int min:1.5)))`, []string{
+			"error test.Node.Qty: elem Qty constraint min: expect integer for int elem got 1.5",
+		}},
+		{`(schema test (Node; ID:int (At:time pattern:'^2')))`, []string{
+			"error test.Node.At: elem At constraint pattern: expect str or raw elem got <time>",
+		}},
+		{`(schema test (Node; ID:int (Kind:str oneof:['a' 1])))`, []string{
+			"error test.Node.Kind: elem Kind constraint oneof: expect str value got 1",
+		}},
+		{`(schema test (Kind:enum A; B:1 C;))`, []string{
+			"error test.Kind: constant B value 1 collides with A",
+		}},
+		{`(schema test (Flag:bits A; B; AB:3 C:2))`, []string{
+			"error test.Flag: constant C value 2 collides with B",
+		}},
+		{`(schema test (Node; ID:int (Name:str ondel:cascade)))`, []string{
+			"error test.Node.Name: ondel cascade on elem that is no reference",
+		}},
+		{`(schema test (Cat; ID:int (Code:int uniq;)) (Prod; ID:int (Cat:@Cat.Code ondel:cascade)))`,
+			[]string{
+				"error test.Prod.Cat: ondel cascade on reference that is not to a primary key",
+			}},
+		{`(schema test (Tag; ID:int) (Node; ID:int (Tag:@Tag.ID ondel:setnull)))`, []string{
+			"warning test.Node.Tag: ondel setnull on elem that is not optional",
+		}},
+		{`(schema test (Tag; ID:int) (Post; ID:int) (Tagged; (Tag:@Tag.ID pk;) (Post:@Post.ID pk;)))`, nil},
+		{`(schema test (Tag; ID:int) (Post; ID:int) (Tagged; (Tag:@Tag.ID pk;) (Post?:@Post.ID pk;)))`,
+			[]string{
+				"error test.Tagged.post: optional composite primary key elem",
+			}},
+		{`(schema test (Cat; (Code:int uniq;) Name:str) (Prod; ID:int @Cat.Code))`, []string{
+			"warning test.Cat: obj model without primary key",
+			"error test.Prod.Cat: reference to model test.Cat without primary key",
+		}},
+	}
+	for _, test := range tests {
+		v, err := exp.NewProg(NewEnv()).RunStr(test.raw, nil)
+		if err != nil {
+			t.Errorf("run %s: %v", test.raw, err)
+			continue
+		}
+		s := mutPtr(v).(*Schema)
+		pro := &Project{Name: "test", Schemas: []*Schema{s}}
+		var got []string
+		for _, d := range Validate(pro) {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("validate %s want %q got %q", test.raw, test.want, got)
+		}
+	}
+}
+
+func TestValidateLoc(t *testing.T) {
+	raw := `(project site
+	(schema shop
+		(Cat; ID:int Name:str)
+		(Prod; ID:int
+			(Cat:@Cat.ID ondel:setnull)
+		)
+	)
+)`
+	pro, err := ReadProject(lit.NewRegs(), strings.NewReader(raw), "testdata/site.xelf")
+	if err != nil {
+		t.Fatalf("read project: %v", err)
+	}
+	ds := Validate(pro)
+	if len(ds) != 1 {
+		t.Fatalf("want one diagnostic got %v", ds)
+	}
+	if loc := ds[0].Loc; !strings.Contains(loc, "site.xelf:5:") {
+		t.Errorf("want loc with line 5 got %s", loc)
+	}
+}
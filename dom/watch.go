@@ -0,0 +1,196 @@
+package dom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/mod"
+)
+
+// Watcher polls the project file and the schema files referenced by the project and reloads the
+// project if any of the files changed. Projects that fail to load or validate are dropped and the
+// last good project is kept. Successfully reloaded projects are published to all listeners.
+//
+// After a failed load the watcher also polls all xelf files in the project directory and the
+// directory itself, because the changed project may refer to files the last good one did not.
+type Watcher struct {
+	Reg  *lit.Regs
+	Path string
+	// Resl is called with every loaded project before it is validated. It is usually set to
+	// qry.ReslQueries with the WatchResl option to resolve named queries.
+	Resl func(*Project) error
+
+	// chk serializes checks and guards the stamps, mu guards the project and listeners
+	chk   sync.Mutex
+	stamp map[string]time.Time
+	mu    sync.Mutex
+	pr    *Project
+	subs  []func(*Project)
+}
+
+// WatchOpt configures a watcher before the project is loaded the first time.
+type WatchOpt func(*Watcher)
+
+// WatchResl returns a watcher option that calls f with every loaded project.
+func WatchResl(f func(*Project) error) WatchOpt {
+	return func(w *Watcher) { w.Resl = f }
+}
+
+// NewWatcher returns a new watcher for the project discovered at path or an error.
+func NewWatcher(reg *lit.Regs, path string, opts ...WatchOpt) (*Watcher, error) {
+	path, err := DiscoverProject(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{Reg: reg, Path: path}
+	for _, opt := range opts {
+		opt(w)
+	}
+	pr, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.pr, w.stamp = pr, w.stamps(pr)
+	return w, nil
+}
+
+// Project returns the last good project.
+func (w *Watcher) Project() *Project {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pr
+}
+
+// Listen registers f to be called with every reloaded project. Listeners are called from the
+// polling goroutine in registration order.
+func (w *Watcher) Listen(f func(*Project)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, f)
+}
+
+// Check reloads and publishes the project if any watched file changed. It returns whether a new
+// project was published or an error if the changed project could not be loaded.
+func (w *Watcher) Check() (bool, error) {
+	w.chk.Lock()
+	defer w.chk.Unlock()
+	if !w.changed() {
+		return false, nil
+	}
+	// load without holding mu, so that readers of the last good project are not blocked
+	pr, err := w.load()
+	if err != nil {
+		// remember the failed state to only retry after the next change
+		w.stamp = w.failStamps(w.Project())
+		return false, err
+	}
+	w.stamp = w.stamps(pr)
+	w.mu.Lock()
+	w.pr = pr
+	subs := w.subs
+	w.mu.Unlock()
+	for _, f := range subs {
+		f(pr)
+	}
+	return true, nil
+}
+
+// Run checks the watched files every interval d until ctx is done. Reload errors are passed to
+// onErr if not nil.
+func (w *Watcher) Run(ctx context.Context, d time.Duration, onErr func(error)) {
+	tick := time.NewTicker(d)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			_, err := w.Check()
+			if err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) load() (*Project, error) {
+	pr, err := OpenProject(w.Reg, w.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reload project: %v", err)
+	}
+	if w.Resl != nil {
+		if err = w.Resl(pr); err != nil {
+			return nil, fmt.Errorf("reload project: %v", err)
+		}
+	}
+	for _, d := range Validate(pr) {
+		if d.Severity == SevError {
+			return nil, fmt.Errorf("reload project: %s", d)
+		}
+	}
+	return pr, nil
+}
+
+// files returns the project file and the schema files referenced by pr.
+func (w *Watcher) files(pr *Project) []string {
+	res := []string{w.Path}
+	for _, s := range pr.Schemas {
+		loc := mod.ParseLoc(extraStr(s.Extra, "file", ""))
+		if loc.Proto() != "file" {
+			continue
+		}
+		if path := loc.Path(); !ContainsStr(res, path) {
+			res = append(res, path)
+		}
+	}
+	return res
+}
+
+// stamps returns the modification times of the watched files of pr.
+func (w *Watcher) stamps(pr *Project) map[string]time.Time { return stampFiles(w.files(pr)) }
+
+// failStamps returns the modification times of the watched files of the last good project pr,
+// of all xelf files in the project directory and of the directory, that changes with new files.
+func (w *Watcher) failStamps(pr *Project) map[string]time.Time {
+	files := w.files(pr)
+	dir := filepath.Dir(w.Path)
+	if fs, err := filepath.Glob(filepath.Join(dir, "*.xelf")); err == nil {
+		for _, f := range fs {
+			if !ContainsStr(files, f) {
+				files = append(files, f)
+			}
+		}
+	}
+	return stampFiles(append(files, dir))
+}
+
+// stampFiles returns the modification times of files. Missing files are included with a zero time.
+func stampFiles(files []string) map[string]time.Time {
+	res := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		var t time.Time
+		if fi, err := os.Stat(f); err == nil {
+			t = fi.ModTime()
+		}
+		res[f] = t
+	}
+	return res
+}
+
+func (w *Watcher) changed() bool {
+	for f, t := range w.stamp {
+		var cur time.Time
+		if fi, err := os.Stat(f); err == nil {
+			cur = fi.ModTime()
+		}
+		if !cur.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package dom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, raw string, mod time.Time) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatalf("chtimes %s: %v", name, err)
+		}
+	}
+	now := time.Now().Add(-time.Hour)
+	write(ProjectFileName, "(import 'shop')\n(project test shop.dom)", now)
+	write("shop.xelf", "(schema shop (Cat; ID:int Name:str))", now)
+	w, err := NewWatcher(nil, dir)
+	if err != nil {
+		t.Fatalf("new watcher: %v", err)
+	}
+	var got []*Project
+	w.Listen(func(pr *Project) { got = append(got, pr) })
+	if ok, err := w.Check(); ok || err != nil {
+		t.Fatalf("check unchanged got %v %v", ok, err)
+	}
+	write("shop.xelf", "(schema shop (Cat; ID:int Name:str) (Prod; ID:int Name:str))", now.Add(time.Minute))
+	if ok, err := w.Check(); !ok || err != nil {
+		t.Fatalf("check changed got %v %v", ok, err)
+	}
+	if len(got) != 1 || got[0] != w.Project() || got[0].Model("shop.prod") == nil {
+		t.Fatalf("want reloaded project with shop.prod got %v", got)
+	}
+	old := w.Project()
+	write("shop.xelf", "(schema shop (Cat; ID:int Name:str)", now.Add(2*time.Minute))
+	if ok, err := w.Check(); ok || err == nil {
+		t.Fatalf("check invalid got %v %v", ok, err)
+	}
+	if w.Project() != old || len(got) != 1 {
+		t.Errorf("want last good project kept")
+	}
+	if ok, err := w.Check(); ok || err != nil {
+		t.Errorf("check after error got %v %v", ok, err)
+	}
+	write("shop.xelf", "(schema shop (Cat; ID:int Name:str))", now.Add(3*time.Minute))
+	write(ProjectFileName, "(import 'shop')\n(import 'extra')\n(project test shop.dom extra.dom)",
+		now.Add(3*time.Minute))
+	if ok, err := w.Check(); ok || err == nil {
+		t.Fatalf("check missing import got %v %v", ok, err)
+	}
+	write("extra.xelf", "(schema extra (Tag; ID:int))", now.Add(4*time.Minute))
+	if ok, err := w.Check(); !ok || err != nil {
+		t.Fatalf("check added import got %v %v", ok, err)
+	}
+	if w.Project().Model("extra.tag") == nil {
+		t.Errorf("want reloaded project with extra.tag")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"time"
 
+	"xelf.org/daql/dom"
 	"xelf.org/daql/hub"
 	"xelf.org/daql/log"
 )
@@ -39,6 +40,14 @@ func (ctr *Ctrl) Services() hub.Services {
 	}
 }
 
+// SetProj sends a reloaded project to the input loop, that passes it to ledgers with a SetProj
+// method. It can be registered as project watcher listener.
+func (ctr *Ctrl) SetProj(pr *dom.Project) {
+	ctr.Input <- &hub.Msg{Subj: "_proj", Data: pr}
+}
+
+type projSetter interface{ SetProj(*dom.Project) }
+
 func (ctr *Ctrl) Handle(m *hub.Msg) {
 	switch m.Subj {
 	case "_btrig":
@@ -47,6 +56,12 @@ func (ctr *Ctrl) Handle(m *hub.Msg) {
 		ctr.Bcast(m.From, ctr.Rev())
 	case "_stop":
 		ctr.Stop()
+	case "_proj":
+		if ps, ok := ctr.Ledger.(projSetter); ok {
+			ps.SetProj(m.Data.(*dom.Project))
+		} else {
+			ctr.Error("evt ledger cannot set project")
+		}
 	case hub.Signoff:
 		ctr.Subs.Unsub(m.From, nil)
 	default:
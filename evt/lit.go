@@ -19,6 +19,8 @@ type MemLedger struct {
 	Reg  lit.Regs
 	Bend *qry.MemBackend
 	evs  []*Event
+	// checks caches the constraint checkers of the backend project models
+	checks map[*dom.Model]*dom.Checker
 }
 
 // NewMemLedger returns a new ledger for testing, that is backed by the memory query backend b. This
@@ -61,6 +63,29 @@ func (l *MemLedger) Rev() time.Time {
 }
 func (l *MemLedger) Project() *dom.Project { return l.Bend.Project }
 
+// SetProj replaces the project of the ledger backend, usually with a reloaded version.
+func (l *MemLedger) SetProj(pr *dom.Project) {
+	l.Bend.SetProj(pr)
+	l.checks = nil
+}
+
+// check checks value v against the constraints of model m using a cached checker.
+func (l *MemLedger) check(m *dom.Model, v lit.Val, isNew bool) error {
+	c := l.checks[m]
+	if c == nil {
+		var err error
+		c, err = dom.NewChecker(m)
+		if err != nil {
+			return err
+		}
+		if l.checks == nil {
+			l.checks = make(map[*dom.Model]*dom.Checker)
+		}
+		l.checks[m] = c
+	}
+	return c.Check(v, isNew)
+}
+
 func (l *MemLedger) Events(rev time.Time, tops ...string) (res []*Event, _ error) {
 	var m map[string]struct{}
 	if len(tops) > 0 {
@@ -106,6 +131,10 @@ func (l *MemLedger) Publish(t Trans) (time.Time, []*Event, error) {
 	check := rev.After(t.Base)
 	var keys []string
 	for _, act := range t.Acts {
+		if m := l.Bend.Project.Model(act.Top); m != nil {
+			// computed fields are never stored
+			act.Arg = dropCalcs(m, act.Arg)
+		}
 		if check && act.Cmd != CmdNew {
 			// collect the keys to look for conflicts
 			keys = append(keys, act.Key)
@@ -120,18 +149,37 @@ func (l *MemLedger) Publish(t Trans) (time.Time, []*Event, error) {
 	// all events (e.g. deletions would alter later indexes or influence a later duplicate).
 	// therefor we need to reverse modification for already applied events.
 	var reverts []func() error
+	var rels dom.Relations
+	dels := make(map[Sig]bool)
 	for _, ev := range evs {
+		if ev.Cmd == CmdDel {
+			dels[ev.Sig] = true
+		}
+	}
+	for i := 0; i < len(evs); i++ {
+		ev := evs[i]
 		revert, err := l.applyEvent(ev)
+		if err == nil {
+			reverts = append(reverts, revert)
+			// referential actions and triggers add events to the same transaction
+			var more []*Event
+			more, err = l.effects(ev, &rels)
+			// rows reached by more than one cascade path are only deleted once
+			more = dedupDels(more, dels)
+			if len(evs)+len(more) > maxEvents {
+				err = fmt.Errorf("publish: more than %d events in transaction", maxEvents)
+			}
+			evs = append(evs, more...)
+		}
 		if err != nil {
-			for i := len(reverts) - 1; i >= 0; i++ {
-				er := reverts[i]()
+			for j := len(reverts) - 1; j >= 0; j-- {
+				er := reverts[j]()
 				if er != nil {
 					panic(fmt.Errorf("revert err: %v\nafter apply: %v", er, err))
 				}
 			}
 			return rev, nil, err
 		}
-		reverts = append(reverts, revert)
 	}
 	// insert event or audit error is a system error that should not depend on user input
 	err := l.insertEvents(evs)
@@ -170,15 +218,15 @@ func (l *MemLedger) applyEvent(ev *Event) (func() error, error) {
 	if m == nil {
 		return nil, fmt.Errorf("no model found for topic %s", ev.Top)
 	}
-	pk, pt, err := primaryKey(m)
+	pks, err := primaryKey(m)
 	if err != nil {
 		return nil, err
 	}
-	d := l.Bend.Data[ev.Top]
+	key := l.listKey(ev.Top, m)
 	switch ev.Cmd {
 	case CmdDel:
 		// find by ev.Key
-		idx, err := indexKey(d, pk, ev.Key)
+		idx, err := l.Bend.Find(key, m, ev.Key)
 		if err != nil {
 			return nil, fmt.Errorf("apply del %s: %w", ev.Top, err)
 		}
@@ -186,81 +234,179 @@ func (l *MemLedger) applyEvent(ev *Event) (func() error, error) {
 			return nil, fmt.Errorf("apply del %s, %s: no value found ", ev.Top, ev.Key)
 		}
 		// delete from vals
-		del := d.Vals[idx]
-		d.Vals = append(d.Vals[:idx], d.Vals[idx+1:]...)
+		del, err := l.Bend.Delete(key, m, idx)
+		if err != nil {
+			return nil, fmt.Errorf("apply del %s: %w", ev.Top, err)
+		}
 		return func() error {
-			d.Vals = append(d.Vals[:idx+1], d.Vals[idx:]...)
-			d.Vals[idx] = del
-			return nil
+			return l.Bend.Insert(key, m, idx, del)
 		}, nil
 	case CmdNew:
-		if d == nil {
-			d = lit.NewList(m.Type())
-			l.Bend.Data[ev.Top] = d
-		}
 		val := l.Reg.Zero(m.Type())
 		mut := val.(lit.Keyr)
-		kv, err := keyVal(pt, ev.Key)
+		parts, err := dom.SplitKey(ev.Key, len(pks))
 		if err != nil {
 			return nil, fmt.Errorf("apply new %s: %w", ev.Top, err)
 		}
-		err = mut.SetKey(pk, kv)
+		for i, f := range pks {
+			kv, err := keyVal(f.Type, parts[i])
+			if err != nil {
+				return nil, fmt.Errorf("apply new %s: %w", ev.Top, err)
+			}
+			pk := cor.Keyed(f.Name)
+			err = mut.SetKey(pk, kv)
+			if err != nil {
+				return nil, fmt.Errorf("apply new %s %s: %w", ev.Top, pk, err)
+			}
+		}
+		defs, err := m.Defaults(ev.Rev)
 		if err != nil {
-			return nil, fmt.Errorf("apply new %s %s: %w", ev.Top, pk, err)
+			return nil, fmt.Errorf("apply new %s: %w", ev.Top, err)
+		}
+		for k, v := range defs {
+			err = mut.SetKey(k, v)
+			if err != nil {
+				return nil, fmt.Errorf("apply new %s default %s: %w", ev.Top, k, err)
+			}
 		}
 		_, err = lit.Apply(mut, lit.Delta(ev.Arg.Keyed))
 		if err != nil {
 			return nil, fmt.Errorf("apply new %s arg: %w", ev.Top, err)
 		}
+		err = l.check(m, mut, true)
+		if err != nil {
+			return nil, fmt.Errorf("apply new %s: %w", ev.Top, err)
+		}
 		if hasRev(m) {
 			err = mut.SetKey("rev", lit.Time(ev.Rev))
 			if err != nil {
 				return nil, fmt.Errorf("apply new %s rev: %w", ev.Top, err)
 			}
 		}
-		idx := len(d.Vals)
-		d.Vals = append(d.Vals, mut)
+		var idx int
+		if d := l.Bend.Data[key]; d != nil {
+			idx = len(d.Vals)
+		}
+		err = l.Bend.Insert(key, m, idx, mut)
+		if err != nil {
+			return nil, fmt.Errorf("apply new %s: %w", ev.Top, err)
+		}
 		return func() error {
-			d.Vals = d.Vals[:idx]
-			return nil
+			_, err := l.Bend.Delete(key, m, idx)
+			return err
 		}, nil
 	case CmdMod:
 		// find by ev.Key
-		idx, err := indexKey(d, pk, ev.Key)
+		idx, err := l.Bend.Find(key, m, ev.Key)
 		if err != nil {
 			return nil, fmt.Errorf("apply mod %s: %w", ev.Top, err)
 		}
 		if idx < 0 {
 			return nil, fmt.Errorf("apply mod %s, %s: no value found ", ev.Top, ev.Key)
 		}
-		mut := d.Vals[idx].(lit.Keyr)
-		org := mut.String()
-		// mod arg
-		_, err = lit.Apply(mut, lit.Delta(ev.Arg.Keyed))
-		if err != nil {
-			return nil, fmt.Errorf("apply mod %s arg: %w", ev.Top, err)
-		}
-		if hasRev(m) {
-			err = mut.SetKey("rev", lit.Time(ev.Rev))
+		var org string
+		// the backend updates its indexes after the value was modified in place
+		err = l.Bend.Update(key, m, idx, func(v lit.Val) error {
+			mut := v.(lit.Keyr)
+			org = mut.String()
+			// mod arg
+			_, err := lit.Apply(mut, lit.Delta(ev.Arg.Keyed))
+			if err != nil {
+				return fmt.Errorf("apply mod %s arg: %w", ev.Top, err)
+			}
+			err = l.check(m, mut, false)
 			if err != nil {
-				return nil, fmt.Errorf("apply mod %s rev: %w", ev.Top, err)
+				if er := lit.ParseInto(org, mut); er != nil {
+					return fmt.Errorf("revert mod %s: %v\nafter check: %w", ev.Top, er, err)
+				}
+				return fmt.Errorf("apply mod %s: %w", ev.Top, err)
+			}
+			if hasRev(m) {
+				err = mut.SetKey("rev", lit.Time(ev.Rev))
+				if err != nil {
+					return fmt.Errorf("apply mod %s rev: %w", ev.Top, err)
+				}
 			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		// write back
-		d.Vals[idx] = mut
 		return func() error {
-			return lit.ParseInto(org, mut)
+			return l.Bend.Update(key, m, idx, func(v lit.Val) error {
+				return lit.ParseInto(org, v.(lit.Keyr))
+			})
 		}, nil
 	}
 	return nil, fmt.Errorf("unknown command %s", ev.Cmd)
 }
-func primaryKey(m *dom.Model) (string, typ.Type, error) {
-	for _, f := range m.Elems {
-		if f.Bits&dom.BitPK != 0 {
-			return cor.Keyed(f.Name), f.Type, nil
+
+// listKey returns the backend data key for topic top of model m. New lists use the topic.
+func (l *MemLedger) listKey(top string, m *dom.Model) string {
+	if l.Bend.Data[top] == nil && l.Bend.Data[m.Qualified()] != nil {
+		return m.Qualified()
+	}
+	return top
+}
+
+// list returns the data list for topic top of model m or nil.
+func (l *MemLedger) list(top string, m *dom.Model) *lit.List {
+	return l.Bend.Data[l.listKey(top, m)]
+}
+
+// primaryKey returns the primary key elems of model m or an error.
+func primaryKey(m *dom.Model) ([]*dom.Elem, error) {
+	if pks := m.PK(); len(pks) > 0 {
+		return pks, nil
+	}
+	return nil, fmt.Errorf("no pk field for model %s", m.Qualified())
+}
+
+// recordKey returns the canonical key string of the primary key elems pks of the record v.
+func recordKey(v lit.Keyr, pks []*dom.Elem) (string, error) {
+	parts := make([]string, 0, len(pks))
+	for _, f := range pks {
+		id, err := v.Key(cor.Keyed(f.Name))
+		if err != nil {
+			return "", err
 		}
+		parts = append(parts, id.String())
 	}
-	return "", typ.Void, fmt.Errorf("no pk field for model %s", m.Qualified())
+	return dom.JoinKey(parts...), nil
+}
+func dropCalcs(m *dom.Model, arg *lit.Dict) *lit.Dict {
+	calcs := m.Calcs()
+	if arg == nil || len(calcs) == 0 {
+		return arg
+	}
+	res := &lit.Dict{Typ: arg.Typ, Keyed: make([]lit.KeyVal, 0, len(arg.Keyed))}
+	for _, kv := range arg.Keyed {
+		var calc bool
+		for _, el := range calcs {
+			if calc = cor.Keyed(el.Name) == kv.Key; calc {
+				break
+			}
+		}
+		if !calc {
+			res.Keyed = append(res.Keyed, kv)
+		}
+	}
+	return res
+}
+
+// dedupDels returns evs without del events for signatures in dels and adds the others to dels.
+func dedupDels(evs []*Event, dels map[Sig]bool) []*Event {
+	res := evs[:0]
+	for _, ev := range evs {
+		if ev.Cmd == CmdDel {
+			if dels[ev.Sig] {
+				continue
+			}
+			dels[ev.Sig] = true
+		}
+		res = append(res, ev)
+	}
+	return res
 }
 func hasRev(m *dom.Model) bool {
 	for _, f := range m.Elems {
@@ -284,22 +430,6 @@ func keyVal(t typ.Type, key string) (lit.Val, error) {
 	return nil, fmt.Errorf("unexpected key type %s", t)
 }
 
-func indexKey(list *lit.List, pk, key string) (int, error) {
-	if list == nil {
-		return -1, fmt.Errorf("no data found")
-	}
-	for i, v := range list.Vals {
-		id, err := v.(lit.Keyr).Key(pk)
-		if err != nil {
-			return -1, err
-		}
-		if id.String() == key {
-			return i, nil
-		}
-	}
-	return -1, nil
-}
-
 type evtVals struct {
 	evt []*Event
 	val []lit.Val
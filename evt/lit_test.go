@@ -82,3 +82,176 @@ func testLedger() (*evt.MemLedger, error) {
 	p.Schemas = append(p.Schemas, ev, pr)
 	return evt.NewMemLedger(reg, qry.NewMemBackend(p, nil))
 }
+
+const shopRaw = `(schema shop
+(Cat; topic;
+	ID:int
+	Name:str
+)
+(Prod; topic;
+	ID:int
+	Name:str
+	(Cat:@Cat.ID ondel:cascade)
+)
+(Note; topic;
+	ID:int
+	(Prod:@Prod.ID ondel:restrict)
+	trigdel:[{top:'shop.cat' key:'1' cmd:'mod' arg:{name:'noted'}}]
+)
+)`
+
+func TestLedgerEffects(t *testing.T) {
+	reg := lit.NewRegs()
+	ev, err := dom.OpenSchema(reg, "evt.xelf")
+	if err != nil {
+		t.Fatalf("open evt: %v", err)
+	}
+	s, err := dom.ReadSchema(reg, strings.NewReader(shopRaw), "shop.xelf")
+	if err != nil {
+		t.Fatalf("read shop: %v", err)
+	}
+	p := &dom.Project{}
+	p.Schemas = append(p.Schemas, ev, s)
+	l, err := evt.NewMemLedger(reg, qry.NewMemBackend(p, nil))
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	name := func(v string) *lit.Dict {
+		return &lit.Dict{Keyed: []lit.KeyVal{{Key: "name", Val: lit.Str(v)}}}
+	}
+	ref := func(k string, v int64) *lit.Dict {
+		return &lit.Dict{Keyed: []lit.KeyVal{{Key: k, Val: lit.Int(v)}}}
+	}
+	_, _, err = l.Publish(evt.Trans{Acts: []evt.Action{
+		{evt.Sig{"shop.cat", "1"}, evt.CmdNew, name("a")},
+		{evt.Sig{"shop.prod", "10"}, evt.CmdNew, ref("cat", 1)},
+		{evt.Sig{"shop.prod", "11"}, evt.CmdNew, ref("cat", 1)},
+		{evt.Sig{"shop.note", "5"}, evt.CmdNew, ref("prod", 11)},
+	}})
+	if err != nil {
+		t.Fatalf("setup publish: %v", err)
+	}
+	count := func(top string) int {
+		if d := l.Bend.Data[top]; d != nil {
+			return len(d.Vals)
+		}
+		return 0
+	}
+	del := func(top, key string) (int, error) {
+		_, evs, err := l.Publish(evt.Trans{Acts: []evt.Action{{Sig: evt.Sig{top, key}, Cmd: evt.CmdDel}}})
+		return len(evs), err
+	}
+	_, err = del("shop.cat", "1")
+	if err == nil || !strings.Contains(err.Error(), "restricted by shop.note 5") {
+		t.Fatalf("want restrict error got %v", err)
+	}
+	if c, p := count("shop.cat"), count("shop.prod"); c != 1 || p != 2 {
+		t.Fatalf("restricted del not reverted got %d cats and %d prods", c, p)
+	}
+	n, err := del("shop.note", "5")
+	if err != nil {
+		t.Fatalf("del note: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("del note want 2 events with trigger got %d", n)
+	}
+	if cat := l.Bend.Data["shop.cat"].Vals[0].String(); !strings.Contains(cat, "noted") {
+		t.Errorf("trigger did not mod cat: %s", cat)
+	}
+	n, err = del("shop.cat", "1")
+	if err != nil {
+		t.Fatalf("del cat: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("del cat want 3 events with cascade got %d", n)
+	}
+	if c, p := count("shop.cat"), count("shop.prod"); c != 0 || p != 0 {
+		t.Errorf("cascade del want no data got %d cats and %d prods", c, p)
+	}
+}
+
+const tagRaw = `(schema tag
+(Tag; topic;
+	ID:int
+	Name:str
+)
+(Post; topic;
+	ID:int
+	Title:str
+)
+(Tagged; topic;
+	(Tag:@Tag.ID pk; ondel:cascade)
+	(Post:@Post.ID pk; ondel:cascade)
+	Note?:str
+)
+)`
+
+func TestLedgerCompositeKey(t *testing.T) {
+	reg := lit.NewRegs()
+	ev, err := dom.OpenSchema(reg, "evt.xelf")
+	if err != nil {
+		t.Fatalf("open evt: %v", err)
+	}
+	s, err := dom.ReadSchema(reg, strings.NewReader(tagRaw), "tag.xelf")
+	if err != nil {
+		t.Fatalf("read tag: %v", err)
+	}
+	p := &dom.Project{}
+	p.Schemas = append(p.Schemas, ev, s)
+	l, err := evt.NewMemLedger(reg, qry.NewMemBackend(p, nil))
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	str := func(k, v string) *lit.Dict {
+		return &lit.Dict{Keyed: []lit.KeyVal{{Key: k, Val: lit.Str(v)}}}
+	}
+	key := dom.JoinKey("1", "2")
+	_, _, err = l.Publish(evt.Trans{Acts: []evt.Action{
+		{evt.Sig{"tag.tag", "1"}, evt.CmdNew, str("name", "a")},
+		{evt.Sig{"tag.post", "2"}, evt.CmdNew, str("title", "b")},
+		{evt.Sig{"tag.post", "3"}, evt.CmdNew, str("title", "c")},
+		{evt.Sig{"tag.tagged", key}, evt.CmdNew, str("note", "x")},
+		{evt.Sig{"tag.tagged", "1,3"}, evt.CmdNew, &lit.Dict{}},
+	}})
+	if err != nil {
+		t.Fatalf("setup publish: %v", err)
+	}
+	d := l.Bend.Data["tag.tagged"]
+	if d == nil || len(d.Vals) != 2 {
+		t.Fatalf("want two tagged got %v", d)
+	}
+	got := d.Vals[0].(lit.Keyr)
+	for k, want := range map[string]string{"tag": "1", "post": "2", "note": "x"} {
+		v, err := got.Key(k)
+		if err != nil || !strings.Contains(v.String(), want) {
+			t.Errorf("tagged %s want %s got %v %v", k, want, v, err)
+		}
+	}
+	_, _, err = l.Publish(evt.Trans{Acts: []evt.Action{
+		{evt.Sig{"tag.tagged", key}, evt.CmdMod, str("note", "y")},
+	}})
+	if err != nil {
+		t.Fatalf("mod tagged: %v", err)
+	}
+	if v, _ := got.Key("note"); !strings.Contains(v.String(), "y") {
+		t.Errorf("mod tagged want note y got %s", v)
+	}
+	_, _, err = l.Publish(evt.Trans{Acts: []evt.Action{{Sig: evt.Sig{"tag.tagged", "2"}, Cmd: evt.CmdDel}}})
+	if err == nil {
+		t.Errorf("del tagged with incomplete key want error")
+	}
+	// both deletions cascade to tagged 1,3
+	_, evs, err := l.Publish(evt.Trans{Acts: []evt.Action{
+		{Sig: evt.Sig{"tag.tag", "1"}, Cmd: evt.CmdDel},
+		{Sig: evt.Sig{"tag.post", "3"}, Cmd: evt.CmdDel},
+	}})
+	if err != nil {
+		t.Fatalf("del tag and post: %v", err)
+	}
+	if len(evs) != 4 {
+		t.Errorf("del tag and post want 4 events with cascade got %d", len(evs))
+	}
+	if d := l.Bend.Data["tag.tagged"]; d != nil && len(d.Vals) != 0 {
+		t.Errorf("cascade del want no tagged got %v", d)
+	}
+}
@@ -0,0 +1,185 @@
+package evt
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/qry"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+// maxEvents limits the number of events in one transaction including all triggered events.
+const maxEvents = 10000
+
+// effects returns the extra events caused by the applied event ev. Deletions apply the referential
+// actions declared on referencing elems. The relations are computed once and stored in rels.
+func (l *MemLedger) effects(ev *Event, rels *dom.Relations) (res []*Event, err error) {
+	m := l.Bend.Project.Model(ev.Top)
+	if ev.Cmd == CmdDel {
+		if *rels == nil {
+			*rels, err = dom.Relate(l.Bend.Project)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res, err = l.refEffects(m, ev, (*rels)[m.Qualified()])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.Object == nil {
+		return res, nil
+	}
+	for _, t := range m.Object.Trigs {
+		if t.Cmd != ev.Cmd {
+			continue
+		}
+		acts, err := l.trigger(t, ev)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %s %s: %w", ev.Top, t.Cmd, err)
+		}
+		for _, act := range acts {
+			res = append(res, &Event{Rev: ev.Rev, Action: act})
+		}
+	}
+	return res, nil
+}
+
+func (l *MemLedger) refEffects(m *dom.Model, ev *Event, mr *dom.ModelRels) (res []*Event, _ error) {
+	if mr == nil {
+		return nil, nil
+	}
+	for _, r := range mr.In {
+		// validate rejects referential actions on references to other keys than the primary key
+		if r.Via.Model != nil || r.Rel&dom.RelEmbed != 0 || r.B.Key != "_" {
+			continue
+		}
+		a, key := r.A.Model, strings.TrimSuffix(r.A.Key, "?")
+		act := a.OnDel(key)
+		if act == "" {
+			continue
+		}
+		top := strings.ToLower(a.Qualified())
+		d := l.list(top, a)
+		if d == nil {
+			continue
+		}
+		pks, err := primaryKey(a)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range d.Vals {
+			k := v.(lit.Keyr)
+			fv, err := k.Key(key)
+			if err != nil {
+				return nil, err
+			}
+			rest, ok := refersTo(fv, ev.Key)
+			if !ok {
+				continue
+			}
+			id, err := recordKey(k, pks)
+			if err != nil {
+				return nil, err
+			}
+			sig := Sig{top, id}
+			switch act {
+			case dom.RefActRestrict:
+				return nil, fmt.Errorf("del %s %s restricted by %s %s", ev.Top, ev.Key, top, sig.Key)
+			case dom.RefActCascade:
+				res = append(res, &Event{Rev: ev.Rev, Action: Action{Sig: sig, Cmd: CmdDel}})
+			case dom.RefActSetNull:
+				if rest == nil {
+					rest = l.Reg.Zero(fv.Type())
+				}
+				arg := &lit.Dict{Keyed: []lit.KeyVal{{Key: key, Val: rest}}}
+				res = append(res, &Event{Rev: ev.Rev, Action: Action{Sig: sig, Cmd: CmdMod, Arg: arg}})
+			}
+		}
+	}
+	return res, nil
+}
+
+// refersTo returns whether the reference value v refers to key. For lists of references it also
+// returns the list without the matching references.
+func refersTo(v lit.Val, key string) (lit.Val, bool) {
+	if v == nil || v.Nil() {
+		return nil, false
+	}
+	list, ok := v.Value().(*lit.List)
+	if !ok {
+		return nil, v.String() == key
+	}
+	rest := &lit.List{Typ: list.Typ, Vals: make(lit.Vals, 0, len(list.Vals))}
+	for _, e := range list.Vals {
+		if e.String() != key {
+			rest.Vals = append(rest.Vals, e)
+		}
+	}
+	return rest, len(rest.Vals) < len(list.Vals)
+}
+
+// trigger evaluates the trigger expression with the event as parameter and returns the actions.
+// The expression can query the backend and must return a list of dicts with top, key, cmd and arg.
+func (l *MemLedger) trigger(t *dom.Trigger, ev *Event) ([]Action, error) {
+	x, err := exp.Read(strings.NewReader(t.Src), ev.Top)
+	if err != nil {
+		return nil, err
+	}
+	var arg lit.Val = lit.Null{}
+	if ev.Arg != nil {
+		arg = ev.Arg
+	}
+	param := lit.MakeObj(lit.Keyed{
+		{Key: "top", Val: lit.Str(ev.Top)},
+		{Key: "key", Val: lit.Str(ev.Key)},
+		{Key: "cmd", Val: lit.Str(ev.Cmd)},
+		{Key: "arg", Val: arg},
+	})
+	a, err := exp.NewProg(qry.NewDoc(extlib.Std, l.Bend)).Run(x, param)
+	if err != nil {
+		return nil, err
+	}
+	idx, ok := a.Value().(lit.Idxr)
+	if !ok {
+		return nil, fmt.Errorf("expect list of actions got %s", a)
+	}
+	var res []Action
+	err = idx.IterIdx(func(i int, v lit.Val) error {
+		k, ok := v.Value().(lit.Keyr)
+		if !ok {
+			return fmt.Errorf("expect action dict got %s", v)
+		}
+		var act Action
+		for _, f := range []struct {
+			key string
+			ptr *string
+		}{{"top", &act.Top}, {"key", &act.Key}, {"cmd", &act.Cmd}} {
+			fv, err := k.Key(f.key)
+			if err != nil {
+				return err
+			}
+			s, err := lit.ToStr(fv)
+			if err != nil {
+				return fmt.Errorf("action %s: %w", f.key, err)
+			}
+			*f.ptr = string(s)
+		}
+		if fv, err := k.Key("arg"); err == nil && fv != nil && !fv.Nil() {
+			switch d := fv.Value().(type) {
+			case *lit.Dict:
+				act.Arg = d
+			case *lit.Keyed:
+				act.Arg = &lit.Dict{Keyed: *d}
+			default:
+				return fmt.Errorf("action arg expects dict got %s", fv)
+			}
+		}
+		res = append(res, act)
+		return nil
+	})
+	return res, err
+}
@@ -13,6 +13,18 @@ func Nogen(s *dom.Schema) bool {
 	return err == nil && !l.Nil()
 }
 
+// NogenModel returns whether the model m is flagged with a nogen tag.
+func NogenModel(m *dom.Model) bool {
+	l, err := m.Extra.Key("nogen")
+	return err == nil && !l.Nil()
+}
+
+// Priv returns whether the elem el is flagged with a priv tag and should not be serialized.
+func Priv(el *dom.Elem) bool {
+	l, err := el.Extra.Key("priv")
+	return err == nil && !l.Nil()
+}
+
 // Gen is the code generation context holding the buffer and additional information.
 type Gen struct {
 	bfr.P
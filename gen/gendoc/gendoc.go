@@ -0,0 +1,276 @@
+// Package gendoc generates markdown documentation pages for dom schemas.
+//
+// Each schema is written to its own page with a section for every model, listing the fields with
+// type and flags, the enum and bits constants, indices and the incoming and outgoing relations.
+// Model references link to the model sections, also across schema pages, that are expected to be
+// named '$schema.md' and placed in the same location as the index page.
+package gendoc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/gen"
+	"xelf.org/daql/mig"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/typ"
+)
+
+func NewGen(pr *dom.Project) *gen.Gen {
+	return &gen.Gen{Project: pr, Target: "docs"}
+}
+
+// IndexName is the file name of the project index page.
+const IndexName = "index.md"
+
+// FileName returns the page file name for schema s.
+func FileName(s *dom.Schema) string { return fmt.Sprintf("%s.md", s.Name) }
+
+func WriteIndexFile(g *gen.Gen, name string, mf mig.Manifest, ss []*dom.Schema) error {
+	return writeFile(g, name, func() error { return WriteIndex(g, mf, ss) })
+}
+
+func WriteSchemaFile(g *gen.Gen, name string, mf mig.Manifest, s *dom.Schema) error {
+	return writeFile(g, name, func() error { return WriteSchema(g, mf, s) })
+}
+
+func writeFile(g *gen.Gen, name string, write func() error) error {
+	b := bfr.Get()
+	defer bfr.Put(b)
+	g.P = bfr.P{Writer: b}
+	err := write()
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(name, b.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("write doc file %s error: %v", name, err)
+	}
+	return nil
+}
+
+// WriteIndex writes the project index page linking the schema pages of ss.
+func WriteIndex(g *gen.Gen, mf mig.Manifest, ss []*dom.Schema) error {
+	g.Fmt("# %s\n", g.Project.Name)
+	writeVersion(g, mf, g.Project.Qualified())
+	g.Fmt("\n| Schema | Version | Models | Description |\n| --- | --- | --- | --- |\n")
+	for _, s := range ss {
+		v, _ := mf.Get(s.Qualified())
+		g.Fmt("| [%s](%s) | %s | %d | %s |\n", s.Label(), FileName(s), v.Vers,
+			len(s.Models), cell(s.Descr()))
+	}
+	return nil
+}
+
+// WriteSchema writes the documentation page for schema s.
+func WriteSchema(g *gen.Gen, mf mig.Manifest, s *dom.Schema) error {
+	if g.Project.Schema(s.Name) == nil {
+		// use a project with only schema s to resolve relations
+		cg := *g
+		cg.Project = &dom.Project{Schemas: []*dom.Schema{s}}
+		g = &cg
+	}
+	rels, err := dom.Relate(g.Project)
+	if err != nil {
+		return err
+	}
+	g.Fmt("# %s\n", s.Label())
+	writeVersion(g, mf, s.Qualified())
+	writeDescr(g, s.Descr())
+	if len(s.Models) > 0 {
+		g.Fmt("\n")
+		for _, m := range s.Models {
+			g.Fmt("- [%s](#%s) %s\n", m.Name, m.Key(), kindName(m))
+		}
+	}
+	for _, m := range s.Models {
+		err = writeModel(g, mf, s, m, rels[m.Qualified()])
+		if err != nil {
+			return fmt.Errorf("write model %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+func writeModel(g *gen.Gen, mf mig.Manifest, s *dom.Schema, m *dom.Model, mr *dom.ModelRels) error {
+	g.Fmt("\n## %s\n\n`%s` %s", m.Name, strings.ToLower(m.Qualified()), kindName(m))
+	if v, ok := mf.Get(m.Qualified()); ok && v.Vers != "" {
+		g.Fmt(" %s", v.Vers)
+	}
+	g.Fmt("\n")
+	writeDescr(g, m.Descr())
+	switch m.Kind.Kind {
+	case knd.Bits, knd.Enum:
+		g.Fmt("\n| Name | Value | Description |\n| --- | --- | --- |\n")
+		for _, el := range m.Elems {
+			g.Fmt("| %s | %d | %s |\n", el.Name, el.Val, cell(el.Descr()))
+		}
+		return nil
+	}
+	if len(m.Elems) > 0 {
+		g.Fmt("\n| Field | Type | Flags | Description |\n| --- | --- | --- | --- |\n")
+		for _, el := range m.Elems {
+			name := elemKey(el)
+			if el.Name == "" {
+				name = "(embedded)"
+			}
+			g.Fmt("| %s | %s | %s | %s |\n", name, typeLink(g, s, m, el),
+				strings.Join(flags(m, el), " "), cell(el.Descr()))
+		}
+	}
+	if m.Object != nil && len(m.Object.Indices) > 0 {
+		g.Fmt("\nIndices:\n\n")
+		for _, idx := range m.Object.Indices {
+			kind := "idx"
+			if idx.Unique {
+				kind = "uniq"
+			}
+			g.Fmt("- %s (%s)", kind, strings.Join(idx.Keys, ", "))
+			if idx.Name != "" {
+				g.Fmt(" `%s`", idx.Name)
+			}
+			g.Fmt("\n")
+		}
+	}
+	if mr != nil && len(mr.Out)+len(mr.In)+len(mr.Via) > 0 {
+		g.Fmt("\nRelations:\n\n")
+		for _, r := range mr.Out {
+			if r.Via.Model != nil {
+				g.Fmt("- to %s via %s %s\n", modelLink(s, r.B.Model),
+					modelLink(s, r.Via.Model), relName(r.Rel))
+				continue
+			}
+			g.Fmt("- `%s` to %s %s\n", r.A.Key, modelLink(s, r.B.Model), relName(r.Rel))
+		}
+		for _, r := range mr.In {
+			if r.Via.Model != nil {
+				g.Fmt("- from %s via %s %s\n", modelLink(s, r.A.Model),
+					modelLink(s, r.Via.Model), relName(r.Rel))
+				continue
+			}
+			g.Fmt("- from %s `%s` %s\n", modelLink(s, r.A.Model), r.A.Key, relName(r.Rel))
+		}
+		for _, r := range mr.Via {
+			g.Fmt("- links %s and %s %s\n", modelLink(s, r.A.Model),
+				modelLink(s, r.B.Model), relName(r.Rel))
+		}
+	}
+	return nil
+}
+
+func writeVersion(g *gen.Gen, mf mig.Manifest, name string) {
+	if v, ok := mf.Get(name); ok && v.Vers != "" {
+		g.Fmt("\nVersion %s\n", v.Vers)
+	}
+}
+
+func writeDescr(g *gen.Gen, d string) {
+	if d = strings.TrimSpace(d); d != "" {
+		g.Fmt("\n%s\n", d)
+	}
+}
+
+func kindName(m *dom.Model) string {
+	switch m.Kind.Kind {
+	case knd.Bits:
+		return "bits"
+	case knd.Enum:
+		return "enum"
+	case knd.Func:
+		return "func"
+	}
+	return "obj"
+}
+
+// flags returns the elem flags and the tags relevant for documentation.
+func flags(m *dom.Model, el *dom.Elem) []string {
+	var res []string
+	for i, n := range dom.BitNames() {
+		if el.Bits&(1<<uint(i)) != 0 {
+			res = append(res, n)
+		}
+	}
+	if el.CalcSrc() != "" {
+		res = append(res, "calc")
+	}
+	if src := el.DefaultSrc(); src != "" {
+		res = append(res, fmt.Sprintf("default:`%s`", cell(src)))
+	}
+	if act := m.OnDel(elemKey(el)); act != "" {
+		res = append(res, "ondel:"+string(act))
+	}
+	if gen.Priv(el) {
+		res = append(res, "priv")
+	}
+	return res
+}
+
+func elemKey(el *dom.Elem) string { return strings.TrimSuffix(el.Key(), "?") }
+
+func relName(r dom.Rel) string {
+	var res string
+	switch r &^ (dom.RelEmbed | dom.RelRelax | dom.RelInter | dom.RelReverse) {
+	case dom.Rel11:
+		res = "1:1"
+	case dom.RelN1:
+		res = "n:1"
+	case dom.Rel1N:
+		res = "1:n"
+	case dom.RelNN:
+		res = "n:m"
+	}
+	if r&dom.RelEmbed != 0 {
+		res += " embedded"
+	}
+	if r&dom.RelRelax != 0 {
+		res += " relaxed"
+	}
+	return res
+}
+
+// typeLink returns the elem type in code format with a link to the referenced model if any.
+func typeLink(g *gen.Gen, s *dom.Schema, m *dom.Model, el *dom.Elem) string {
+	res := fmt.Sprintf("`%s`", cell(el.Type.String()))
+	ref := typ.Last(el.Type).Ref
+	if strings.EqualFold(ref, m.Qualified()+"."+elemKey(el)) {
+		// the elem type of the model itself
+		return res
+	}
+	if rm := refModel(g, ref); rm != nil {
+		res += " " + modelLink(s, rm)
+	}
+	return res
+}
+
+// refModel returns the model referenced by a model or model elem type reference or nil.
+func refModel(g *gen.Gen, ref string) *dom.Model {
+	if ref == "" {
+		return nil
+	}
+	ref = strings.ToLower(ref)
+	if m := g.Project.Model(ref); m != nil {
+		return m
+	}
+	if idx := strings.LastIndexByte(ref, '.'); idx > 0 {
+		return g.Project.Model(ref[:idx])
+	}
+	return nil
+}
+
+// modelLink returns a markdown link to the model section on the page of schema s or another page.
+func modelLink(s *dom.Schema, m *dom.Model) string {
+	name := strings.ToLower(m.Qualified())
+	if m.Schema == s.Name {
+		return fmt.Sprintf("[%s](#%s)", name, m.Key())
+	}
+	return fmt.Sprintf("[%s](%s.md#%s)", name, m.Schema, m.Key())
+}
+
+// cell returns text usable as markdown table cell.
+func cell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
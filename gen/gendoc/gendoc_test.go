@@ -0,0 +1,57 @@
+package gendoc
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/mig"
+	"xelf.org/xelf/bfr"
+)
+
+const shopRaw = `(schema shop doc:'Shop models.'
+	(Flag:bits Hot; New;)
+	(Cat; ID:int (Name:str doc:'Display name.') uniq:['name'])
+	(Prod; doc:'Products for sale.' (ID:int auto;) (Cat:@Cat.ID ondel:cascade)
+		Flags?:<bits@shop.Flag> (Qty:int default:1))
+)`
+
+func TestWriteSchema(t *testing.T) {
+	s, err := dom.ReadSchema(nil, strings.NewReader(shopRaw), "shop")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	mf := mig.Manifest{
+		{Name: "shop", Vers: "v0.2.0"},
+		{Name: "shop.Cat", Vers: "v0.1.0"},
+		{Name: "shop.Prod", Vers: "v0.1.3"},
+	}
+	var b strings.Builder
+	g := NewGen(nil)
+	g.P = bfr.P{Writer: &b}
+	err = WriteSchema(g, mf, s)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"# shop\n\nVersion v0.2.0\n\nShop models.\n",
+		"- [Flag](#flag) bits\n- [Cat](#cat) obj\n- [Prod](#prod) obj\n",
+		"| Hot | 1 |  |\n| New | 2 |  |\n",
+		"## Cat\n\n`shop.cat` obj v0.1.0\n",
+		"| name | `str` |  | Display name. |\n",
+		"- uniq (name)\n",
+		"- from [shop.prod](#prod) `cat` n:1\n",
+		"## Prod\n\n`shop.prod` obj v0.1.3\n\nProducts for sale.\n",
+		" | pk auto |  |\n",
+		" [shop.cat](#cat) | ondel:cascade |  |\n",
+		" [shop.flag](#flag) | opt |  |\n",
+		"| qty | `int` | default:`1` |  |\n",
+		"| flags | ",
+		"- `cat` to [shop.cat](#cat) n:1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"math/bits"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"xelf.org/daql/dom"
 	"xelf.org/daql/gen"
 	"xelf.org/xelf/bfr"
 	"xelf.org/xelf/cor"
+	"xelf.org/xelf/exp"
 	"xelf.org/xelf/knd"
 	"xelf.org/xelf/lit"
 	"xelf.org/xelf/typ"
@@ -50,12 +53,25 @@ func WriteSchema(g *gen.Gen, s *dom.Schema) error {
 	tmp := g.Writer
 	g.Writer = b
 	for _, m := range s.Models {
+		if gen.NogenModel(m) {
+			continue
+		}
 		g.Byte('\n')
 		err := WriteModel(g, m)
 		if err != nil {
 			return fmt.Errorf("write model %s: %w", m.Name, err)
 		}
 	}
+	for _, q := range s.Queries {
+		if q.Res == typ.Void {
+			continue
+		}
+		g.Byte('\n')
+		err := WriteQuery(g, q)
+		if err != nil {
+			return fmt.Errorf("write query %s: %w", q.Name, err)
+		}
+	}
 	// swap back
 	g.Writer = tmp
 	g.Fmt("%spackage %s\n", g.Header, pkgName(g.Pkg))
@@ -114,10 +130,37 @@ func WriteModel(g *gen.Gen, m *dom.Model) (err error) {
 	case knd.Obj:
 		g.Fmt("type %s ", m.Name)
 		// NOTE: we need this custom type to avoid flattening params of embedded obj types
+		var priv map[string]bool
+		ps := make([]typ.Param, 0, len(m.Elems))
+		for _, el := range m.Elems {
+			if el.CalcSrc() != "" {
+				// computed elems are written as getter methods
+				continue
+			}
+			if gen.Priv(el) {
+				if priv == nil {
+					priv = make(map[string]bool)
+				}
+				priv[privKey(el.Name)] = true
+			}
+			ps = append(ps, typ.P(el.Name, el.Type))
+		}
 		err = writeObj(g, typ.Type{Kind: knd.Obj, Ref: m.Qualified(),
-			Body: &typ.ParamBody{Params: m.Params()},
-		})
+			Body: &typ.ParamBody{Params: ps},
+		}, priv)
 		g.Byte('\n')
+		if err == nil {
+			err = writeNew(g, m)
+		}
+		if err == nil {
+			err = writeValidate(g, m)
+		}
+		if err == nil {
+			err = writeCalcs(g, m)
+		}
+		if err == nil {
+			err = writeKey(g, m)
+		}
 	case knd.Func:
 		ps := m.Params()
 		last := len(ps) - 1
@@ -183,6 +226,27 @@ func (f %[1]sFunc) Serve(m *hub.Msg) (*hub.Msg, error) {
 	return err
 }
 
+// WriteQuery writes a type declaration for the result element type of a resolved named query.
+func WriteQuery(g *gen.Gen, q *dom.Query) error {
+	if doc, err := q.Extra.Key("doc"); err == nil {
+		ch, err := lit.ToStr(doc)
+		if ch != "" && err == nil {
+			g.Prepend(fmt.Sprintf("%s %s", cor.Cased(q.Name), ch), "// ")
+		}
+	}
+	t := q.Res
+	if t.Kind&knd.List != 0 {
+		t = typ.ContEl(t)
+	}
+	t.Kind &^= knd.None
+	g.Fmt("type %s ", cor.Cased(q.Name))
+	err := WriteType(g, t)
+	if err != nil {
+		return err
+	}
+	return g.Byte('\n')
+}
+
 func pkgName(pkg string) string {
 	if idx := strings.LastIndexByte(pkg, '/'); idx != -1 {
 		pkg = pkg[idx+1:]
@@ -275,3 +339,395 @@ func writeEnumConsts(g *gen.Gen, m *dom.Model) {
 	}
 	g.Fmt("\n)\n")
 }
+
+// writeNew writes a constructor for obj model m that sets the elem defaults, if it has any.
+func writeNew(g *gen.Gen, m *dom.Model) error {
+	var els []*dom.Elem
+	for _, el := range m.Elems {
+		if el.Name != "" && el.DefaultSrc() != "" && el.CalcSrc() == "" {
+			els = append(els, el)
+		}
+	}
+	if len(els) == 0 {
+		return nil
+	}
+	g.Fmt("\n// New%[1]s returns a new %[1]s with default values.\n", m.Name)
+	g.Fmt("func New%[1]s() *%[1]s {\n\treturn &%[1]s{\n", m.Name)
+	for _, el := range els {
+		g.Fmt("\t\t%s: ", cor.Cased(strings.TrimSuffix(el.Name, "?")))
+		err := writeDefault(g, el)
+		if err != nil {
+			return fmt.Errorf("write default %s: %w", el.Name, err)
+		}
+		g.Fmt(",\n")
+	}
+	g.Fmt("\t}\n}\n")
+	return nil
+}
+
+// writeDefault writes the default value of el. Defaults depending on the current time are only
+// supported as plain now symbol for time elems, other defaults are evaluated and written as
+// literal.
+func writeDefault(g *gen.Gen, el *dom.Elem) error {
+	src, t := el.DefaultSrc(), el.Type
+	if src == "now" || src == "(now)" {
+		if t.Kind != knd.Time {
+			return fmt.Errorf("now default for %s", t)
+		}
+		return g.Fmt(Import(g, "time.Now") + "()")
+	}
+	x, err := exp.Read(strings.NewReader(src), el.Name)
+	if err != nil {
+		return err
+	}
+	if usesNow(x) {
+		return fmt.Errorf("cannot generate time dependent default %s", src)
+	}
+	v, err := el.Default(time.Time{})
+	if err != nil {
+		return err
+	}
+	switch t.Kind & knd.Data {
+	case knd.Enum:
+		s, err := lit.ToStr(v)
+		if err != nil {
+			return err
+		}
+		if err = WriteType(g, t); err != nil {
+			return err
+		}
+		return g.Fmt("(%q)", string(s))
+	case knd.Bits:
+		if err = WriteType(g, t); err != nil {
+			return err
+		}
+		return g.Fmt("(%s)", v)
+	}
+	return WriteVal(g, t, v)
+}
+
+// usesNow returns whether the expression x uses the now symbol or calls it.
+func usesNow(x exp.Exp) bool {
+	switch v := x.(type) {
+	case *exp.Sym:
+		return v.Sym == "now"
+	case *exp.Call:
+		for _, a := range v.Args {
+			if usesNow(a) {
+				return true
+			}
+		}
+	case *exp.Tupl:
+		for _, a := range v.Els {
+			if usesNow(a) {
+				return true
+			}
+		}
+	case *exp.Tag:
+		return v.Exp != nil && usesNow(v.Exp)
+	}
+	return false
+}
+
+// writeValidate writes a validate method for obj model m if any of its elems declares constraints.
+// Go values cannot tell whether they are new, so the req constraints are checked in an additional
+// validate new method, that callers use for values they are about to create. Required obj and dict
+// elems are not checked, because they have no simple zero check.
+func writeValidate(g *gen.Gen, m *dom.Model) error {
+	var b, r strings.Builder
+	var pats []string
+	for _, el := range m.Elems {
+		if el.Name == "" || el.CalcSrc() != "" {
+			continue
+		}
+		c, err := dom.ElemConstr(el)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			continue
+		}
+		name := cor.Cased(strings.TrimSuffix(el.Name, "?"))
+		node := dom.ElemLabelKey(m, el)
+		t := el.Type
+		k := t.Kind & knd.Data
+		char := k == knd.Str || k == knd.Raw
+		if cond := zeroCond(t, "m."+name); c.Req && cond != "" {
+			fmt.Fprintf(&r, "\tif %s {\n\t\treturn errors.New(%q)\n\t}\n", cond, node+" is required")
+		}
+		val, pre := "m."+name, ""
+		if t.Kind&knd.None != 0 {
+			pre, val = val+" != nil && ", "*"+val
+		} else if el.Bits&dom.BitOpt != 0 && k != knd.List {
+			switch k {
+			case knd.Str:
+				pre = val + ` != "" && `
+			case knd.Raw:
+				pre = "len(" + val + ") != 0 && "
+			default:
+				pre = val + " != 0 && "
+			}
+		}
+		check := func(cond, msg string) {
+			fmt.Fprintf(&b, "\tif %s%s {\n\t\treturn errors.New(%q)\n\t}\n", pre, cond, node+msg)
+		}
+		if c.Min != nil {
+			num := numLit(k, *c.Min)
+			check(val+" < "+num, " below min "+num)
+		}
+		if c.Max != nil {
+			num := numLit(k, *c.Max)
+			check(val+" > "+num, " above max "+num)
+		}
+		if c.Len > 0 {
+			if char {
+				g.Imports.Add("unicode/utf8")
+				check(fmt.Sprintf("utf8.RuneCountInString(string(%s)) > %d", val, c.Len),
+					fmt.Sprintf(" longer than %d", c.Len))
+			} else {
+				check(fmt.Sprintf("len(%s) > %d", val, c.Len),
+					fmt.Sprintf(" longer than %d", c.Len))
+			}
+		}
+		if c.Pattern != nil {
+			pat := "pat" + m.Name + name
+			pats = append(pats, fmt.Sprintf("var %s = regexp.MustCompile(%q)\n",
+				pat, c.Pattern.String()))
+			check(fmt.Sprintf("!%s.MatchString(string(%s))", pat, val),
+				" does not match pattern "+c.Pattern.String())
+		}
+		if len(c.OneOf) > 0 {
+			conds := make([]string, 0, len(c.OneOf))
+			strs := make([]string, 0, len(c.OneOf))
+			cmp := val
+			if k == knd.Raw {
+				cmp = "string(" + val + ")"
+			}
+			for _, o := range c.OneOf {
+				var str string
+				if char {
+					s, err := lit.ToStr(o)
+					if err != nil {
+						return err
+					}
+					str = string(s)
+					strs = append(strs, str)
+					str = strconv.Quote(str)
+				} else {
+					f, err := lit.ToReal(o)
+					if err != nil {
+						return err
+					}
+					str = numLit(k, float64(f))
+					strs = append(strs, str)
+				}
+				conds = append(conds, cmp+" != "+str)
+			}
+			check(strings.Join(conds, " && "), " is not one of "+strings.Join(strs, ", "))
+		}
+	}
+	if b.Len() == 0 && r.Len() == 0 {
+		return nil
+	}
+	g.Imports.Add("errors")
+	ret := "nil"
+	if b.Len() > 0 {
+		ret = "m.Validate()"
+		g.Fmt("\nfunc (m *%s) Validate() error {\n%s\treturn nil\n}\n", m.Name, b.String())
+	}
+	if r.Len() > 0 {
+		g.Fmt("\n// ValidateNew checks the required elems of a new %s and validates it.\n", m.Name)
+		g.Fmt("func (m *%s) ValidateNew() error {\n%s\treturn %s\n}\n", m.Name, r.String(), ret)
+	}
+	if len(pats) > 0 {
+		g.Imports.Add("regexp")
+		for _, pat := range pats {
+			g.Fmt("\n%s", pat)
+		}
+	}
+	return nil
+}
+
+// numLit returns a go literal for the constraint number f of an elem with kind k.
+func numLit(k knd.Kind, f float64) string {
+	if k == knd.Int {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// zeroCond returns a go condition that tests whether the value of type t in val is zero, or an
+// empty string if no simple condition exists.
+func zeroCond(t typ.Type, val string) string {
+	if t.Kind&knd.None != 0 {
+		return val + " == nil"
+	}
+	switch t.Kind & knd.Data {
+	case knd.Bool:
+		return "!" + val
+	case knd.Int, knd.Real, knd.Span, knd.Bits:
+		return val + " == 0"
+	case knd.Str, knd.Enum:
+		return val + ` == ""`
+	case knd.Raw, knd.List:
+		return "len(" + val + ") == 0"
+	case knd.UUID:
+		return val + " == [16]byte{}"
+	case knd.Time:
+		return val + ".IsZero()"
+	}
+	return ""
+}
+
+// writeCalcs writes getter methods for the computed elems of model m. Only simple expressions with
+// literals, elem references, cat and arithmetic calls can be translated, other computed elems are
+// skipped and must be queried.
+func writeCalcs(g *gen.Gen, m *dom.Model) error {
+	for _, el := range m.Calcs() {
+		if el.Type.Kind == knd.Any {
+			continue
+		}
+		x, err := el.CalcExp()
+		if err != nil {
+			return err
+		}
+		body, _, ok := calcGo(m, x, false)
+		if !ok {
+			continue
+		}
+		name := cor.Cased(strings.TrimSuffix(el.Name, "?"))
+		g.Fmt("\n// %s returns the computed value of %s.\n", name, el.CalcSrc())
+		g.Fmt("func (m *%s) %s() ", m.Name, name)
+		err = WriteType(g, el.Type)
+		if err != nil {
+			return err
+		}
+		g.Fmt(" {\n\treturn %s\n}\n", body)
+	}
+	return nil
+}
+
+// writeKey writes a key method for models with a composite primary key, that returns the
+// canonical key string as used for event keys. The method is named PrimaryKey if the model has a
+// Key elem.
+func writeKey(g *gen.Gen, m *dom.Model) error {
+	pks := m.PK()
+	if len(pks) < 2 {
+		return nil
+	}
+	method := "Key"
+	if hasMember(m, method) {
+		method = "PrimaryKey"
+		if hasMember(m, method) {
+			return fmt.Errorf("composite key method of %s clashes with elems Key and PrimaryKey",
+				m.Name)
+		}
+	}
+	parts := make([]string, 0, len(pks))
+	for _, el := range pks {
+		name := "m." + cor.Cased(strings.TrimSuffix(el.Name, "?"))
+		switch el.Type.Kind & knd.Data {
+		case knd.Int:
+			parts = append(parts, fmt.Sprintf("%s(%s, 10)", Import(g, "strconv.FormatInt"), name))
+		case knd.Str:
+			parts = append(parts, name)
+		case knd.Enum:
+			parts = append(parts, fmt.Sprintf("string(%s)", name))
+		case knd.UUID:
+			parts = append(parts, fmt.Sprintf("%s(\"%%x-%%x-%%x-%%x-%%x\", %[2]s[:4], %[2]s[4:6], "+
+				"%[2]s[6:8], %[2]s[8:10], %[2]s[10:])", Import(g, "fmt.Sprintf"), name))
+		default:
+			return fmt.Errorf("composite key elem %s type %s not supported", el.Name, el.Type)
+		}
+	}
+	g.Fmt("\n// %s returns the canonical key string of the composite primary key.\n", method)
+	g.Fmt("func (m *%s) %s() string {\n\treturn %s(%s)\n}\n", m.Name, method,
+		Import(g, "xelf.org/daql/dom.JoinKey"), strings.Join(parts, ", "))
+	return nil
+}
+
+// hasMember returns whether model m has an elem with the go name.
+func hasMember(m *dom.Model, name string) bool {
+	for _, el := range m.Elems {
+		if el.Name != "" && cor.Cased(strings.TrimSuffix(el.Name, "?")) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// calcGo returns the go expression and kind for the computed elem expression x of model m.
+func calcGo(m *dom.Model, x exp.Exp, nested bool) (string, knd.Kind, bool) {
+	switch v := x.(type) {
+	case *exp.Lit:
+		k := v.Val.Type().Kind
+		if k&knd.Char != 0 {
+			s, err := lit.ToStr(v.Val)
+			return strconv.Quote(string(s)), knd.Str, err == nil
+		}
+		if k&knd.Num != 0 {
+			return v.Val.String(), k, true
+		}
+	case *exp.Sym:
+		if !strings.HasPrefix(v.Sym, ".") || strings.HasPrefix(v.Sym, "..") {
+			break
+		}
+		for _, el := range m.Elems {
+			if !strings.EqualFold(strings.TrimSuffix(el.Name, "?"), v.Sym[1:]) {
+				continue
+			}
+			k := el.Type.Kind
+			if k&knd.None != 0 || k&(knd.Str|knd.Num) == 0 {
+				break
+			}
+			name := "m." + cor.Cased(strings.TrimSuffix(el.Name, "?"))
+			if el.CalcSrc() != "" {
+				name += "()"
+			}
+			return name, k, true
+		}
+	case *exp.Call:
+		if len(v.Args) < 3 {
+			break
+		}
+		s, ok := v.Args[0].(*exp.Sym)
+		if !ok {
+			break
+		}
+		var op string
+		var k knd.Kind
+		switch s.Sym {
+		case "cat":
+			op, k = " + ", knd.Str
+		case "add":
+			op = " + "
+		case "sub":
+			op = " - "
+		case "mul":
+			op = " * "
+		case "div":
+			op = " / "
+		default:
+			return "", 0, false
+		}
+		args := make([]string, 0, len(v.Args)-1)
+		for _, a := range v.Args[1:] {
+			str, ak, ok := calcGo(m, a, true)
+			if !ok || k == knd.Str && ak != knd.Str || k != knd.Str && ak&knd.Num == 0 {
+				return "", 0, false
+			}
+			if k == 0 {
+				k = ak
+			}
+			args = append(args, str)
+		}
+		res := strings.Join(args, op)
+		if nested {
+			res = "(" + res + ")"
+		}
+		return res, k, true
+	}
+	return "", 0, false
+}
+
+func privKey(name string) string { return strings.ToLower(strings.TrimSuffix(name, "?")) }
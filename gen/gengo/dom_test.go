@@ -18,6 +18,15 @@ const fooRaw = `(schema foo
 	(Node3; Kind:<bits@bar.Kind>)
 	(Node4; Kind:<bits@foo.Kind>)
 	(Node5; @Node4)
+	(Node6; Name:str (Cache?:str priv;))
+	(Node7; nogen; Name:str)
+	(Node8; (Name:str req; len:5 pattern:'^[a-z]+$') (Qty?:int min:1 max:9) (Kind:str oneof:['a' 'b'])
+		(Rate?:real max:0.5) (Size:int oneof:[1 2]))
+	(Node9; (Kind:<enum@foo.Kind> default:'b') (Created:time default:now) (Qty:int default:1))
+	(Node10; First:str Last:str Qty:int Full:(cat .first ' ' .last) Next:(add .qty 1) Any:(len .first))
+	(Node11; (A:int pk;) (B:str pk;) (C:<enum@foo.Kind> pk;) Note?:str)
+	(Node12; (Note:str default:'unknown'))
+	(Node13; (Key:str pk;) (Rev:int pk;))
 )`
 
 func TestWriteFile(t *testing.T) {
@@ -65,6 +74,91 @@ func TestWriteFile(t *testing.T) {
 		{"node5", "package foo\n\ntype Node5 struct {\n" +
 			"\tNode4\n" + "}\n",
 		},
+		{"node6", "package foo\n\ntype Node6 struct {\n" +
+			"\tName  string `json:\"name\"`\n" +
+			"\tCache string `json:\"-\"`\n" + "}\n",
+		},
+		{"node7", "package foo\n"},
+		{"node8", "package foo\n\nimport (\n\t\"errors\"\n\t\"regexp\"\n\t\"unicode/utf8\"\n)\n\n" +
+			"type Node8 struct {\n" +
+			"\tName string  `json:\"name\"`\n" +
+			"\tQty  int64   `json:\"qty,omitempty\"`\n" +
+			"\tKind string  `json:\"kind\"`\n" +
+			"\tRate float64 `json:\"rate,omitempty\"`\n" +
+			"\tSize int64   `json:\"size\"`\n" + "}\n\n" +
+			"func (m *Node8) Validate() error {\n" +
+			"\tif utf8.RuneCountInString(string(m.Name)) > 5 {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Name longer than 5\")\n\t}\n" +
+			"\tif !patNode8Name.MatchString(string(m.Name)) {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Name does not match pattern ^[a-z]+$\")\n\t}\n" +
+			"\tif m.Qty != 0 && m.Qty < 1 {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Qty below min 1\")\n\t}\n" +
+			"\tif m.Qty != 0 && m.Qty > 9 {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Qty above max 9\")\n\t}\n" +
+			"\tif m.Kind != \"a\" && m.Kind != \"b\" {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Kind is not one of a, b\")\n\t}\n" +
+			"\tif m.Rate != 0 && m.Rate > 0.5 {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Rate above max 0.5\")\n\t}\n" +
+			"\tif m.Size != 1 && m.Size != 2 {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Size is not one of 1, 2\")\n\t}\n" +
+			"\treturn nil\n}\n\n" +
+			"// ValidateNew checks the required elems of a new Node8 and validates it.\n" +
+			"func (m *Node8) ValidateNew() error {\n" +
+			"\tif m.Name == \"\" {\n" +
+			"\t\treturn errors.New(\"foo.Node8.Name is required\")\n\t}\n" +
+			"\treturn m.Validate()\n}\n\n" +
+			"var patNode8Name = regexp.MustCompile(\"^[a-z]+$\")\n",
+		},
+		{"node9", "package foo\n\nimport (\n\t\"time\"\n)\n\n" +
+			"type Node9 struct {\n" +
+			"\tKind    Kind      `json:\"kind\"`\n" +
+			"\tCreated time.Time `json:\"created\"`\n" +
+			"\tQty     int64     `json:\"qty\"`\n" + "}\n\n" +
+			"// NewNode9 returns a new Node9 with default values.\n" +
+			"func NewNode9() *Node9 {\n" +
+			"\treturn &Node9{\n" +
+			"\t\tKind:    Kind(\"b\"),\n" +
+			"\t\tCreated: time.Now(),\n" +
+			"\t\tQty:     1,\n" +
+			"\t}\n}\n",
+		},
+		{"node10", "package foo\n\ntype Node10 struct {\n" +
+			"\tFirst string `json:\"first\"`\n" +
+			"\tLast  string `json:\"last\"`\n" +
+			"\tQty   int64  `json:\"qty\"`\n" + "}\n\n" +
+			"// Full returns the computed value of (cat .first ' ' .last).\n" +
+			"func (m *Node10) Full() string {\n" +
+			"\treturn m.First + \" \" + m.Last\n}\n\n" +
+			"// Next returns the computed value of (add .qty 1).\n" +
+			"func (m *Node10) Next() int64 {\n" +
+			"\treturn m.Qty + 1\n}\n",
+		},
+		{"node11", "package foo\n\nimport (\n\t\"strconv\"\n\t\"xelf.org/daql/dom\"\n)\n\n" +
+			"type Node11 struct {\n" +
+			"\tA    int64  `json:\"a\"`\n" +
+			"\tB    string `json:\"b\"`\n" +
+			"\tC    Kind   `json:\"c\"`\n" +
+			"\tNote string `json:\"note,omitempty\"`\n" + "}\n\n" +
+			"// Key returns the canonical key string of the composite primary key.\n" +
+			"func (m *Node11) Key() string {\n" +
+			"\treturn dom.JoinKey(strconv.FormatInt(m.A, 10), m.B, string(m.C))\n}\n",
+		},
+		{"node12", "package foo\n\ntype Node12 struct {\n" +
+			"\tNote string `json:\"note\"`\n" + "}\n\n" +
+			"// NewNode12 returns a new Node12 with default values.\n" +
+			"func NewNode12() *Node12 {\n" +
+			"\treturn &Node12{\n" +
+			"\t\tNote: \"unknown\",\n" +
+			"\t}\n}\n",
+		},
+		{"node13", "package foo\n\nimport (\n\t\"strconv\"\n\t\"xelf.org/daql/dom\"\n)\n\n" +
+			"type Node13 struct {\n" +
+			"\tKey string `json:\"key\"`\n" +
+			"\tRev int64  `json:\"rev\"`\n" + "}\n\n" +
+			"// PrimaryKey returns the canonical key string of the composite primary key.\n" +
+			"func (m *Node13) PrimaryKey() string {\n" +
+			"\treturn dom.JoinKey(m.Key, strconv.FormatInt(m.Rev, 10))\n}\n",
+		},
 	}
 	pkgs := map[string]string{
 		"foo": "path/to/foo",
@@ -51,7 +51,7 @@ func WriteType(g *gen.Gen, t typ.Type) error {
 		return g.Fmt(Import(g, "*lit.Dict"))
 	case knd.Obj:
 		if t.Ref == "" {
-			return writeObj(g, t)
+			return writeObj(g, t, nil)
 		}
 		fallthrough
 	case knd.Bits, knd.Enum:
@@ -73,7 +73,8 @@ func WriteType(g *gen.Gen, t typ.Type) error {
 	return g.Fmt(r)
 }
 
-func writeObj(g *gen.Gen, t typ.Type) error {
+// writeObj writes a struct type for t. Fields with names in priv are not serialized.
+func writeObj(g *gen.Gen, t typ.Type, priv map[string]bool) error {
 	opt := t.Kind&knd.None != 0
 	if opt {
 		g.Byte('*')
@@ -94,7 +95,9 @@ func writeObj(g *gen.Gen, t typ.Type) error {
 		if err != nil {
 			return fmt.Errorf("write field %s: %w", f.Name, err)
 		}
-		if f.Key != "" {
+		if priv[privKey(f.Name)] {
+			g.Fmt(" `json:\"-\"`")
+		} else if f.Key != "" {
 			g.Fmt(" `json:\"")
 			g.Fmt(f.Key)
 			if opt {
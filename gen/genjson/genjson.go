@@ -0,0 +1,375 @@
+// Package genjson generates JSON Schema documents for dom schemas.
+//
+// Each schema is written as one document with a definition for every model. References to models
+// of other schemas point to the definitions in the documents of those schemas, that are expected
+// to be named '$schema.schema.json' and placed in the same location.
+package genjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/gen"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/cor"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Draft is the JSON Schema dialect of the generated documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+func NewGen(pr *dom.Project) *gen.Gen {
+	return &gen.Gen{Project: pr, Target: "jsonschema"}
+}
+
+// FileName returns the document file name for schema s.
+func FileName(s *dom.Schema) string { return fmt.Sprintf("%s.schema.json", s.Name) }
+
+func WriteSchemaFile(g *gen.Gen, name string, s *dom.Schema) error {
+	b := bfr.Get()
+	defer bfr.Put(b)
+	g.P = bfr.P{Writer: b, Tab: "\t"}
+	err := WriteSchema(g, s)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(name, b.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("write schema file %s error: %v", name, err)
+	}
+	return nil
+}
+
+// WriteSchema writes an indented JSON Schema document for schema s to the gen buffer.
+func WriteSchema(g *gen.Gen, s *dom.Schema) error {
+	defs := make(node, 0, len(s.Models))
+	for _, m := range s.Models {
+		if gen.NogenModel(m) {
+			continue
+		}
+		d, err := modelSchema(g, s, m)
+		if err != nil {
+			return fmt.Errorf("write model %s: %w", m.Name, err)
+		}
+		if d != nil {
+			defs = append(defs, field{m.Name, d})
+		}
+	}
+	doc := node{{"$schema", Draft}, {"$id", FileName(s)}, {"title", s.Label()}}
+	if d := s.Descr(); d != "" {
+		doc = append(doc, field{"description", d})
+	}
+	doc = append(doc, field{"$defs", defs})
+	raw, err := marshal(doc)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	err = json.Indent(&b, raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	b.WriteByte('\n')
+	_, err = g.Writer.Write(b.Bytes())
+	return err
+}
+
+// modelSchema returns the schema definition for model m of schema s, nil if the model kind has no
+// json representation, or an error. Bits are represented as integers and enums as lowercase names.
+// Computed elems are marked read only and private elems are left out.
+func modelSchema(g *gen.Gen, s *dom.Schema, m *dom.Model) (node, error) {
+	res := node{{"title", m.Name}}
+	if d := m.Descr(); d != "" {
+		res = append(res, field{"description", d})
+	}
+	switch m.Kind.Kind {
+	case knd.Bits:
+		var all int64
+		for _, el := range m.Elems {
+			all |= el.Val
+		}
+		return append(res, field{"type", "integer"}, field{"minimum", 0},
+			field{"maximum", all}), nil
+	case knd.Enum:
+		names := make([]string, 0, len(m.Elems))
+		for _, el := range m.Elems {
+			names = append(names, strings.ToLower(el.Name))
+		}
+		return append(res, field{"type", "string"}, field{"enum", names}), nil
+	case knd.Obj:
+		props, req, err := elemProps(g, s, m, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, field{"type", "object"}, field{"properties", props})
+		if len(req) > 0 {
+			res = append(res, field{"required", req})
+		}
+		return append(res, field{"additionalProperties", false}), nil
+	}
+	return nil, nil
+}
+
+// elemProps appends the properties and required keys of the elems of model m. Embedded models
+// are flattened like their json encoding.
+func elemProps(g *gen.Gen, s *dom.Schema, m *dom.Model, props node, req []string) (node, []string, error) {
+	for _, el := range m.Elems {
+		if gen.Priv(el) {
+			continue
+		}
+		if el.Name == "" {
+			em := refModel(g, s, el.Type.Ref)
+			if em == nil {
+				return nil, nil, fmt.Errorf("embedded model %s not found", el.Type.Ref)
+			}
+			var err error
+			props, req, err = elemProps(g, s, em, props, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		key := strings.TrimSuffix(el.Key(), "?")
+		p, err := typeSchema(g, s, el.Type)
+		if err != nil {
+			return nil, nil, fmt.Errorf("elem %s: %w", el.Name, err)
+		}
+		if d := el.Descr(); d != "" {
+			p = append(node{{"description", d}}, p...)
+		}
+		calc := el.CalcSrc() != ""
+		if calc {
+			p = append(p, field{"readOnly", true})
+		}
+		c, err := dom.ElemConstr(el)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c != nil {
+			p = constrSchema(p, el, c)
+		}
+		props = append(props, field{key, p})
+		if !calc && el.Bits&dom.BitOpt == 0 && el.Type.Kind&knd.None == 0 {
+			req = append(req, key)
+		}
+	}
+	return props, req, nil
+}
+
+// typeSchema returns the schema for type t used in schema s or an error.
+func typeSchema(g *gen.Gen, s *dom.Schema, t typ.Type) (res node, err error) {
+	switch t.Kind {
+	case knd.Any, knd.Data:
+		return node{}, nil
+	}
+	switch t.Kind & (knd.Data | knd.Ref) {
+	case knd.Bool:
+		res = node{{"type", "boolean"}}
+	case knd.Int:
+		res = node{{"type", "integer"}}
+	case knd.Real, knd.Num:
+		res = node{{"type", "number"}}
+	case knd.Str, knd.Char, knd.Span:
+		res = node{{"type", "string"}}
+	case knd.Raw:
+		res = node{{"type", "string"}, {"contentEncoding", "base64"}}
+	case knd.UUID:
+		res = node{{"type", "string"}, {"format", "uuid"}}
+	case knd.Time:
+		res = node{{"type", "string"}, {"format", "date-time"}}
+	case knd.List:
+		res = node{{"type", "array"}}
+		if el := typ.ContEl(t); el != typ.Any {
+			items, err := typeSchema(g, s, el)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, field{"items", items})
+		}
+	case knd.Dict:
+		res = node{{"type", "object"}}
+		if el := typ.ContEl(t); el != typ.Any {
+			vals, err := typeSchema(g, s, el)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, field{"additionalProperties", vals})
+		}
+	case knd.Obj:
+		if t.Ref == "" {
+			res, err = objSchema(g, s, t)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+		fallthrough
+	case knd.Bits, knd.Enum, knd.Ref:
+		ref, err := defRef(g, s, t.Ref)
+		if err != nil {
+			return nil, err
+		}
+		res = node{{"$ref", ref}}
+	default:
+		return nil, fmt.Errorf("type %s cannot be represented in json schema", t)
+	}
+	if t.Kind&knd.None != 0 {
+		if len(res) > 0 && res[0].k == "type" {
+			res[0].v = []string{res[0].v.(string), "null"}
+		} else {
+			res = node{{"anyOf", []node{res, {{"type", "null"}}}}}
+		}
+	}
+	return res, nil
+}
+
+func objSchema(g *gen.Gen, s *dom.Schema, t typ.Type) (node, error) {
+	b, ok := t.Body.(*typ.ParamBody)
+	if !ok {
+		return nil, fmt.Errorf("invalid obj type %s", t)
+	}
+	var props node
+	var req []string
+	for _, p := range b.Params {
+		if p.Key == "" {
+			return nil, fmt.Errorf("unnamed field in obj type %s", t)
+		}
+		ps, err := typeSchema(g, s, p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", p.Name, err)
+		}
+		props = append(props, field{p.Key, ps})
+		if !p.IsOpt() {
+			req = append(req, p.Key)
+		}
+	}
+	res := node{{"type", "object"}, {"properties", props}}
+	if len(req) > 0 {
+		res = append(res, field{"required", req})
+	}
+	return append(res, field{"additionalProperties", false}), nil
+}
+
+// defRef returns the json pointer to the model definition of the qualified model name ref.
+func defRef(g *gen.Gen, s *dom.Schema, ref string) (string, error) {
+	idx := strings.IndexByte(ref, '.')
+	if idx < 0 {
+		return "", fmt.Errorf("unqualified model reference %q", ref)
+	}
+	name := cor.Cased(ref[idx+1:])
+	if m := refModel(g, s, ref); m != nil {
+		name = m.Name
+	}
+	if sch := ref[:idx]; sch != s.Name {
+		return fmt.Sprintf("%s.schema.json#/$defs/%s", sch, name), nil
+	}
+	return fmt.Sprintf("#/$defs/%s", name), nil
+}
+
+func refModel(g *gen.Gen, s *dom.Schema, ref string) *dom.Model {
+	idx := strings.IndexByte(ref, '.')
+	if idx < 0 {
+		return nil
+	}
+	key := strings.ToLower(ref[idx+1:])
+	if ref[:idx] == s.Name {
+		return s.Model(key)
+	}
+	return g.Project.Schema(ref[:idx]).Model(key)
+}
+
+// constrSchema appends the keywords matching the elem constraints c to the property p.
+func constrSchema(p node, el *dom.Elem, c *dom.Constr) node {
+	if c.Min != nil {
+		p = append(p, field{"minimum", *c.Min})
+	}
+	if c.Max != nil {
+		p = append(p, field{"maximum", *c.Max})
+	}
+	if c.Len > 0 {
+		if el.Type.Kind&knd.List != 0 {
+			p = append(p, field{"maxItems", c.Len})
+		} else {
+			p = append(p, field{"maxLength", c.Len})
+		}
+	}
+	if c.Pattern != nil {
+		p = append(p, field{"pattern", c.Pattern.String()})
+	}
+	if len(c.OneOf) > 0 {
+		vals := make([]interface{}, 0, len(c.OneOf))
+		for _, v := range c.OneOf {
+			vals = append(vals, jsonVal(v))
+		}
+		p = append(p, field{"enum", vals})
+	}
+	return p
+}
+
+func jsonVal(v lit.Val) interface{} {
+	switch v.Type().Kind & knd.Data {
+	case knd.Bool:
+		return !v.Zero()
+	case knd.Int:
+		if n, err := lit.ToInt(v); err == nil {
+			return int64(n)
+		}
+	case knd.Real, knd.Num:
+		if r, err := lit.ToReal(v); err == nil {
+			return float64(r)
+		}
+	}
+	if s, err := lit.ToStr(v); err == nil {
+		return string(s)
+	}
+	return v.String()
+}
+
+// node is a json object that keeps the field order.
+type node []field
+
+type field struct {
+	k string
+	v interface{}
+}
+
+func (n node) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range n {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := marshal(f.k)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := marshal(f.v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.k, err)
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// marshal returns the json encoding of v without escaping html characters.
+func marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte{'\n'}), nil
+}
@@ -0,0 +1,155 @@
+package genjson
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/bfr"
+)
+
+const barRaw = `(schema bar (Kind:enum X; Y; Z;))`
+const fooRaw = `(schema foo doc:'Foo models.'
+	(Align:bits A; B; C:3)
+	(Kind:enum A; B; C;)
+	(Node1; Name?:str)
+	(Node2; Start:time Data:raw ID:uuid)
+	(Node3; Kind:<enum@bar.Kind>)
+	(Node4; Kind:<enum@foo.Kind> Align?:<bits@foo.Align>)
+	(Node5; ID:int @Node4)
+	(Node6; Name:str (Cache?:str priv;))
+	(Node7; nogen; Name:str)
+	(Node8; (Name:str len:5 pattern:'^[a-z]+$') (Qty?:int min:1 max:9) (Kind:str oneof:['a' 'b']))
+	(Node9; Tags:list|str Attrs:dict|int Pos:<obj X:int Y?:real> Note:str?)
+	(Node10; First:str Last:str Full:(cat .first ' ' .last))
+	(Node11; ID:int (Parent?:@Node11.ID doc:'Parent node.') Kids:list|@Node5.ID)
+)`
+
+func TestModelSchema(t *testing.T) {
+	_, err := dom.ReadSchema(nil, strings.NewReader(barRaw), "bar")
+	if err != nil {
+		t.Fatalf("schema bar error %v", err)
+	}
+	s, err := dom.ReadSchema(nil, strings.NewReader(fooRaw), "foo")
+	if err != nil {
+		t.Fatalf("schema foo error %v", err)
+	}
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"align", `{"title":"Align","type":"integer","minimum":0,"maximum":3}`},
+		{"kind", `{"title":"Kind","type":"string","enum":["a","b","c"]}`},
+		{"node1", `{"title":"Node1","type":"object","properties":{` +
+			`"name":{"type":"string"}},"additionalProperties":false}`},
+		{"node2", `{"title":"Node2","type":"object","properties":{` +
+			`"start":{"type":"string","format":"date-time"},` +
+			`"data":{"type":"string","contentEncoding":"base64"},` +
+			`"id":{"type":"string","format":"uuid"}},` +
+			`"required":["start","data","id"],"additionalProperties":false}`},
+		{"node3", `{"title":"Node3","type":"object","properties":{` +
+			`"kind":{"$ref":"bar.schema.json#/$defs/Kind"}},` +
+			`"required":["kind"],"additionalProperties":false}`},
+		{"node5", `{"title":"Node5","type":"object","properties":{` +
+			`"id":{"type":"integer"},` +
+			`"kind":{"$ref":"#/$defs/Kind"},` +
+			`"align":{"$ref":"#/$defs/Align"}},` +
+			`"required":["id","kind"],"additionalProperties":false}`},
+		{"node6", `{"title":"Node6","type":"object","properties":{` +
+			`"name":{"type":"string"}},"required":["name"],"additionalProperties":false}`},
+		{"node8", `{"title":"Node8","type":"object","properties":{` +
+			`"name":{"type":"string","maxLength":5,"pattern":"^[a-z]+$"},` +
+			`"qty":{"type":"integer","minimum":1,"maximum":9},` +
+			`"kind":{"type":"string","enum":["a","b"]}},` +
+			`"required":["name","kind"],"additionalProperties":false}`},
+		{"node9", `{"title":"Node9","type":"object","properties":{` +
+			`"tags":{"type":"array","items":{"type":"string"}},` +
+			`"attrs":{"type":"object","additionalProperties":{"type":"integer"}},` +
+			`"pos":{"type":"object","properties":{"x":{"type":"integer"},"y":{"type":"number"}},` +
+			`"required":["x"],"additionalProperties":false},` +
+			`"note":{"type":["string","null"]}},` +
+			`"required":["tags","attrs","pos"],"additionalProperties":false}`},
+		{"node10", `{"title":"Node10","type":"object","properties":{` +
+			`"first":{"type":"string"},"last":{"type":"string"},` +
+			`"full":{"type":"string","readOnly":true}},` +
+			`"required":["first","last"],"additionalProperties":false}`},
+		{"node11", `{"title":"Node11","type":"object","properties":{` +
+			`"id":{"type":"integer"},` +
+			`"parent":{"description":"Parent node.","type":"integer"},` +
+			`"kids":{"type":"array","items":{"type":"integer"}}},` +
+			`"required":["id","kids"],"additionalProperties":false}`},
+	}
+	g := NewGen(nil)
+	for _, test := range tests {
+		m := s.Model(test.model)
+		if m == nil {
+			t.Errorf("model %s not found", test.model)
+			continue
+		}
+		d, err := modelSchema(g, s, m)
+		if err != nil {
+			t.Errorf("model %s error: %v", test.model, err)
+			continue
+		}
+		raw, err := marshal(d)
+		if err != nil {
+			t.Errorf("marshal %s error: %v", test.model, err)
+			continue
+		}
+		if got := string(raw); got != test.want {
+			t.Errorf("for %s want %s\n\tgot %s", test.model, test.want, got)
+		}
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	s, err := dom.ReadSchema(nil, strings.NewReader(`(schema baz doc:'Baz models.'
+		(Kind:enum A; B;) (Node7; nogen; Name:str) (Node; ID:int Kind:<enum@baz.Kind>))`), "baz")
+	if err != nil {
+		t.Fatalf("schema baz error %v", err)
+	}
+	var b strings.Builder
+	g := NewGen(&dom.Project{Schemas: []*dom.Schema{s}})
+	g.P = bfr.P{Writer: &b}
+	err = WriteSchema(g, s)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	want := `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"$id": "baz.schema.json",
+	"title": "baz",
+	"description": "Baz models.",
+	"$defs": {
+		"Kind": {
+			"title": "Kind",
+			"type": "string",
+			"enum": [
+				"a",
+				"b"
+			]
+		},
+		"Node": {
+			"title": "Node",
+			"type": "object",
+			"properties": {
+				"id": {
+					"type": "integer"
+				},
+				"kind": {
+					"$ref": "#/$defs/Kind"
+				}
+			},
+			"required": [
+				"id",
+				"kind"
+			],
+			"additionalProperties": false
+		}
+	}
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+}
@@ -0,0 +1,410 @@
+package gensql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// token is a sql token. The type is one of w for words, q for quoted identifiers, s for strings,
+// n for numbers or the punctuation character itself.
+type token struct {
+	typ byte
+	val string
+}
+
+// stmt is a sql statement with its source text.
+type stmt struct {
+	raw  string
+	toks []token
+}
+
+// splitDDL returns the statements of the sql source without comments.
+func splitDDL(src string) ([]stmt, error) {
+	var res []stmt
+	var cur stmt
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			cur.raw = strings.TrimSpace(src[start:end])
+			res = append(res, cur)
+		}
+		cur, start = stmt{}, -1
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(src[i:], "--"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			i += end
+			continue
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+			continue
+		case c == ';':
+			flush(i)
+			i++
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		var tok token
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := c
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src); j++ {
+				if src[j] == end {
+					if j+1 < len(src) && src[j+1] == end {
+						b.WriteByte(end)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated quote at offset %d", i)
+			}
+			tok = token{'q', b.String()}
+			if c == '\'' {
+				tok.typ = 's'
+			}
+			i = j + 1
+		case isWordStart(c):
+			j := i + 1
+			for j < len(src) && (isWordStart(src[j]) || isDigit(src[j]) || src[j] == '$') {
+				j++
+			}
+			tok, i = token{'w', src[i:j]}, j
+		case isDigit(c):
+			j := i + 1
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			tok, i = token{'n', src[i:j]}, j
+		default:
+			tok, i = token{c, string(c)}, i+1
+		}
+		cur.toks = append(cur.toks, tok)
+	}
+	flush(len(src))
+	return res, nil
+}
+
+func isWordStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool     { return c >= '0' && c <= '9' }
+
+func isKw(t token, words ...string) bool {
+	if t.typ != 'w' {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.val, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// colKws are the keywords that start column constraints.
+var colKws = []string{"CONSTRAINT", "NOT", "NULL", "PRIMARY", "UNIQUE", "DEFAULT", "REFERENCES",
+	"CHECK", "COLLATE", "GENERATED", "AS"}
+
+// isConstraint returns whether t starts a table constraint.
+func isConstraint(t token) bool {
+	return isKw(t, "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE", "LIKE")
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+// end returns whether all tokens are consumed.
+func (p *parser) end() bool { return p.pos >= len(p.toks) }
+
+// atEnd returns whether the current list element is consumed.
+func (p *parser) atEnd() bool {
+	t := p.peek()
+	return p.end() || t.typ == ',' || t.typ == ')'
+}
+
+func (p *parser) peekKw(w string) bool { return isKw(p.peek(), w) }
+
+// kw consumes and returns true if the next tokens are the keywords words.
+func (p *parser) kw(words ...string) bool {
+	if p.pos+len(words) > len(p.toks) {
+		return false
+	}
+	for i, w := range words {
+		if !isKw(p.toks[p.pos+i], w) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *parser) punct(c byte) bool {
+	if p.peek().typ == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) ident() (string, bool) {
+	t := p.peek()
+	if t.typ != 'w' && t.typ != 'q' {
+		return "", false
+	}
+	p.pos++
+	return strings.ToLower(t.val), true
+}
+
+// qualified parses an optionally schema qualified name.
+func (p *parser) qualified() (sch, name string, ok bool) {
+	name, ok = p.ident()
+	if ok && p.punct('.') {
+		sch = name
+		name, ok = p.ident()
+	}
+	return sch, name, ok
+}
+
+// idents parses a parenthesized list of identifiers.
+func (p *parser) idents() (res []string, _ bool) {
+	if !p.punct('(') {
+		return nil, false
+	}
+	for {
+		id, ok := p.ident()
+		if !ok {
+			return nil, false
+		}
+		res = append(res, id)
+		if p.punct(')') {
+			return res, true
+		}
+		if !p.punct(',') {
+			return nil, false
+		}
+	}
+}
+
+// group parses balanced parenthesis and returns the enclosed tokens.
+func (p *parser) group() ([]token, bool) {
+	if !p.punct('(') {
+		return nil, false
+	}
+	start := p.pos
+	for depth := 1; !p.end(); {
+		switch p.next().typ {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return p.toks[start : p.pos-1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// part consumes and returns the tokens up to the end of the current list element.
+func (p *parser) part() []token {
+	start := p.pos
+	for !p.atEnd() {
+		if p.peek().typ == '(' {
+			if _, ok := p.group(); !ok {
+				break
+			}
+			continue
+		}
+		p.pos++
+	}
+	return p.toks[start:p.pos]
+}
+
+// expr consumes and returns the tokens of an expression up to the next column constraint.
+func (p *parser) expr() []token {
+	start := p.pos
+	for !p.atEnd() {
+		if p.pos > start && isKw(p.peek(), colKws...) {
+			break
+		}
+		if p.peek().typ == '(' {
+			if _, ok := p.group(); !ok {
+				break
+			}
+			continue
+		}
+		p.pos++
+	}
+	return p.toks[start:p.pos]
+}
+
+func (p *parser) constraintName() {
+	if p.kw("CONSTRAINT") {
+		p.ident()
+	}
+}
+
+// sqlType parses a column type with optional schema, type arguments and array suffix.
+func (p *parser) sqlType() (t sqlType, _ bool) {
+	var words []string
+	for !p.atEnd() {
+		tok := p.peek()
+		switch {
+		case isKw(tok, colKws...):
+		case isKw(tok, "ARRAY"):
+			p.pos++
+			t.array = true
+			continue
+		case tok.typ == 'w' || tok.typ == 'q' && len(words) == 0:
+			p.pos++
+			if len(words) == 0 && t.schema == "" && p.punct('.') {
+				t.schema = strings.ToLower(tok.val)
+				continue
+			}
+			words = append(words, strings.ToLower(tok.val))
+			continue
+		case tok.typ == '(':
+			args, ok := p.group()
+			if !ok {
+				return t, false
+			}
+			for _, a := range args {
+				if a.typ != ',' {
+					t.args = append(t.args, a.val)
+				}
+			}
+			continue
+		case tok.typ == '[':
+			p.pos++
+			if p.peek().typ == 'n' {
+				p.pos++
+			}
+			if !p.punct(']') {
+				return t, false
+			}
+			t.array = true
+			continue
+		}
+		break
+	}
+	t.name = strings.Join(words, " ")
+	return t, t.name != ""
+}
+
+// references parses the referenced table and column and the referential actions. Unmapped
+// clauses are returned as text.
+func (p *parser) references() (*ddlRef, string, bool) {
+	sch, name, ok := p.qualified()
+	if !ok {
+		return nil, "", false
+	}
+	r := &ddlRef{schema: sch, table: name}
+	if p.peek().typ == '(' {
+		keys, ok := p.idents()
+		if !ok || len(keys) != 1 {
+			return nil, "", false
+		}
+		r.col = keys[0]
+	}
+	var rest []string
+	for !p.atEnd() && !isKw(p.peek(), colKws...) {
+		switch {
+		case p.kw("ON", "DELETE"):
+			r.onDel = p.action()
+			if r.onDel == "" || r.onDel == "set default" {
+				rest = append(rest, "ON DELETE "+strings.ToUpper(r.onDel))
+				r.onDel = ""
+			}
+		case p.kw("ON", "UPDATE"):
+			if act := p.action(); act != "no action" {
+				rest = append(rest, "ON UPDATE "+strings.ToUpper(act))
+			}
+		default:
+			rest = append(rest, tokText(p.expr()))
+		}
+	}
+	return r, strings.TrimSpace(strings.Join(rest, " ")), true
+}
+
+func (p *parser) action() string {
+	switch {
+	case p.kw("CASCADE"):
+		return "cascade"
+	case p.kw("RESTRICT"):
+		return "restrict"
+	case p.kw("SET", "NULL"):
+		return "set null"
+	case p.kw("SET", "DEFAULT"):
+		return "set default"
+	case p.kw("NO", "ACTION"):
+		return "no action"
+	}
+	return ""
+}
+
+// tokText returns the tokens as sql text.
+func tokText(toks []token) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && space(toks[i-1], t) {
+			b.WriteByte(' ')
+		}
+		switch t.typ {
+		case 's':
+			fmt.Fprintf(&b, "'%s'", strings.ReplaceAll(t.val, "'", "''"))
+		case 'q':
+			fmt.Fprintf(&b, "\"%s\"", strings.ReplaceAll(t.val, "\"", "\"\""))
+		default:
+			b.WriteString(t.val)
+		}
+	}
+	return b.String()
+}
+
+func space(prev, cur token) bool {
+	switch {
+	case prev.typ == '(' || prev.typ == '.' || prev.typ == ':':
+		return false
+	case cur.typ == ')' || cur.typ == ',' || cur.typ == '.' || cur.typ == ':':
+		return false
+	case cur.typ == '(':
+		// function calls have no space
+		return prev.typ != 'w' && prev.typ != 'q' || isKw(prev, "IN", "CHECK", "KEY", "REFERENCES",
+			"AS", "ON", "USING", "AND", "OR", "NOT", "WITH", "EXISTS", "INCLUDE")
+	}
+	return true
+}
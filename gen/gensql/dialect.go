@@ -0,0 +1,198 @@
+package gensql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Dialect abstracts the differences of the supported databases for ddl and query generation.
+type Dialect interface {
+	Name() string
+	// Ident returns the column or schema identifier name, quoted if it is a reserved word.
+	Ident(name string) string
+	// Table returns the quoted table or enum type name for model m.
+	Table(m *dom.Model) string
+	// Type returns the column type for t or an error.
+	Type(t typ.Type) (string, error)
+	// Param returns the placeholder of the bound parameter n starting at one.
+	Param(n int) string
+	// Limit returns the limit and offset clause or an empty string.
+	Limit(lim, off int64) string
+	// Arg returns the driver argument for the literal value v or an error.
+	Arg(v lit.Val) (interface{}, error)
+}
+
+var (
+	// Postgres maps each schema to a postgres schema and uses native enum, uuid and array types.
+	Postgres Dialect = pgDialect{}
+	// SQLite prefixes table names with the schema name and stores time and uuid values as text,
+	// enums as text with a check constraint and containers as json text.
+	SQLite Dialect = sqliteDialect{}
+)
+
+// DialectFor returns the dialect for a gen target name. It defaults to postgres.
+func DialectFor(target string) Dialect {
+	if target == SQLite.Name() {
+		return SQLite
+	}
+	return Postgres
+}
+
+type pgDialect struct{}
+
+func (pgDialect) Name() string                       { return "postgres" }
+func (pgDialect) Ident(name string) string           { return quoteIdent(name) }
+func (pgDialect) Table(m *dom.Model) string          { return quoteQualified(TableName(m)) }
+func (pgDialect) Type(t typ.Type) (string, error)    { return TypeString(t) }
+func (pgDialect) Param(n int) string                 { return fmt.Sprintf("$%d", n) }
+func (pgDialect) Arg(v lit.Val) (interface{}, error) { return argVal(v, false) }
+func (pgDialect) Limit(lim, off int64) string {
+	var b strings.Builder
+	if lim > 0 {
+		fmt.Fprintf(&b, " LIMIT %d", lim)
+	}
+	if off > 0 {
+		fmt.Fprintf(&b, " OFFSET %d", off)
+	}
+	return b.String()
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                       { return "sqlite" }
+func (sqliteDialect) Ident(name string) string           { return quoteIdent(name) }
+func (sqliteDialect) Table(m *dom.Model) string          { return quoteIdent(sqliteTable(m)) }
+func (sqliteDialect) Param(int) string                   { return "?" }
+func (sqliteDialect) Arg(v lit.Val) (interface{}, error) { return argVal(v, true) }
+func (sqliteDialect) Limit(lim, off int64) string {
+	if lim <= 0 && off <= 0 {
+		return ""
+	}
+	if lim <= 0 {
+		// sqlite only allows an offset after a limit
+		lim = -1
+	}
+	if off > 0 {
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", lim, off)
+	}
+	return fmt.Sprintf(" LIMIT %d", lim)
+}
+func (sqliteDialect) Type(t typ.Type) (string, error) {
+	switch t.Kind {
+	case knd.Any, knd.Data:
+		return "TEXT", nil
+	}
+	switch t.Kind & knd.Data {
+	case knd.Bool, knd.Int, knd.Bits:
+		return "INTEGER", nil
+	case knd.Real:
+		return "REAL", nil
+	case knd.Num:
+		return "NUMERIC", nil
+	case knd.Str, knd.Char, knd.Enum, knd.UUID, knd.Time, knd.Span:
+		return "TEXT", nil
+	case knd.Raw:
+		return "BLOB", nil
+	case knd.List, knd.Dict, knd.Obj:
+		return "TEXT", nil
+	}
+	return "", fmt.Errorf("type %s cannot be represented in sqlite", t)
+}
+
+// sqliteTable returns the unquoted sqlite table name for model m prefixed with the schema name.
+func sqliteTable(m *dom.Model) string {
+	return strings.ToLower(fmt.Sprintf("%s_%s", m.Schema, m.Name))
+}
+
+// sqlKeywords holds the reserved words of postgres and sqlite that are likely used as names.
+var sqlKeywords = map[string]bool{
+	"all": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "between": true, "both": true, "case": true, "cast": true, "check": true,
+	"collate": true, "column": true, "constraint": true, "create": true, "cross": true,
+	"current_date": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "default": true, "delete": true, "desc": true, "distinct": true,
+	"do": true, "drop": true, "else": true, "end": true, "except": true, "exists": true,
+	"false": true, "fetch": true, "for": true, "foreign": true, "from": true, "full": true,
+	"grant": true, "group": true, "having": true, "in": true, "index": true, "inner": true,
+	"insert": true, "intersect": true, "into": true, "is": true, "join": true, "left": true,
+	"like": true, "limit": true, "natural": true, "not": true, "null": true, "offset": true,
+	"on": true, "only": true, "or": true, "order": true, "outer": true, "primary": true,
+	"references": true, "returning": true, "right": true, "select": true, "session_user": true,
+	"set": true, "some": true, "table": true, "then": true, "to": true, "true": true,
+	"union": true, "unique": true, "update": true, "user": true, "using": true, "values": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+// quoteIdent returns name in double quotes if it is a reserved word or no plain identifier.
+func quoteIdent(name string) string {
+	if !sqlKeywords[name] && plainIdent(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteQualified returns the dot separated parts of name each quoted if necessary.
+func quoteQualified(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = quoteIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func plainIdent(name string) bool {
+	for i, r := range name {
+		if r != '_' && (r < 'a' || r > 'z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// argVal converts v to a driver argument. Container values are passed as json text. With text
+// set time values are passed as formatted strings as well.
+func argVal(v lit.Val, text bool) (interface{}, error) {
+	if v == nil || v.Nil() {
+		return nil, nil
+	}
+	switch v.Type().Kind & knd.Data {
+	case knd.Bool:
+		b, err := lit.ToBool(v)
+		return bool(b), err
+	case knd.Int, knd.Bits:
+		n, err := lit.ToInt(v)
+		return int64(n), err
+	case knd.Real, knd.Num:
+		r, err := lit.ToReal(v)
+		return float64(r), err
+	case knd.Str, knd.Char, knd.Enum, knd.UUID, knd.Span:
+		s, err := lit.ToStr(v)
+		return string(s), err
+	case knd.Raw:
+		if r, ok := v.Value().(lit.Raw); ok {
+			return []byte(r), nil
+		}
+	case knd.Time:
+		if t, ok := v.Value().(lit.Time); ok {
+			if text {
+				return time.Time(t).UTC().Format(time.RFC3339Nano), nil
+			}
+			return time.Time(t), nil
+		}
+	case knd.List, knd.Dict, knd.Obj:
+		var b strings.Builder
+		err := v.Print(&bfr.P{Writer: &b, JSON: true})
+		if err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	}
+	return nil, fmt.Errorf("unsupported argument %s", v)
+}
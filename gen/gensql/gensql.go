@@ -0,0 +1,402 @@
+// Package gensql generates SQL data definition and query statements for dom schemas.
+//
+// The postgres dialect maps each schema to a postgres schema of the same name, the sqlite dialect
+// uses the schema name as table name prefix. Enum models are written as enum types or check
+// constraints and obj models as tables, except models only used as embedded types. Embedded model
+// fields are flattened into the table columns. Foreign keys are derived from the project relations.
+// Postgres foreign keys are added after all tables of the schema are created, so models can refer
+// to later or their own models. Names that are reserved words, like user or order, are quoted.
+//
+// ImportDDL reads existing create statements back into a dom schema declaration.
+package gensql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/gen"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/typ"
+)
+
+// NewGen returns a new generator for dialect d.
+func NewGen(pr *dom.Project, d Dialect) *gen.Gen {
+	return &gen.Gen{Project: pr, Target: d.Name()}
+}
+
+// FileName returns the file name for the ddl statements of schema s in dialect d.
+func FileName(d Dialect, s *dom.Schema) string {
+	if d == SQLite {
+		return fmt.Sprintf("%s.sqlite.sql", s.Name)
+	}
+	return fmt.Sprintf("%s.pg.sql", s.Name)
+}
+
+func WriteSchemaFile(g *gen.Gen, name string, s *dom.Schema) error {
+	b := bfr.Get()
+	defer bfr.Put(b)
+	g.P = bfr.P{Writer: b, Tab: "\t"}
+	err := WriteSchema(g, s)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(name, b.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("write schema file %s error: %v", name, err)
+	}
+	return nil
+}
+
+// WriteSchema writes the ddl statements for schema s in the dialect of the gen target.
+func WriteSchema(g *gen.Gen, s *dom.Schema) error {
+	if g.Project.Schema(s.Name) == nil {
+		// use a project with only schema s to resolve relations and embedded models
+		cg := *g
+		cg.Project = &dom.Project{Schemas: []*dom.Schema{s}}
+		g = &cg
+	}
+	rels, err := dom.Relate(g.Project)
+	if err != nil {
+		return err
+	}
+	d := DialectFor(g.Target)
+	if d == Postgres {
+		g.Fmt("CREATE SCHEMA %s;\n", d.Ident(s.Key()))
+	}
+	var tables []*dom.Model
+	for _, m := range s.Models {
+		if gen.NogenModel(m) {
+			continue
+		}
+		switch m.Kind.Kind {
+		case knd.Enum:
+			if d != Postgres {
+				continue
+			}
+			g.Byte('\n')
+			err = WriteEnum(g, m)
+		case knd.Obj:
+			if len(m.PK()) == 0 && rels.Embedded(m) {
+				continue
+			}
+			if d == Postgres || len(tables) > 0 {
+				g.Byte('\n')
+			}
+			err = WriteTable(g, m, rels[m.Qualified()])
+			if err == nil {
+				err = WriteIndices(g, m)
+			}
+			tables = append(tables, m)
+		}
+		if err != nil {
+			return fmt.Errorf("write model %s: %w", m.Name, err)
+		}
+	}
+	if d != Postgres {
+		return nil
+	}
+	for _, m := range tables {
+		err = WriteForeignKeys(g, m, rels[m.Qualified()])
+		if err != nil {
+			return fmt.Errorf("write model %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// WriteEnum writes a postgres create type statement for the enum model m.
+func WriteEnum(g *gen.Gen, m *dom.Model) error {
+	g.Fmt("CREATE TYPE %s AS ENUM (%s);\n", Postgres.Table(m), enumVals(m))
+	return nil
+}
+
+// WriteTable writes a create table statement for the obj model m. The sqlite dialect declares the
+// foreign keys of the outgoing relations mr and checks enum values as part of the columns.
+func WriteTable(g *gen.Gen, m *dom.Model, mr *dom.ModelRels) error {
+	d := DialectFor(g.Target)
+	cols, err := Columns(g, m)
+	if err != nil {
+		return err
+	}
+	// composite primary keys are declared as table constraint
+	pks := m.PK()
+	g.Fmt("CREATE TABLE %s (\n", d.Table(m))
+	for i, c := range cols {
+		if i > 0 {
+			g.Fmt(",\n")
+		}
+		el := c.Elem
+		ts := c.Type
+		if el.Bits&dom.BitAuto != 0 && d == Postgres {
+			switch ts {
+			case "int8":
+				ts = "serial8"
+			case "int4":
+				ts = "serial4"
+			}
+		}
+		g.Fmt("\t%s %s", d.Ident(c.Key), ts)
+		switch {
+		case el.Bits&dom.BitPK != 0 && len(pks) == 1:
+			g.Fmt(" PRIMARY KEY")
+			if el.Bits&dom.BitAuto != 0 && d == SQLite {
+				g.Fmt(" AUTOINCREMENT")
+			}
+		case el.Bits&dom.BitOpt == 0 && el.Type.Kind&knd.None == 0:
+			g.Fmt(" NOT NULL")
+		}
+		if el.Bits&dom.BitUniq != 0 {
+			g.Fmt(" UNIQUE")
+		}
+		if d != SQLite {
+			continue
+		}
+		if el.Type.Kind&knd.Data == knd.Enum {
+			em := g.Project.Model(strings.ToLower(el.Type.Ref))
+			if em == nil {
+				return fmt.Errorf("enum %s not found", el.Type.Ref)
+			}
+			g.Fmt(" CHECK (%s IN (%s))", d.Ident(c.Key), enumVals(em))
+		}
+		if r, ok := foreignKey(mr, c.Key); ok {
+			ref, err := refKey(r)
+			if err != nil {
+				return err
+			}
+			g.Fmt(" REFERENCES %s (%s)%s", d.Table(r.B.Model), d.Ident(ref), onDel(m, c.Key))
+		}
+	}
+	if len(pks) > 1 {
+		keys := make([]string, 0, len(pks))
+		for _, el := range pks {
+			keys = append(keys, d.Ident(ColKey(el)))
+		}
+		g.Fmt(",\n\tPRIMARY KEY (%s)", strings.Join(keys, ", "))
+	}
+	return g.Fmt("\n);\n")
+}
+
+// WriteIndices writes create index statements for the indices and elems flagged with idx of m.
+func WriteIndices(g *gen.Gen, m *dom.Model) error {
+	for _, el := range m.Elems {
+		if el.Bits&dom.BitIdx != 0 && el.Bits&(dom.BitPK|dom.BitUniq) == 0 {
+			err := writeIndex(g, m, &dom.Index{Keys: []string{ColKey(el)}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if m.Object == nil {
+		return nil
+	}
+	for _, idx := range m.Object.Indices {
+		err := writeIndex(g, m, idx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeIndex(g *gen.Gen, m *dom.Model, idx *dom.Index) error {
+	if len(idx.Keys) == 0 {
+		return fmt.Errorf("index without keys")
+	}
+	d := DialectFor(g.Target)
+	keys := make([]string, 0, len(idx.Keys))
+	cols := make([]string, 0, len(idx.Keys))
+	for _, k := range idx.Keys {
+		keys = append(keys, strings.ToLower(k))
+		cols = append(cols, d.Ident(strings.ToLower(k)))
+	}
+	name := idx.Name
+	if name == "" {
+		// postgres index names are scoped by schema, sqlite index names are global
+		pre := m.Key()
+		if d != Postgres {
+			pre = sqliteTable(m)
+		}
+		name = fmt.Sprintf("%s_%s_idx", pre, strings.Join(keys, "_"))
+	}
+	g.Fmt("CREATE ")
+	if idx.Unique {
+		g.Fmt("UNIQUE ")
+	}
+	return g.Fmt("INDEX %s ON %s (%s);\n", d.Ident(name), d.Table(m), strings.Join(cols, ", "))
+}
+
+// WriteForeignKeys writes postgres alter table statements adding the foreign keys of the outgoing
+// relations mr of model m preceded by an empty line.
+func WriteForeignKeys(g *gen.Gen, m *dom.Model, mr *dom.ModelRels) error {
+	if mr == nil {
+		return nil
+	}
+	var sep bool
+	for _, r := range mr.Out {
+		if !isForeignKey(r) {
+			continue
+		}
+		if !sep {
+			g.Byte('\n')
+			sep = true
+		}
+		key := strings.TrimSuffix(r.A.Key, "?")
+		ref, err := refKey(r)
+		if err != nil {
+			return err
+		}
+		g.Fmt("ALTER TABLE %s ADD FOREIGN KEY (%s) REFERENCES %s (%s)%s;\n",
+			Postgres.Table(m), Postgres.Ident(key), Postgres.Table(r.B.Model),
+			Postgres.Ident(ref), onDel(m, key))
+	}
+	return nil
+}
+
+// isForeignKey returns whether the relation r is a foreign key. Relaxed, intermediate, embedded
+// and list references are no foreign keys.
+func isForeignKey(r dom.Relation) bool {
+	return r.Via.Model == nil && r.Rel&(dom.RelEmbed|dom.RelRelax) == 0 && r.Rel != dom.RelNN
+}
+
+// foreignKey returns the foreign key relation for column key in mr.
+func foreignKey(mr *dom.ModelRels, key string) (dom.Relation, bool) {
+	if mr != nil {
+		for _, r := range mr.Out {
+			if isForeignKey(r) && strings.TrimSuffix(r.A.Key, "?") == key {
+				return r, true
+			}
+		}
+	}
+	return dom.Relation{}, false
+}
+
+// refKey returns the referenced column of relation r.
+func refKey(r dom.Relation) (string, error) {
+	if r.B.Key != "_" {
+		return r.B.Key, nil
+	}
+	pks := r.B.Model.PK()
+	if len(pks) == 0 {
+		return "", fmt.Errorf("reference %s to model %s without primary key",
+			r.A.Key, r.B.Qualified())
+	}
+	return ColKey(pks[0]), nil
+}
+
+func onDel(m *dom.Model, key string) string {
+	switch m.OnDel(key) {
+	case dom.RefActCascade:
+		return " ON DELETE CASCADE"
+	case dom.RefActSetNull:
+		return " ON DELETE SET NULL"
+	case dom.RefActRestrict:
+		return " ON DELETE RESTRICT"
+	}
+	return ""
+}
+
+func enumVals(m *dom.Model) string {
+	vals := make([]string, 0, len(m.Elems))
+	for _, el := range m.Elems {
+		vals = append(vals, fmt.Sprintf("'%s'", strings.ToLower(el.Name)))
+	}
+	return strings.Join(vals, ", ")
+}
+
+// Column is a table column with the column type for an elem.
+type Column struct {
+	Key  string
+	Type string
+	Elem *dom.Elem
+}
+
+// Columns returns the table columns in the dialect of the gen target for the obj model m with
+// flattened embedded models. Computed and private elems are not stored and have no column.
+func Columns(g *gen.Gen, m *dom.Model) ([]Column, error) {
+	d := DialectFor(g.Target)
+	res := make([]Column, 0, len(m.Elems))
+	for _, el := range m.Elems {
+		if el.CalcSrc() != "" || gen.Priv(el) {
+			continue
+		}
+		if el.Name == "" {
+			em := g.Project.Model(strings.ToLower(el.Type.Ref))
+			if em == nil {
+				return nil, fmt.Errorf("embedded model %s not found", el.Type.Ref)
+			}
+			cs, err := Columns(g, em)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, cs...)
+			continue
+		}
+		ts, err := d.Type(el.Type)
+		if err != nil {
+			return nil, fmt.Errorf("elem %s: %w", el.Name, err)
+		}
+		res = append(res, Column{ColKey(el), ts, el})
+	}
+	return res, nil
+}
+
+// TypeString returns the postgres type for t or an error. Lists of primitive types use arrays,
+// other containers and inline obj types are stored as jsonb.
+func TypeString(t typ.Type) (string, error) {
+	switch t.Kind {
+	case knd.Any, knd.Data:
+		return "jsonb", nil
+	}
+	switch t.Kind & knd.Data {
+	case knd.Bool:
+		return "bool", nil
+	case knd.Int:
+		return "int8", nil
+	case knd.Real:
+		return "float8", nil
+	case knd.Num:
+		return "numeric", nil
+	case knd.Str, knd.Char:
+		return "text", nil
+	case knd.Raw:
+		return "bytea", nil
+	case knd.UUID:
+		return "uuid", nil
+	case knd.Time:
+		return "timestamptz", nil
+	case knd.Span:
+		return "interval", nil
+	case knd.Bits:
+		return "int4", nil
+	case knd.Enum:
+		if t.Ref == "" {
+			return "", fmt.Errorf("no type name for %s", t)
+		}
+		return quoteQualified(strings.ToLower(t.Ref)), nil
+	case knd.List:
+		el := typ.Deopt(typ.ContEl(t))
+		switch el.Kind & knd.Data {
+		case knd.Bool, knd.Int, knd.Real, knd.Num, knd.Str, knd.Char,
+			knd.Raw, knd.UUID, knd.Time, knd.Span, knd.Enum:
+			ts, err := TypeString(el)
+			if err != nil {
+				return "", err
+			}
+			return ts + "[]", nil
+		}
+		return "jsonb", nil
+	case knd.Dict, knd.Obj:
+		return "jsonb", nil
+	}
+	return "", fmt.Errorf("type %s cannot be represented in postgres", t)
+}
+
+// TableName returns the qualified lowercase postgres name of the table or type for model m. The
+// name parts are not quoted, use the dialect table name in statements.
+func TableName(m *dom.Model) string { return strings.ToLower(m.Qualified()) }
+
+// ColKey returns the column name for elem el.
+func ColKey(el *dom.Elem) string { return strings.TrimSuffix(el.Key(), "?") }
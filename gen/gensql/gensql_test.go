@@ -0,0 +1,212 @@
+package gensql
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/bfr"
+)
+
+const shopRaw = `(schema shop
+	(Kind:enum Food; Tool;)
+	(Flag:bits Hot; New;)
+	(Pos; X:int Y:int)
+	(Cat; ID:int Name:str uniq:['name'])
+	(Prod; (ID:int auto;) (Name:str idx;) Kind:<enum@shop.Kind> Flags?:<bits@shop.Flag>
+		(Cat:@Cat.ID ondel:cascade) (Alt?:@Cat.ID ondel:setnull)
+		Tags:list|str Attrs?:dict|str Created:time @Pos
+		Full:(cat .name ' ' .kind) (Cache?:str priv;)
+		idx:['kind' 'created'])
+	(Log; nogen; ID:int)
+	(Rate; (Prod:@Prod.ID pk;) Val:real (Note?:int ref:'Cat'))
+)`
+
+func TestWriteSchema(t *testing.T) {
+	s, err := dom.ReadSchema(nil, strings.NewReader(shopRaw), "shop")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	var b strings.Builder
+	g := NewGen(nil, Postgres)
+	g.P = bfr.P{Writer: &b}
+	err = WriteSchema(g, s)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	want := `CREATE SCHEMA shop;
+
+CREATE TYPE shop.kind AS ENUM ('food', 'tool');
+
+CREATE TABLE shop.cat (
+	id int8 PRIMARY KEY,
+	name text NOT NULL
+);
+CREATE UNIQUE INDEX cat_name_idx ON shop.cat (name);
+
+CREATE TABLE shop.prod (
+	id serial8 PRIMARY KEY,
+	name text NOT NULL,
+	kind shop.kind NOT NULL,
+	flags int4,
+	cat int8 NOT NULL,
+	alt int8,
+	tags text[] NOT NULL,
+	attrs jsonb,
+	created timestamptz NOT NULL,
+	x int8 NOT NULL,
+	y int8 NOT NULL
+);
+CREATE INDEX prod_name_idx ON shop.prod (name);
+CREATE INDEX prod_kind_created_idx ON shop.prod (kind, created);
+
+CREATE TABLE shop.rate (
+	prod int8 PRIMARY KEY,
+	val float8 NOT NULL,
+	note int8
+);
+
+ALTER TABLE shop.prod ADD FOREIGN KEY (cat) REFERENCES shop.cat (id) ON DELETE CASCADE;
+ALTER TABLE shop.prod ADD FOREIGN KEY (alt) REFERENCES shop.cat (id) ON DELETE SET NULL;
+
+ALTER TABLE shop.rate ADD FOREIGN KEY (prod) REFERENCES shop.prod (id);
+`
+	if got := b.String(); got != want {
+		t.Errorf("want %s\ngot %s", want, got)
+	}
+}
+
+func TestWriteSchemaCompositeKey(t *testing.T) {
+	raw := `(schema tag (Tag; ID:int) (Post; ID:int) (Tagged; (Tag:@Tag.ID pk;) (Post:@Post.ID pk;)))`
+	s, err := dom.ReadSchema(nil, strings.NewReader(raw), "tag")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	var b strings.Builder
+	g := NewGen(nil, Postgres)
+	g.P = bfr.P{Writer: &b}
+	err = WriteSchema(g, s)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	want := `CREATE SCHEMA tag;
+
+CREATE TABLE tag.tag (
+	id int8 PRIMARY KEY
+);
+
+CREATE TABLE tag.post (
+	id int8 PRIMARY KEY
+);
+
+CREATE TABLE tag.tagged (
+	tag int8 NOT NULL,
+	post int8 NOT NULL,
+	PRIMARY KEY (tag, post)
+);
+
+ALTER TABLE tag.tagged ADD FOREIGN KEY (tag) REFERENCES tag.tag (id);
+ALTER TABLE tag.tagged ADD FOREIGN KEY (post) REFERENCES tag.post (id);
+`
+	if got := b.String(); got != want {
+		t.Errorf("want %s\ngot %s", want, got)
+	}
+}
+
+func TestWriteSchemaSQLite(t *testing.T) {
+	s, err := dom.ReadSchema(nil, strings.NewReader(shopRaw), "shop")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	var b strings.Builder
+	g := NewGen(nil, SQLite)
+	g.P = bfr.P{Writer: &b}
+	err = WriteSchema(g, s)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	want := `CREATE TABLE shop_cat (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL
+);
+CREATE UNIQUE INDEX shop_cat_name_idx ON shop_cat (name);
+
+CREATE TABLE shop_prod (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK (kind IN ('food', 'tool')),
+	flags INTEGER,
+	cat INTEGER NOT NULL REFERENCES shop_cat (id) ON DELETE CASCADE,
+	alt INTEGER REFERENCES shop_cat (id) ON DELETE SET NULL,
+	tags TEXT NOT NULL,
+	attrs TEXT,
+	created TEXT NOT NULL,
+	x INTEGER NOT NULL,
+	y INTEGER NOT NULL
+);
+CREATE INDEX shop_prod_name_idx ON shop_prod (name);
+CREATE INDEX shop_prod_kind_created_idx ON shop_prod (kind, created);
+
+CREATE TABLE shop_rate (
+	prod INTEGER PRIMARY KEY REFERENCES shop_prod (id),
+	val REAL NOT NULL,
+	note INTEGER
+);
+`
+	if got := b.String(); got != want {
+		t.Errorf("want %s\ngot %s", want, got)
+	}
+}
+
+func TestWriteSchemaReserved(t *testing.T) {
+	raw := `(schema app (User; ID:int (Order:int idx;) Group?:str) (Item; ID:int @User.ID))`
+	s, err := dom.ReadSchema(nil, strings.NewReader(raw), "app")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	tests := []struct {
+		d    Dialect
+		want string
+	}{
+		{Postgres, `CREATE SCHEMA app;
+
+CREATE TABLE app."user" (
+	id int8 PRIMARY KEY,
+	"order" int8 NOT NULL,
+	"group" text
+);
+CREATE INDEX user_order_idx ON app."user" ("order");
+
+CREATE TABLE app.item (
+	id int8 PRIMARY KEY,
+	"user" int8 NOT NULL
+);
+
+ALTER TABLE app.item ADD FOREIGN KEY ("user") REFERENCES app."user" (id);
+`},
+		{SQLite, `CREATE TABLE app_user (
+	id INTEGER PRIMARY KEY,
+	"order" INTEGER NOT NULL,
+	"group" TEXT
+);
+CREATE INDEX app_user_order_idx ON app_user ("order");
+
+CREATE TABLE app_item (
+	id INTEGER PRIMARY KEY,
+	"user" INTEGER NOT NULL REFERENCES app_user (id)
+);
+`},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		g := NewGen(nil, test.d)
+		g.P = bfr.P{Writer: &b}
+		err = WriteSchema(g, s)
+		if err != nil {
+			t.Fatalf("write %s error: %v", test.d.Name(), err)
+		}
+		if got := b.String(); got != test.want {
+			t.Errorf("%s want %s\ngot %s", test.d.Name(), test.want, got)
+		}
+	}
+}
@@ -0,0 +1,679 @@
+package gensql
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// ImportDDL reads the create table, type and index statements of r and returns an equivalent
+// dom schema declaration in xelf format. The schema name of a create schema statement or of the
+// first qualified table is used, name is the fallback.
+//
+// Tables are ordered so that referenced tables come first. Statements, column and table
+// constraints that cannot be mapped are kept as comments.
+func ImportDDL(r io.Reader, name string) (string, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	stmts, err := splitDDL(string(src))
+	if err != nil {
+		return "", err
+	}
+	im := &importer{}
+	for _, st := range stmts {
+		if !im.stmt(st) {
+			im.notes = append(im.notes, st.raw)
+		}
+	}
+	if im.schema == "" {
+		im.schema = strings.ToLower(name)
+	}
+	if im.schema == "" {
+		return "", fmt.Errorf("import ddl without schema name")
+	}
+	var b strings.Builder
+	im.write(&b)
+	return b.String(), nil
+}
+
+type importer struct {
+	schema string
+	enums  []*ddlEnum
+	tables []*ddlTable
+	notes  []string
+}
+
+type ddlEnum struct {
+	name string
+	vals []string
+}
+
+type ddlTable struct {
+	schema, name string
+	cols         []*ddlCol
+	uniq, idx    [][]string
+	notes        []string
+}
+
+type ddlCol struct {
+	name  string
+	typ   sqlType
+	opt   bool
+	pk    bool
+	auto  bool
+	uniq  bool
+	idx   bool
+	def   string
+	oneof []string
+	ref   *ddlRef
+	notes []string
+}
+
+type ddlRef struct {
+	schema, table, col string
+	onDel              string
+}
+
+type sqlType struct {
+	schema string
+	name   string
+	args   []string
+	array  bool
+}
+
+func (im *importer) table(schema, name string) *ddlTable {
+	if schema != "" && schema != im.schema {
+		return nil
+	}
+	for _, t := range im.tables {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (t *ddlTable) col(name string) *ddlCol {
+	for _, c := range t.cols {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// stmt imports the statement st and returns whether it could be mapped.
+func (im *importer) stmt(st stmt) bool {
+	p := &parser{toks: st.toks}
+	switch {
+	case p.kw("CREATE", "SCHEMA"):
+		p.kw("IF", "NOT", "EXISTS")
+		name, ok := p.ident()
+		if !ok || !p.end() {
+			return false
+		}
+		if im.schema == "" {
+			im.schema = name
+		}
+		return true
+	case p.kw("CREATE", "TYPE"):
+		return im.createType(p)
+	case p.kw("CREATE", "TABLE"):
+		return im.createTable(p)
+	case p.kw("CREATE", "UNIQUE", "INDEX"):
+		return im.createIndex(p, true)
+	case p.kw("CREATE", "INDEX"):
+		return im.createIndex(p, false)
+	case p.kw("ALTER", "TABLE"):
+		p.kw("ONLY")
+		sch, name, ok := p.qualified()
+		t := im.table(sch, name)
+		if !ok || t == nil || !p.kw("ADD") {
+			return false
+		}
+		if !t.constraint(p) {
+			return false
+		}
+		if !p.end() {
+			t.notes = append(t.notes, tokText(p.toks[p.pos:]))
+		}
+		return true
+	}
+	return false
+}
+
+func (im *importer) createType(p *parser) bool {
+	sch, name, ok := p.qualified()
+	if !ok || !p.kw("AS", "ENUM") || !p.punct('(') {
+		return false
+	}
+	e := &ddlEnum{name: name}
+	for !p.punct(')') {
+		v := p.next()
+		if v.typ != 's' || !isSym(v.val) {
+			return false
+		}
+		e.vals = append(e.vals, strings.ToLower(v.val))
+		p.punct(',')
+	}
+	if !p.end() {
+		return false
+	}
+	if im.schema == "" {
+		im.schema = sch
+	}
+	im.enums = append(im.enums, e)
+	return true
+}
+
+func (im *importer) createTable(p *parser) bool {
+	p.kw("IF", "NOT", "EXISTS")
+	sch, name, ok := p.qualified()
+	if !ok || !p.punct('(') {
+		return false
+	}
+	if im.schema == "" {
+		im.schema = sch
+	} else if sch != "" && sch != im.schema {
+		return false
+	}
+	t := &ddlTable{schema: sch, name: name}
+	for {
+		start := p.pos
+		if isConstraint(p.peek()) {
+			if !t.constraint(p) {
+				p.pos = start
+				t.notes = append(t.notes, tokText(p.part()))
+			}
+		} else if !t.column(p) {
+			return false
+		}
+		if p.punct(')') {
+			break
+		}
+		if !p.punct(',') {
+			return false
+		}
+	}
+	if !p.end() {
+		t.notes = append(t.notes, tokText(p.toks[p.pos:]))
+	}
+	im.tables = append(im.tables, t)
+	return true
+}
+
+func (im *importer) createIndex(p *parser, uniq bool) bool {
+	p.kw("CONCURRENTLY")
+	p.kw("IF", "NOT", "EXISTS")
+	if !p.peekKw("ON") {
+		if _, ok := p.ident(); !ok {
+			return false
+		}
+	}
+	if !p.kw("ON") {
+		return false
+	}
+	p.kw("ONLY")
+	sch, name, ok := p.qualified()
+	t := im.table(sch, name)
+	if !ok || t == nil {
+		return false
+	}
+	if p.kw("USING") && !p.kw("BTREE") {
+		return false
+	}
+	keys, ok := p.idents()
+	if !ok || !p.end() || !t.hasCols(keys) {
+		return false
+	}
+	t.addIndex(keys, uniq)
+	return true
+}
+
+// column parses a column definition and returns false if the column name or type is missing.
+func (t *ddlTable) column(p *parser) bool {
+	name, ok := p.ident()
+	if !ok {
+		return false
+	}
+	c := &ddlCol{name: strings.ToLower(name), opt: true}
+	c.typ, ok = p.sqlType()
+	if !ok {
+		return false
+	}
+	t.cols = append(t.cols, c)
+	for !p.atEnd() {
+		start := p.pos
+		p.constraintName()
+		switch {
+		case p.kw("NOT", "NULL"):
+			c.opt = false
+		case p.kw("NULL"):
+		case p.kw("PRIMARY", "KEY"):
+			c.pk, c.opt = true, false
+			if p.kw("AUTOINCREMENT") {
+				c.auto = true
+			}
+		case p.kw("UNIQUE"):
+			c.uniq = true
+		case p.kw("DEFAULT"):
+			expr := p.expr()
+			if !c.defVal(expr) {
+				c.notes = append(c.notes, "DEFAULT "+tokText(expr))
+			}
+		case p.kw("REFERENCES"):
+			ref, rest, ok := p.references()
+			if !ok {
+				p.pos = start
+				c.notes = append(c.notes, tokText(p.part()))
+				return true
+			}
+			c.ref = ref
+			if rest != "" {
+				c.notes = append(c.notes, rest)
+			}
+		case p.kw("CHECK"):
+			expr, ok := p.group()
+			if vals, in := checkIn(expr); !ok || !in || vals.col != c.name {
+				c.notes = append(c.notes, "CHECK ("+tokText(expr)+")")
+			} else {
+				c.oneof = vals.vals
+			}
+		default:
+			p.pos = start
+			c.notes = append(c.notes, tokText(p.part()))
+			return true
+		}
+	}
+	return true
+}
+
+// constraint parses a table constraint and returns false if it cannot be mapped.
+func (t *ddlTable) constraint(p *parser) bool {
+	p.constraintName()
+	switch {
+	case p.kw("PRIMARY", "KEY"):
+		keys, ok := p.idents()
+		if !ok || len(keys) != 1 || !t.hasCols(keys) {
+			return false
+		}
+		c := t.col(keys[0])
+		c.pk, c.opt = true, false
+		return true
+	case p.kw("UNIQUE"):
+		keys, ok := p.idents()
+		if !ok || !t.hasCols(keys) {
+			return false
+		}
+		t.addIndex(keys, true)
+		return true
+	case p.kw("FOREIGN", "KEY"):
+		keys, ok := p.idents()
+		if !ok || len(keys) != 1 || !t.hasCols(keys) || !p.kw("REFERENCES") {
+			return false
+		}
+		ref, rest, ok := p.references()
+		if !ok || rest != "" {
+			return false
+		}
+		t.col(keys[0]).ref = ref
+		return true
+	case p.kw("CHECK"):
+		expr, ok := p.group()
+		vals, in := checkIn(expr)
+		if !ok || !in || t.col(vals.col) == nil {
+			return false
+		}
+		t.col(vals.col).oneof = vals.vals
+		return true
+	}
+	return false
+}
+
+func (t *ddlTable) hasCols(keys []string) bool {
+	for _, k := range keys {
+		if t.col(k) == nil {
+			return false
+		}
+	}
+	return len(keys) > 0
+}
+
+func (t *ddlTable) addIndex(keys []string, uniq bool) {
+	if len(keys) == 1 {
+		c := t.col(keys[0])
+		if uniq {
+			c.uniq = true
+		} else {
+			c.idx = true
+		}
+	} else if uniq {
+		t.uniq = append(t.uniq, keys)
+	} else {
+		t.idx = append(t.idx, keys)
+	}
+}
+
+func (c *ddlCol) defVal(expr []token) bool {
+	// drop postgres type casts like 'draft'::text
+	for i := 0; i+1 < len(expr); i++ {
+		if expr[i].val == ":" && expr[i+1].val == ":" {
+			expr = expr[:i]
+			break
+		}
+	}
+	if len(expr) == 0 {
+		return false
+	}
+	switch fst := expr[0]; {
+	case len(expr) == 1 && fst.typ == 's':
+		c.def = quote(fst.val)
+	case len(expr) == 1 && fst.typ == 'n':
+		c.def = fst.val
+	case len(expr) == 2 && fst.val == "-" && expr[1].typ == 'n':
+		c.def = "-" + expr[1].val
+	case len(expr) == 1 && isKw(fst, "TRUE", "FALSE"):
+		c.def = strings.ToLower(fst.val)
+	case len(expr) == 1 && isKw(fst, "NULL"):
+	case len(expr) == 1 && isKw(fst, "CURRENT_TIMESTAMP"),
+		len(expr) == 3 && isKw(fst, "NOW") && expr[1].val == "(" && expr[2].val == ")":
+		c.def = "now"
+	case isKw(fst, "NEXTVAL"):
+		c.auto = true
+	default:
+		return false
+	}
+	return true
+}
+
+type inCheck struct {
+	col  string
+	vals []string
+}
+
+// checkIn returns the column and values of check expressions like 'kind IN ('a', 'b')'.
+func checkIn(expr []token) (res inCheck, _ bool) {
+	if len(expr) < 5 || expr[0].typ != 'w' && expr[0].typ != 'q' || !isKw(expr[1], "IN") ||
+		expr[2].val != "(" || expr[len(expr)-1].val != ")" {
+		return res, false
+	}
+	res.col = strings.ToLower(expr[0].val)
+	for i, tok := range expr[3 : len(expr)-1] {
+		if i%2 == 1 {
+			if tok.typ != ',' {
+				return res, false
+			}
+		} else if tok.typ != 's' {
+			return res, false
+		} else {
+			res.vals = append(res.vals, tok.val)
+		}
+	}
+	return res, len(res.vals) > 0
+}
+
+func (im *importer) write(b *strings.Builder) {
+	fmt.Fprintf(b, "(schema %s\n", im.schema)
+	for _, e := range im.enums {
+		fmt.Fprintf(b, "\t(%s:enum\n", cased(e.name))
+		for _, v := range e.vals {
+			fmt.Fprintf(b, "\t\t%s;\n", cased(v))
+		}
+		b.WriteString("\t)\n")
+	}
+	for _, t := range im.sortTables() {
+		fmt.Fprintf(b, "\t(%s;\n", cased(t.name))
+		for i, c := range elemOrder(t.cols) {
+			for _, n := range c.notes {
+				fmt.Fprintf(b, "\t\t// %s: %s\n", c.name, n)
+			}
+			im.writeCol(b, c, i)
+		}
+		for _, keys := range t.idx {
+			fmt.Fprintf(b, "\t\tidx:[%s]\n", quoteList(keys))
+		}
+		for _, keys := range t.uniq {
+			fmt.Fprintf(b, "\t\tuniq:[%s]\n", quoteList(keys))
+		}
+		for _, n := range t.notes {
+			writeComment(b, "\t\t", n)
+		}
+		b.WriteString("\t)\n")
+	}
+	for _, n := range im.notes {
+		writeComment(b, "\t", n)
+	}
+	b.WriteString(")\n")
+}
+
+// elemOrder returns the columns in elem order. A first id column that is not the primary key is
+// moved to the second position, because daql makes a first ID elem the primary key.
+func elemOrder(cols []*ddlCol) []*ddlCol {
+	if len(cols) < 2 || cols[0].pk || cased(cols[0].name) != "ID" {
+		return cols
+	}
+	id := *cols[0]
+	id.notes = append(id.notes[:len(id.notes):len(id.notes)],
+		"moved from the first position, because it is not the primary key")
+	res := make([]*ddlCol, 0, len(cols))
+	return append(append(append(res, cols[1]), &id), cols[2:]...)
+}
+
+func (im *importer) writeCol(b *strings.Builder, c *ddlCol, i int) {
+	name := cased(c.name)
+	var tags []string
+	// the first ID elem is the primary key by default
+	if c.pk && (name != "ID" || i > 0) {
+		tags = append(tags, "pk;")
+	}
+	ts, auto, note := im.colType(c)
+	if note != "" {
+		fmt.Fprintf(b, "\t\t// %s: type %s\n", c.name, note)
+	}
+	if c.auto || auto {
+		tags = append(tags, "auto;")
+	}
+	if c.uniq {
+		tags = append(tags, "uniq;")
+	} else if c.idx {
+		tags = append(tags, "idx;")
+	}
+	if c.def != "" {
+		tags = append(tags, "default:"+c.def)
+	}
+	if c.typ.name == "varchar" || c.typ.name == "character varying" {
+		if len(c.typ.args) == 1 && !c.typ.array {
+			tags = append(tags, "len:"+c.typ.args[0])
+		}
+	}
+	if len(c.oneof) > 0 {
+		tags = append(tags, fmt.Sprintf("oneof:[%s]", quoteList(c.oneof)))
+	}
+	if c.ref != nil {
+		switch c.ref.onDel {
+		case "cascade":
+			tags = append(tags, "ondel:cascade")
+		case "set null":
+			tags = append(tags, "ondel:setnull")
+		case "restrict":
+			tags = append(tags, "ondel:restrict")
+		}
+	}
+	if c.opt && !c.pk {
+		name += "?"
+	}
+	if len(tags) == 0 {
+		fmt.Fprintf(b, "\t\t%s:%s\n", name, ts)
+		return
+	}
+	fmt.Fprintf(b, "\t\t(%s:%s %s)\n", name, ts, strings.Join(tags, " "))
+}
+
+// colType returns the daql type for column c, whether it is a serial type and the original type
+// if it cannot be mapped.
+func (im *importer) colType(c *ddlCol) (string, bool, string) {
+	if r := c.ref; r != nil {
+		var pk string
+		if t := im.table(r.schema, r.table); t != nil {
+			pk = t.pkCol()
+		}
+		col := r.col
+		if col == "" {
+			col = pk
+		}
+		if col == "" {
+			col = "id"
+		}
+		if r.schema != "" && r.schema != im.schema {
+			return fmt.Sprintf("@%s.%s.%s", r.schema, cased(r.table), cased(col)), false, ""
+		}
+		return fmt.Sprintf("@%s.%s", cased(r.table), cased(col)), false, ""
+	}
+	t := c.typ
+	ts, auto := "", false
+	switch t.name {
+	case "smallint", "int2", "integer", "int", "int4", "bigint", "int8":
+		ts = "int"
+	case "smallserial", "serial2", "serial", "serial4", "bigserial", "serial8":
+		ts, auto = "int", true
+	case "real", "float4", "float", "float8", "double", "double precision":
+		ts = "real"
+	case "numeric", "decimal":
+		ts = "num"
+	case "text", "varchar", "character varying", "char", "character", "bpchar", "citext", "clob":
+		ts = "str"
+	case "boolean", "bool":
+		ts = "bool"
+	case "bytea", "blob":
+		ts = "raw"
+	case "uuid":
+		ts = "uuid"
+	case "date", "datetime", "timestamp", "timestamptz",
+		"timestamp with time zone", "timestamp without time zone":
+		ts = "time"
+	case "interval":
+		ts = "span"
+	case "json", "jsonb":
+		ts = "any"
+	default:
+		for _, e := range im.enums {
+			if e.name == t.name && (t.schema == "" || t.schema == im.schema) {
+				ts = fmt.Sprintf("<enum@%s.%s>", im.schema, cased(e.name))
+			}
+		}
+	}
+	if ts == "" {
+		return "any", false, t.String()
+	}
+	if t.array {
+		ts = "list|" + ts
+	}
+	return ts, auto, ""
+}
+
+func (t *ddlTable) pkCol() string {
+	for _, c := range t.cols {
+		if c.pk {
+			return c.name
+		}
+	}
+	return ""
+}
+
+// sortTables returns the tables ordered so that referenced tables come first, if possible.
+func (im *importer) sortTables() []*ddlTable {
+	res := make([]*ddlTable, 0, len(im.tables))
+	done := make(map[*ddlTable]bool, len(im.tables))
+	for len(res) < len(im.tables) {
+		var next *ddlTable
+		for _, t := range im.tables {
+			if !done[t] && im.depsDone(t, done) {
+				next = t
+				break
+			}
+		}
+		if next == nil {
+			// reference cycle, use the next table in declaration order
+			for _, t := range im.tables {
+				if !done[t] {
+					next = t
+					break
+				}
+			}
+		}
+		done[next] = true
+		res = append(res, next)
+	}
+	return res
+}
+
+func (im *importer) depsDone(t *ddlTable, done map[*ddlTable]bool) bool {
+	for _, c := range t.cols {
+		if c.ref == nil {
+			continue
+		}
+		if rt := im.table(c.ref.schema, c.ref.table); rt != nil && rt != t && !done[rt] {
+			return false
+		}
+	}
+	return true
+}
+
+func (t sqlType) String() string {
+	var b strings.Builder
+	if t.schema != "" {
+		b.WriteString(t.schema)
+		b.WriteByte('.')
+	}
+	b.WriteString(t.name)
+	if len(t.args) > 0 {
+		fmt.Fprintf(&b, "(%s)", strings.Join(t.args, ", "))
+	}
+	if t.array {
+		b.WriteString("[]")
+	}
+	return b.String()
+}
+
+// cased returns the dom name for a sql name. Underscores are kept so the key matches the name.
+func cased(s string) string {
+	parts := strings.Split(strings.ToLower(s), "_")
+	for i, p := range parts {
+		if p == "id" {
+			parts[i] = "ID"
+		} else if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func quoteList(l []string) string {
+	res := make([]string, 0, len(l))
+	for _, s := range l {
+		res = append(res, quote(s))
+	}
+	return strings.Join(res, " ")
+}
+
+func writeComment(b *strings.Builder, ind, raw string) {
+	for _, line := range strings.Split(raw, "\n") {
+		fmt.Fprintf(b, "%s// %s\n", ind, strings.TrimRight(line, " \t\r"))
+	}
+}
+
+func isSym(s string) bool {
+	for i, r := range s {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,175 @@
+package gensql
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/bfr"
+)
+
+func TestImportDDLRoundtrip(t *testing.T) {
+	s, err := dom.ReadSchema(nil, strings.NewReader(shopRaw), "shop")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	var b strings.Builder
+	g := NewGen(nil, Postgres)
+	g.P = bfr.P{Writer: &b}
+	err = WriteSchema(g, s)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	got, err := ImportDDL(strings.NewReader(b.String()), "")
+	if err != nil {
+		t.Fatalf("import error: %v", err)
+	}
+	want := `(schema shop
+	(Kind:enum
+		Food;
+		Tool;
+	)
+	(Cat;
+		ID:int
+		(Name:str uniq;)
+	)
+	(Prod;
+		(ID:int auto;)
+		(Name:str idx;)
+		Kind:<enum@shop.Kind>
+		Flags?:int
+		(Cat:@Cat.ID ondel:cascade)
+		(Alt?:@Cat.ID ondel:setnull)
+		Tags:list|str
+		Attrs?:any
+		Created:time
+		X:int
+		Y:int
+		idx:['kind' 'created']
+	)
+	(Rate;
+		(Prod:@Prod.ID pk;)
+		Val:real
+		Note?:int
+	)
+)
+`
+	if got != want {
+		t.Fatalf("want %s\ngot %s", want, got)
+	}
+	is, err := dom.ReadSchema(nil, strings.NewReader(got), "shop")
+	if err != nil {
+		t.Fatalf("read imported schema: %v", err)
+	}
+	m := is.Model("rate")
+	if m == nil || len(m.Elems) != 3 || m.Elems[0].Bits&dom.BitPK == 0 {
+		t.Errorf("imported rate model want prod pk got %v", m)
+	}
+	if is.Model("prod").OnDel("cat") != dom.RefActCascade {
+		t.Errorf("imported prod.cat want ondel cascade")
+	}
+}
+
+func TestImportDDL(t *testing.T) {
+	raw := `-- legacy tables
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE TYPE status AS ENUM ('draft', 'live');
+CREATE TABLE item (
+	id bigserial PRIMARY KEY,
+	cat_id int NOT NULL REFERENCES category (id) ON DELETE CASCADE,
+	name varchar(80) NOT NULL UNIQUE,
+	status status NOT NULL DEFAULT 'draft'::status,
+	price numeric(10, 2) CHECK (price > 0),
+	size text CHECK (size IN ('s', 'm', 'l')),
+	created timestamp with time zone DEFAULT now() NOT NULL,
+	geo point,
+	owner uuid REFERENCES auth.acct ON UPDATE CASCADE,
+	CONSTRAINT item_name_check CHECK (length(name) > 2)
+) WITH (fillfactor = 70);
+CREATE TABLE category (
+	id integer NOT NULL,
+	parent integer REFERENCES category,
+	label text,
+	PRIMARY KEY (id),
+	UNIQUE (parent, label)
+);
+CREATE INDEX item_status_created ON item (status, created);
+CREATE INDEX item_lower_name ON item (lower(name));
+CREATE VIEW live AS SELECT * FROM item
+	WHERE status = 'live';
+`
+	got, err := ImportDDL(strings.NewReader(raw), "legacy")
+	if err != nil {
+		t.Fatalf("import error: %v", err)
+	}
+	want := `(schema legacy
+	(Status:enum
+		Draft;
+		Live;
+	)
+	(Category;
+		ID:int
+		Parent?:@Category.ID
+		Label?:str
+		uniq:['parent' 'label']
+	)
+	(Item;
+		(ID:int auto;)
+		(Cat_ID:@Category.ID ondel:cascade)
+		(Name:str uniq; len:80)
+		(Status:<enum@legacy.Status> default:'draft')
+		// price: CHECK (price > 0)
+		Price?:num
+		(Size?:str oneof:['s' 'm' 'l'])
+		(Created:time default:now)
+		// geo: type point
+		Geo?:any
+		// owner: ON UPDATE CASCADE
+		Owner?:@auth.Acct.ID
+		idx:['status' 'created']
+		// CONSTRAINT item_name_check CHECK (length(name) > 2)
+		// WITH (fillfactor = 70)
+	)
+	// CREATE EXTENSION IF NOT EXISTS "uuid-ossp"
+	// CREATE INDEX item_lower_name ON item (lower(name))
+	// CREATE VIEW live AS SELECT * FROM item
+	// 	WHERE status = 'live'
+)
+`
+	if got != want {
+		t.Errorf("want %s\ngot %s", want, got)
+	}
+}
+
+func TestImportDDLNonPKID(t *testing.T) {
+	raw := `CREATE TABLE acct (
+	id int NOT NULL UNIQUE,
+	code text PRIMARY KEY,
+	name text NOT NULL
+);
+`
+	got, err := ImportDDL(strings.NewReader(raw), "legacy")
+	if err != nil {
+		t.Fatalf("import error: %v", err)
+	}
+	want := `(schema legacy
+	(Acct;
+		(Code:str pk;)
+		// id: moved from the first position, because it is not the primary key
+		(ID:int uniq;)
+		Name:str
+	)
+)
+`
+	if got != want {
+		t.Fatalf("want %s\ngot %s", want, got)
+	}
+	s, err := dom.ReadSchema(nil, strings.NewReader(got), "legacy")
+	if err != nil {
+		t.Fatalf("read imported schema: %v", err)
+	}
+	pks := s.Model("acct").PK()
+	if len(pks) != 1 || pks[0].Name != "Code" {
+		t.Errorf("imported acct want code pk got %v", pks)
+	}
+}
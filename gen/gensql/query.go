@@ -0,0 +1,489 @@
+package gensql
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/qry"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+)
+
+// Select returns a select statement and the bound arguments for the query job j in dialect d.
+//
+// The job subject must be a model. Whr expressions are compiled from the logic, comparison,
+// arithmetic and cat specs. Sub expressions without field references are evaluated and bound as
+// arguments. Computed model elems are compiled from their expression. Aggregate fields and group
+// keys are compiled to aggregate functions and a group by clause. The aft cursor is compiled to a
+// keyset condition on the order keys.
+func Select(p *exp.Prog, d Dialect, j *qry.Job) (string, []interface{}, error) {
+	if j.Model == nil {
+		return "", nil, fmt.Errorf("query %s without model subject", j.Ref)
+	}
+	c := &compiler{p: p, d: d, j: j}
+	c.WriteString("SELECT ")
+	nest := j.Kind == qry.KindCount && (j.Lim > 0 || j.Off > 0 || len(j.Grp) > 0)
+	if j.Kind == qry.KindCount {
+		c.WriteString("count(*) FROM ")
+		if nest {
+			c.WriteString("(SELECT 1 FROM ")
+		}
+	} else {
+		err := c.sel()
+		if err != nil {
+			return "", nil, err
+		}
+		c.WriteString(" FROM ")
+	}
+	c.WriteString(d.Table(j.Model))
+	if len(j.Whr) > 0 {
+		c.WriteString(" WHERE ")
+		err := c.logic(" AND ", j.Whr)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if len(j.Aft) > 0 {
+		if len(j.Whr) > 0 {
+			c.WriteString(" AND (")
+		} else {
+			c.WriteString(" WHERE ")
+		}
+		if err := c.aft(); err != nil {
+			return "", nil, err
+		}
+		if len(j.Whr) > 0 {
+			c.WriteByte(')')
+		}
+	}
+	if len(j.Grp) > 0 {
+		c.WriteString(" GROUP BY ")
+		for i, key := range j.Grp {
+			if i > 0 {
+				c.WriteString(", ")
+			}
+			if err := c.col(key); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	if j.Kind != qry.KindCount && len(j.Ord) > 0 {
+		c.WriteString(" ORDER BY ")
+		for i, o := range j.Ord {
+			if i > 0 {
+				c.WriteString(", ")
+			}
+			err := c.ord(o)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	lim := j.Lim
+	if j.Kind == qry.KindOne {
+		lim = 1
+	}
+	c.WriteString(d.Limit(lim, j.Off))
+	if nest {
+		c.WriteString(") AS t")
+	}
+	return c.String(), c.args, nil
+}
+
+type compiler struct {
+	strings.Builder
+	p    *exp.Prog
+	d    Dialect
+	j    *qry.Job
+	args []interface{}
+}
+
+func (c *compiler) sel() error {
+	fs := c.j.Sel.Fields
+	if len(fs) == 0 {
+		c.WriteString("*")
+		return nil
+	}
+	for i, f := range fs {
+		if i > 0 {
+			c.WriteString(", ")
+		}
+		if f.Sub != nil {
+			return fmt.Errorf("sub query field %s not supported", f.Key)
+		}
+		if f.Agg != "" {
+			fmt.Fprintf(c, "%s(", f.Agg)
+			if f.Exp == nil {
+				c.WriteByte('*')
+			} else if err := c.expr(f.Exp); err != nil {
+				return fmt.Errorf("field %s: %w", f.Key, err)
+			}
+			fmt.Fprintf(c, ") AS %s", c.d.Ident(f.Key))
+			continue
+		}
+		if f.Exp == nil {
+			if err := c.col(f.Key); err != nil {
+				return err
+			}
+			if c.calc(f.Key) != nil {
+				fmt.Fprintf(c, " AS %s", c.d.Ident(f.Key))
+			}
+			continue
+		}
+		if s, ok := f.Exp.(*exp.Sym); ok && s.Sym == "."+f.Key && c.calc(f.Key) == nil {
+			c.WriteString(c.d.Ident(f.Key))
+			continue
+		}
+		if err := c.expr(f.Exp); err != nil {
+			return fmt.Errorf("field %s: %w", f.Key, err)
+		}
+		fmt.Fprintf(c, " AS %s", c.d.Ident(f.Key))
+	}
+	return nil
+}
+
+func (c *compiler) ord(o qry.Ord) error {
+	if o.Subj {
+		if err := c.col(o.Key); err != nil {
+			return err
+		}
+	} else {
+		c.WriteString(c.d.Ident(o.Key))
+	}
+	if o.Desc {
+		c.WriteString(" DESC")
+	}
+	return nil
+}
+
+// aft writes the keyset condition that selects the rows following the aft cursor in job order.
+func (c *compiler) aft() error {
+	ords := c.j.Ord
+	for i, o := range ords {
+		if i > 0 {
+			c.WriteString(" OR ")
+		}
+		if len(ords) > 1 {
+			c.WriteByte('(')
+		}
+		for k, p := range ords[:i] {
+			if err := c.ordExpr(p); err != nil {
+				return err
+			}
+			c.WriteString(" = ")
+			if err := c.bind(c.j.Aft[k]); err != nil {
+				return err
+			}
+			c.WriteString(" AND ")
+		}
+		if err := c.ordExpr(o); err != nil {
+			return err
+		}
+		if o.Desc {
+			c.WriteString(" < ")
+		} else {
+			c.WriteString(" > ")
+		}
+		if err := c.bind(c.j.Aft[i]); err != nil {
+			return err
+		}
+		if len(ords) > 1 {
+			c.WriteByte(')')
+		}
+	}
+	return nil
+}
+
+// ordExpr writes the column or selection expression of the order key o.
+func (c *compiler) ordExpr(o qry.Ord) error {
+	if !o.Subj {
+		if f := c.j.Sel.Field(o.Key); f != nil && f.Exp != nil {
+			return c.expr(f.Exp)
+		}
+	}
+	return c.col(o.Key)
+}
+
+// col writes the column for the subject field key or the expression of a computed elem.
+func (c *compiler) col(key string) error {
+	if el := c.calc(key); el != nil {
+		x, err := el.CalcExp()
+		if err != nil {
+			return err
+		}
+		return c.expr(x)
+	}
+	c.WriteString(c.d.Ident(key))
+	return nil
+}
+
+func (c *compiler) calc(key string) *dom.Elem {
+	for _, el := range c.j.Model.Calcs() {
+		if strings.TrimSuffix(el.Key(), "?") == key {
+			return el
+		}
+	}
+	return nil
+}
+
+func (c *compiler) expr(x exp.Exp) error {
+	switch v := x.(type) {
+	case *exp.Lit:
+		return c.bind(v.Val)
+	case *exp.Sym:
+		if key, ok := fieldKey(v.Sym); ok {
+			return c.col(key)
+		}
+	case *exp.Call:
+		name, args := callArgs(v)
+		switch name {
+		case "and":
+			return c.logic(" AND ", args)
+		case "or":
+			return c.logic(" OR ", args)
+		case "not":
+			if len(args) != 1 {
+				break
+			}
+			c.WriteString("NOT ")
+			return c.logic("", args)
+		case "eq", "ne":
+			return c.cmp(name, args)
+		case "lt", "le", "gt", "ge":
+			return c.chain(name, args)
+		case "in", "ni":
+			return c.in(name == "ni", args)
+		case "add", "sub", "mul", "div", "cat":
+			return c.infix(name, args)
+		}
+	}
+	if hasField(x) {
+		return fmt.Errorf("cannot compile %s to sql", x)
+	}
+	// expressions without field references are evaluated and bound
+	v, err := c.p.Eval(c.j, x)
+	if err != nil {
+		return err
+	}
+	return c.bind(v)
+}
+
+func (c *compiler) bind(v lit.Val) error {
+	arg, err := c.d.Arg(v)
+	if err != nil {
+		return err
+	}
+	c.args = append(c.args, arg)
+	c.WriteString(c.d.Param(len(c.args)))
+	return nil
+}
+
+// logic writes args joined by op and each arg in parenthesis if more than one.
+func (c *compiler) logic(op string, args []exp.Exp) error {
+	if len(args) == 0 {
+		return fmt.Errorf("logic without arguments")
+	}
+	paren := len(args) > 1 || op == ""
+	for i, arg := range args {
+		if i > 0 {
+			c.WriteString(op)
+		}
+		if paren {
+			c.WriteByte('(')
+		}
+		if err := c.expr(arg); err != nil {
+			return err
+		}
+		if paren {
+			c.WriteByte(')')
+		}
+	}
+	return nil
+}
+
+var sqlOps = map[string]string{
+	"eq": " = ", "ne": " <> ", "lt": " < ", "le": " <= ", "gt": " > ", "ge": " >= ",
+	"add": " + ", "sub": " - ", "mul": " * ", "div": " / ", "cat": " || ",
+}
+
+// cmp compares the first arg with all other args. Null literals are compared with is null.
+func (c *compiler) cmp(name string, args []exp.Exp) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%s expects at least two arguments", name)
+	}
+	for i, arg := range args[1:] {
+		if i > 0 {
+			c.WriteString(" AND ")
+		}
+		a, b := args[0], arg
+		if isNull(a) {
+			a, b = b, a
+		}
+		if err := c.expr(a); err != nil {
+			return err
+		}
+		if isNull(b) {
+			if name == "eq" {
+				c.WriteString(" IS NULL")
+			} else {
+				c.WriteString(" IS NOT NULL")
+			}
+			continue
+		}
+		c.WriteString(sqlOps[name])
+		if err := c.expr(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chain compares each arg with the following arg.
+func (c *compiler) chain(name string, args []exp.Exp) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%s expects at least two arguments", name)
+	}
+	for i := 1; i < len(args); i++ {
+		if i > 1 {
+			c.WriteString(" AND ")
+		}
+		if err := c.expr(args[i-1]); err != nil {
+			return err
+		}
+		c.WriteString(sqlOps[name])
+		if err := c.expr(args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) in(not bool, args []exp.Exp) error {
+	if len(args) != 2 || hasField(args[1]) {
+		return fmt.Errorf("in expects a value and a list without field references")
+	}
+	v, err := c.p.Eval(c.j, args[1])
+	if err != nil {
+		return err
+	}
+	idx, ok := v.Value().(lit.Idxr)
+	if !ok {
+		return fmt.Errorf("in expects a list got %s", v)
+	}
+	if idx.Len() == 0 {
+		if not {
+			c.WriteString("TRUE")
+		} else {
+			c.WriteString("FALSE")
+		}
+		return nil
+	}
+	if err = c.expr(args[0]); err != nil {
+		return err
+	}
+	if not {
+		c.WriteString(" NOT")
+	}
+	c.WriteString(" IN (")
+	err = idx.IterIdx(func(i int, el lit.Val) error {
+		if i > 0 {
+			c.WriteString(", ")
+		}
+		return c.bind(el)
+	})
+	if err != nil {
+		return err
+	}
+	c.WriteByte(')')
+	return nil
+}
+
+// infix writes args in parenthesis joined by the operator. Additions of text use concatenation.
+func (c *compiler) infix(name string, args []exp.Exp) error {
+	if len(args) < 2 {
+		return fmt.Errorf("%s expects at least two arguments", name)
+	}
+	if name == "add" && c.isChar(args[0]) {
+		name = "cat"
+	}
+	c.WriteByte('(')
+	for i, arg := range args {
+		if i > 0 {
+			c.WriteString(sqlOps[name])
+		}
+		if err := c.expr(arg); err != nil {
+			return err
+		}
+	}
+	c.WriteByte(')')
+	return nil
+}
+
+func (c *compiler) isChar(x exp.Exp) bool {
+	switch v := x.(type) {
+	case *exp.Lit:
+		return v.Val.Type().Kind&knd.Char != 0
+	case *exp.Sym:
+		if key, ok := fieldKey(v.Sym); ok {
+			f, err := c.j.Task.Field(key)
+			return err == nil && f.Type.Kind&knd.Char != 0
+		}
+	}
+	return false
+}
+
+// callArgs returns the spec name and flattened arguments of resolved or unresolved calls.
+func callArgs(c *exp.Call) (name string, args []exp.Exp) {
+	if c.Spec != nil {
+		name, args = c.Sig.Ref, c.Args
+	} else if len(c.Args) > 0 {
+		if s, ok := c.Args[0].(*exp.Sym); ok {
+			name, args = s.Sym, c.Args[1:]
+		}
+	}
+	res := make([]exp.Exp, 0, len(args))
+	for _, arg := range args {
+		if t, ok := arg.(*exp.Tupl); ok {
+			res = append(res, t.Els...)
+		} else if arg != nil {
+			res = append(res, arg)
+		}
+	}
+	return name, res
+}
+
+// fieldKey returns the key of a simple field reference symbol like '.name'.
+func fieldKey(sym string) (string, bool) {
+	if len(sym) < 2 || sym[0] != '.' || sym[1] == '.' || strings.ContainsAny(sym[1:], "./") {
+		return "", false
+	}
+	return strings.ToLower(sym[1:]), true
+}
+
+// hasField returns whether x contains field references of the job subject.
+func hasField(x exp.Exp) bool {
+	switch v := x.(type) {
+	case *exp.Sym:
+		return len(v.Sym) > 1 && v.Sym[0] == '.' && v.Sym[1] != '.'
+	case *exp.Call:
+		for _, arg := range v.Args {
+			if hasField(arg) {
+				return true
+			}
+		}
+	case *exp.Tupl:
+		for _, el := range v.Els {
+			if hasField(el) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isNull(x exp.Exp) bool {
+	l, ok := x.(*exp.Lit)
+	return ok && (l.Val == nil || l.Val.Nil())
+}
@@ -0,0 +1,110 @@
+package gensql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/qry"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+// selBackend compiles query jobs and records the statement instead of executing it.
+type selBackend struct {
+	pro  *dom.Project
+	d    Dialect
+	sql  string
+	args []interface{}
+}
+
+func (b *selBackend) Proj() *dom.Project { return b.pro }
+func (b *selBackend) Exec(p *exp.Prog, j *qry.Job) (*exp.Lit, error) {
+	var err error
+	b.sql, b.args, err = Select(p, b.d, j)
+	if err != nil {
+		return nil, err
+	}
+	return exp.LitVal(p.Reg.Zero(j.Res)), nil
+}
+
+func TestSelect(t *testing.T) {
+	reg := lit.NewRegs()
+	s, err := dom.ReadSchema(reg, strings.NewReader(shopRaw), "shop")
+	if err != nil {
+		t.Fatalf("schema error %v", err)
+	}
+	pro := &dom.Project{Schemas: []*dom.Schema{s}}
+	tests := []struct {
+		raw  string
+		pg   string
+		lite string
+		args string
+	}{
+		{`(*shop.cat)`,
+			`SELECT id, name FROM shop.cat`, `SELECT id, name FROM shop_cat`, `[]`},
+		{`(#shop.cat (eq .name 'a'))`,
+			`SELECT count(*) FROM shop.cat WHERE name = $1`,
+			`SELECT count(*) FROM shop_cat WHERE name = ?`, `[a]`},
+		{`(#shop.cat lim:5 off:10)`,
+			`SELECT count(*) FROM (SELECT 1 FROM shop.cat LIMIT 5 OFFSET 10) AS t`,
+			`SELECT count(*) FROM (SELECT 1 FROM shop_cat LIMIT 5 OFFSET 10) AS t`, `[]`},
+		{`(?shop.cat (gt .id 3) _ name;)`,
+			`SELECT name FROM shop.cat WHERE id > $1 LIMIT 1`,
+			`SELECT name FROM shop_cat WHERE id > ? LIMIT 1`, `[3]`},
+		{`(*shop.prod (or (eq .kind 'food') (lt 1 .id 10)) (ne .alt null) desc:created off:20 _ id; full;)`,
+			`SELECT id, (name || $1 || kind) AS full FROM shop.prod` +
+				` WHERE ((kind = $2) OR ($3 < id AND id < $4)) AND (alt IS NOT NULL)` +
+				` ORDER BY created DESC OFFSET 20`,
+			`SELECT id, (name || ? || kind) AS full FROM shop_prod` +
+				` WHERE ((kind = ?) OR (? < id AND id < ?)) AND (alt IS NOT NULL)` +
+				` ORDER BY created DESC LIMIT -1 OFFSET 20`,
+			`[  food 1 10]`},
+		{`(*shop.prod (in .cat [1 2]) (not (eq .name (cat 'a' 'b'))) asc:name lim:3 _ id; n:(add .name '!'))`,
+			`SELECT id, (name || $1) AS n FROM shop.prod` +
+				` WHERE (cat IN ($2, $3)) AND (NOT (name = $4)) ORDER BY name LIMIT 3`,
+			`SELECT id, (name || ?) AS n FROM shop_prod` +
+				` WHERE (cat IN (?, ?)) AND (NOT (name = ?)) ORDER BY name LIMIT 3`,
+			`[! 1 2 ab]`},
+		{`(*shop.prod grp:cat asc:cat _ cat; n:(count) m:(max .name))`,
+			`SELECT cat, count(*) AS n, max(name) AS m FROM shop.prod GROUP BY cat ORDER BY cat`,
+			`SELECT cat, count(*) AS n, max(name) AS m FROM shop_prod GROUP BY cat ORDER BY cat`,
+			`[]`},
+		{`(#shop.prod (gt .id 3) grp:cat)`,
+			`SELECT count(*) FROM (SELECT 1 FROM shop.prod WHERE id > $1 GROUP BY cat) AS t`,
+			`SELECT count(*) FROM (SELECT 1 FROM shop_prod WHERE id > ? GROUP BY cat) AS t`,
+			`[3]`},
+		{`(*shop.prod asc:name asc:id lim:2 aft:'WydhJyAzXQ' _ id; name;)`,
+			`SELECT id, name FROM shop.prod` +
+				` WHERE (name > $1) OR (name = $2 AND id > $3) ORDER BY name, id LIMIT 2`,
+			`SELECT id, name FROM shop_prod` +
+				` WHERE (name > ?) OR (name = ? AND id > ?) ORDER BY name, id LIMIT 2`,
+			`[a a 3]`},
+		{`(*shop.cat (eq .name 'a') desc:id lim:5 aft:'WzNd')`,
+			`SELECT id, name FROM shop.cat WHERE name = $1 AND (id < $2) ORDER BY id DESC LIMIT 5`,
+			`SELECT id, name FROM shop_cat WHERE name = ? AND (id < ?) ORDER BY id DESC LIMIT 5`,
+			`[a 3]`},
+	}
+	for _, d := range []Dialect{Postgres, SQLite} {
+		for _, test := range tests {
+			b := &selBackend{pro: pro, d: d}
+			_, err := exp.NewProg(qry.NewDoc(extlib.Std, b), reg).RunStr(test.raw, nil)
+			if err != nil {
+				t.Errorf("%s query %s: %v", d.Name(), test.raw, err)
+				continue
+			}
+			want := test.pg
+			if d == SQLite {
+				want = test.lite
+			}
+			if b.sql != want {
+				t.Errorf("%s query %s\n\twant %s\n\tgot  %s", d.Name(), test.raw, want, b.sql)
+			}
+			if got := fmt.Sprint(b.args); got != test.args {
+				t.Errorf("%s query %s want args %s got %s", d.Name(), test.raw, test.args, got)
+			}
+		}
+	}
+}
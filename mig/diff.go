@@ -0,0 +1,250 @@
+package mig
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Change describes one structural difference between two project definitions.
+//
+// The op is '+' for additions, '-' for removals and '*' for modifications. The kind is one of
+// schema, model, elem, const, index, or kind, type, val, flags and tags for modified properties.
+// The name is the qualified name of the changed node and old and new hold the changed details.
+// Break is set for changes that are not compatible with data or clients of the old definition.
+type Change struct {
+	Op    string `json:"op"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+	Break bool   `json:"break,omitempty"`
+}
+
+func (c Change) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", c.Op, c.Kind, c.Name)
+	switch {
+	case c.Old != "" && c.New != "":
+		fmt.Fprintf(&b, " %s -> %s", c.Old, c.New)
+	case c.New != "":
+		fmt.Fprintf(&b, " %s", c.New)
+	case c.Old != "":
+		fmt.Fprintf(&b, " %s", c.Old)
+	}
+	if c.Break {
+		b.WriteString(" (breaking)")
+	}
+	return b.String()
+}
+
+// Breaking returns only the breaking changes of cs.
+func Breaking(cs []Change) (res []Change) {
+	for _, c := range cs {
+		if c.Break {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// DiffProjects returns the structural changes from the old to the cur project. In contrast to the
+// manifest diff it reports what changed: added and removed schemas, models, elems, enum or bits
+// constants and indices, as well as modified elem types, flags and tags and constant values.
+//
+// Removed nodes, changed model kinds, renumbered constants, added required elems, narrowed elem
+// types, flags that make elems required, unique or change the primary key, and added unique
+// indices are marked as breaking changes.
+func DiffProjects(old, cur *dom.Project) []Change {
+	var d differ
+	for _, s := range old.Schemas {
+		if cur.Schema(s.Name) == nil {
+			d.add("-", "schema", s.Name, "", "", true)
+		}
+	}
+	for _, s := range cur.Schemas {
+		if o := old.Schema(s.Name); o == nil {
+			d.add("+", "schema", s.Name, "", "", false)
+		} else {
+			d.schema(o, s)
+		}
+	}
+	return d.res
+}
+
+type differ struct{ res []Change }
+
+func (d *differ) add(op, kind, name, old, cur string, brk bool) {
+	d.res = append(d.res, Change{op, kind, name, old, cur, brk})
+}
+
+func (d *differ) schema(old, cur *dom.Schema) {
+	for _, m := range old.Models {
+		if cur.Model(m.Key()) == nil {
+			d.add("-", "model", m.Qualified(), "", "", true)
+		}
+	}
+	for _, m := range cur.Models {
+		if o := old.Model(m.Key()); o == nil {
+			d.add("+", "model", m.Qualified(), "", "", false)
+		} else {
+			d.model(o, m)
+		}
+	}
+}
+
+func (d *differ) model(old, cur *dom.Model) {
+	name := cur.Qualified()
+	if old.Kind.Kind != cur.Kind.Kind {
+		d.add("*", "kind", name, old.Kind.String(), cur.Kind.String(), true)
+	}
+	kind := "elem"
+	if cur.Kind.Kind&(knd.Enum|knd.Bits) != 0 {
+		kind = "const"
+	}
+	for _, el := range old.Elems {
+		if findElem(cur, elemKey(el)) == nil {
+			d.add("-", kind, name+"."+elemKey(el), elemDetail(old, el), "", true)
+		}
+	}
+	for _, el := range cur.Elems {
+		key := elemKey(el)
+		o := findElem(old, key)
+		if o == nil {
+			d.add("+", kind, name+"."+key, "", elemDetail(cur, el), kind == "elem" && required(el))
+			continue
+		}
+		if kind == "const" {
+			if o.Val != el.Val {
+				d.add("*", "val", name+"."+key, fmt.Sprint(o.Val), fmt.Sprint(el.Val), true)
+			}
+			continue
+		}
+		if ot, t := o.Type.String(), el.Type.String(); ot != t {
+			d.add("*", "type", name+"."+key, ot, t, !widens(o.Type, el.Type))
+		}
+		if o.Bits != el.Bits {
+			d.add("*", "flags", name+"."+key, bitsString(o.Bits), bitsString(el.Bits),
+				breakBits(o.Bits, el.Bits))
+		}
+		if ox, x := extraString(o), extraString(el); ox != x {
+			d.add("*", "tags", name+"."+key, ox, x, false)
+		}
+	}
+	oi, ci := indexStrings(old), indexStrings(cur)
+	for _, idx := range oi {
+		if !dom.ContainsStr(ci, idx) {
+			d.add("-", "index", name, idx, "", false)
+		}
+	}
+	for _, idx := range ci {
+		if !dom.ContainsStr(oi, idx) {
+			d.add("+", "index", name, "", idx, strings.Contains(idx, "uniq("))
+		}
+	}
+}
+
+// elemKey returns the elem key without optional marker or the type reference prefixed with '@'
+// for embedded elems.
+func elemKey(el *dom.Elem) string {
+	if el.Name == "" {
+		return "@" + strings.ToLower(el.Type.Ref)
+	}
+	return strings.TrimSuffix(el.Key(), "?")
+}
+
+// required returns whether an added elem el needs a value for existing data.
+func required(el *dom.Elem) bool {
+	return el.Bits&dom.BitOpt == 0 && el.Type.Kind&knd.None == 0 &&
+		el.DefaultSrc() == "" && el.CalcSrc() == ""
+}
+
+// widens returns whether the cur elem type accepts all values of the old type. That is the case
+// for types that only become optional or ints that become reals.
+func widens(old, cur typ.Type) bool {
+	if old.Kind&knd.None != 0 && cur.Kind&knd.None == 0 {
+		return false
+	}
+	od, cd := old.Kind&knd.Data, cur.Kind&knd.Data
+	if od == knd.Int && cd == knd.Real {
+		return true
+	}
+	return strings.TrimSuffix(old.String(), "?") == strings.TrimSuffix(cur.String(), "?")
+}
+
+// breakBits returns whether the changed flags make an elem required or unique or change the
+// primary key.
+func breakBits(old, cur dom.Bit) bool {
+	return old&dom.BitOpt != 0 && cur&dom.BitOpt == 0 ||
+		old&dom.BitUniq == 0 && cur&dom.BitUniq != 0 ||
+		old&dom.BitPK != cur&dom.BitPK
+}
+
+func findElem(m *dom.Model, key string) *dom.Elem {
+	for _, el := range m.Elems {
+		if elemKey(el) == key {
+			return el
+		}
+	}
+	return nil
+}
+
+func elemDetail(m *dom.Model, el *dom.Elem) string {
+	if m.Kind.Kind&(knd.Enum|knd.Bits) != 0 {
+		return fmt.Sprint(el.Val)
+	}
+	return el.Type.String()
+}
+
+func bitsString(b dom.Bit) string {
+	var res []string
+	for i, n := range dom.BitNames() {
+		if b&(1<<uint(i)) != 0 {
+			res = append(res, n)
+		}
+	}
+	if len(res) == 0 {
+		return "none"
+	}
+	return strings.Join(res, "|")
+}
+
+// extraString returns the elem tags without the pos tag, that changes with unrelated edits.
+func extraString(el *dom.Elem) string {
+	if el.Extra == nil {
+		return ""
+	}
+	x := &lit.Dict{Typ: el.Extra.Typ}
+	for _, kv := range el.Extra.Keyed {
+		if kv.Key != "pos" {
+			x.Keyed = append(x.Keyed, kv)
+		}
+	}
+	if len(x.Keyed) == 0 {
+		return ""
+	}
+	return x.String()
+}
+
+func indexStrings(m *dom.Model) []string {
+	if m.Object == nil {
+		return nil
+	}
+	res := make([]string, 0, len(m.Object.Indices))
+	for _, idx := range m.Object.Indices {
+		kind := "idx"
+		if idx.Unique {
+			kind = "uniq"
+		}
+		s := fmt.Sprintf("%s(%s)", kind, strings.Join(idx.Keys, " "))
+		if idx.Name != "" {
+			s = idx.Name + ":" + s
+		}
+		res = append(res, s)
+	}
+	return res
+}
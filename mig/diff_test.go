@@ -0,0 +1,85 @@
+package mig
+
+import (
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom"
+)
+
+func TestDiffProjects(t *testing.T) {
+	old := testProject(t, `(schema shop
+		(Flag:bits A; B;)
+		(Cat; ID:int Name:str)
+		(Prod; ID:int (Name:str idx;) Qty:int Ref:str Cat:@Cat.ID idx:['name' 'qty'])
+		(Log; ID:int)
+	)`, `(schema old (Note; ID:int))`)
+	cur := testProject(t, `(schema shop
+		(Flag:bits A; B; C;)
+		(Cat; ID:int Name:str (Label?:str default:'x') Code:str)
+		(Prod; ID:int (Name:str uniq;) Qty:real Ref:int (Cat:@Cat.ID ondel:cascade)
+			uniq:['name' 'qty'])
+		(Rate; ID:int Val:real)
+	)`)
+	want := []string{
+		"- schema old !",
+		"- model shop.Log !",
+		"+ const shop.Flag.c",
+		"+ elem shop.Cat.label",
+		"+ elem shop.Cat.code !",
+		"* flags shop.Prod.name !",
+		"* type shop.Prod.qty",
+		"* type shop.Prod.ref !",
+		"* tags shop.Prod.cat",
+		"- index shop.Prod",
+		"+ index shop.Prod !",
+		"+ model shop.Rate",
+	}
+	got := DiffProjects(old, cur)
+	if len(got) != len(want) {
+		t.Fatalf("want %d changes got %d: %v", len(want), len(got), got)
+	}
+	for i, c := range got {
+		s := c.Op + " " + c.Kind + " " + c.Name
+		if c.Break {
+			s += " !"
+		}
+		if s != want[i] {
+			t.Errorf("change %d want %s got %s", i, want[i], c)
+		}
+	}
+	details := []string{
+		"+ const shop.Flag.c 4",
+		"* flags shop.Prod.name idx -> uniq (breaking)",
+		"* type shop.Prod.qty int -> real",
+		"- index shop.Prod idx(name qty)",
+		"+ index shop.Prod uniq(name qty) (breaking)",
+	}
+	for _, d := range details {
+		var found bool
+		for _, c := range got {
+			found = found || c.String() == d
+		}
+		if !found {
+			t.Errorf("change %s not found in %v", d, got)
+		}
+	}
+	if n := len(Breaking(got)); n != 6 {
+		t.Errorf("want 6 breaking changes got %d", n)
+	}
+	if cs := DiffProjects(cur, cur); len(cs) != 0 {
+		t.Errorf("want no changes got %v", cs)
+	}
+}
+
+func testProject(t *testing.T, raws ...string) *dom.Project {
+	pr := &dom.Project{}
+	for _, raw := range raws {
+		s, err := dom.ReadSchema(nil, strings.NewReader(raw), "")
+		if err != nil {
+			t.Fatalf("schema error %v", err)
+		}
+		pr.Schemas = append(pr.Schemas, s)
+	}
+	return pr
+}
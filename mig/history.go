@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"xelf.org/daql/dom"
+	"xelf.org/daql/log"
 	"xelf.org/xelf/bfr"
 	"xelf.org/xelf/cor"
 	"xelf.org/xelf/ext"
@@ -99,6 +100,7 @@ func ReadHistory(reg *lit.Regs, path string) (_ History, err error) {
 	sort.Slice(h.recs, func(i, j int) bool {
 		return h.recs[i].First().Vers < h.recs[j].First().Vers
 	})
+	var old *dom.Project
 	if len(h.recs) > 0 {
 		lst := h.recs[len(h.recs)-1]
 		v, _ := ParseVers(h.curr.First().Vers)
@@ -108,8 +110,15 @@ func ReadHistory(reg *lit.Regs, path string) (_ History, err error) {
 			return nil, fmt.Errorf("inconsistent history manifest version %d != %d",
 				v, lv)
 		}
+		// the last recorded project is used to detect breaking changes
+		r, err := h.Record(lst.First().Vers)
+		if err != nil {
+			log.Debug("read last record failed", "path", lst.Path, "err", err)
+		} else {
+			old = r.Project
+		}
 	}
-	h.curr.Manifest, err = h.curr.Manifest.Update(h.curr.Project)
+	h.curr.Manifest, err = h.curr.Manifest.UpdateFrom(h.curr.Project, old)
 	if err != nil {
 		return nil, err
 	}
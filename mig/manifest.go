@@ -118,6 +118,16 @@ func (mf Manifest) Update(pr *dom.Project) (Manifest, error) {
 	return mv.Manifest(pr)
 }
 
+// UpdateFrom works like Update but bumps the major version of nodes with breaking changes from the
+// old project definition, that must match the manifest. A nil old project is ignored.
+func (mf Manifest) UpdateFrom(pr, old *dom.Project) (Manifest, error) {
+	mv := NewVersioner(mf)
+	if old != nil {
+		mv.Break(DiffProjects(old, pr))
+	}
+	return mv.Manifest(pr)
+}
+
 func (mf Manifest) Len() int           { return len(mf) }
 func (mf Manifest) Less(i, j int) bool { return mf[i].Name < mf[j].Name }
 func (mf Manifest) Swap(i, j int)      { mf[i], mf[j] = mf[j], mf[i] }
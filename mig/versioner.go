@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"strings"
 
 	"xelf.org/daql/dom"
 	"xelf.org/xelf/lit"
@@ -31,6 +32,29 @@ func NewVersioner(mf Manifest) Versioner {
 // Versioner sets and returns node version details, usually based on the last recorded manifest.
 type Versioner map[string]*entry
 
+// Break marks the nodes affected by the breaking changes in cs, usually the structural diff to the
+// last recorded project. The marked nodes, their schema and the project bump the major version
+// instead of the minor version. It must be called before any version is computed.
+func (mv Versioner) Break(cs []Change) {
+	for _, c := range Breaking(cs) {
+		for _, key := range breakKeys(c) {
+			if e := mv[key]; e != nil {
+				e.why = append(e.why, c)
+			}
+		}
+	}
+}
+
+// breakKeys returns the version keys of the project, schema and model affected by change c.
+func breakKeys(c Change) []string {
+	parts := strings.SplitN(c.Name, ".", 3)
+	res := []string{"_", strings.ToLower(parts[0])}
+	if len(parts) > 1 {
+		res = append(res, parts[0]+"."+parts[1])
+	}
+	return res
+}
+
 // Manifest returns a update manifest for Project.
 func (mv Versioner) Manifest(pr *dom.Project) (Manifest, error) {
 	_, err := mv.Version(pr)
@@ -94,6 +118,9 @@ func (mv Versioner) Version(n dom.Node) (res Version, err error) {
 				return res, err
 			}
 		}
+		for _, q := range d.Queries {
+			fmt.Fprintf(hp, " query:%s:%s\n", q.Name, q.Src)
+		}
 	case *dom.Project:
 		fmt.Fprintf(hm, "%s\n", res.Name)
 		hashExtra(hm, hp, d.Extra)
@@ -112,7 +139,11 @@ func (mv Versioner) Version(n dom.Node) (res Version, err error) {
 		res.Vers = resv.String()
 		mv[key] = &entry{cur: res}
 	} else if res.Minor != e.old.Minor || res.Patch != e.old.Patch {
-		if res.Minor != e.old.Minor {
+		if res.Minor != e.old.Minor && len(e.why) > 0 {
+			resv.Major++
+			resv.Minor = 0
+			resv.Patch = 0
+		} else if res.Minor != e.old.Minor {
 			resv.Minor++
 			resv.Patch = 0
 		} else {
@@ -130,6 +161,7 @@ func (mv Versioner) Version(n dom.Node) (res Version, err error) {
 type entry struct {
 	old Version
 	cur Version
+	why []Change
 }
 
 func (mv Versioner) hashNode(hm, hp io.Writer, n dom.Node) error {
@@ -148,7 +180,7 @@ func hashExtra(hm, hp io.Writer, d *lit.Dict) {
 	}
 	for _, kv := range d.Keyed {
 		switch kv.Key {
-		case "doc", "file", "hist":
+		case "doc", "file", "pos", "hist":
 			continue
 		case "backup":
 			fmt.Fprintf(hm, "    %s:%s\n", kv.Key, kv.Val)
@@ -0,0 +1,41 @@
+package mig
+
+import "testing"
+
+func TestVersionerBreak(t *testing.T) {
+	old := testProject(t, `(schema shop (Cat; ID:int Name:str) (Prod; ID:int Name:str))`)
+	mf, err := Manifest{}.Update(old)
+	if err != nil {
+		t.Fatalf("manifest error %v", err)
+	}
+	tests := []struct {
+		raw  string
+		want map[string]string
+	}{
+		{`(schema shop (Cat; ID:int Name:str) (Prod; ID:int Name:str))`, map[string]string{
+			"_": "v0.0.1", "shop": "v0.0.1", "shop.Cat": "v0.0.1", "shop.Prod": "v0.0.1",
+		}},
+		{`(schema shop (Cat; ID:int Name:str Note?:str) (Prod; ID:int Name:str))`, map[string]string{
+			"_": "v0.1.0", "shop": "v0.1.0", "shop.Cat": "v0.1.0", "shop.Prod": "v0.0.1",
+		}},
+		{`(schema shop (Cat; ID:int) (Prod; ID:int Name:str))`, map[string]string{
+			"_": "v1.0.0", "shop": "v1.0.0", "shop.Cat": "v1.0.0", "shop.Prod": "v0.0.1",
+		}},
+		{`(schema shop (Cat; ID:int Name:str) (Prod; ID:int Name:str Qty:int))`, map[string]string{
+			"_": "v1.0.0", "shop": "v1.0.0", "shop.Cat": "v0.0.1", "shop.Prod": "v1.0.0",
+		}},
+	}
+	for _, test := range tests {
+		cur := testProject(t, test.raw)
+		got, err := mf.UpdateFrom(cur, old)
+		if err != nil {
+			t.Errorf("update %s: %v", test.raw, err)
+			continue
+		}
+		for name, want := range test.want {
+			if v, _ := got.Get(name); v.Vers != want {
+				t.Errorf("update %s: %s want %s got %s", test.raw, name, want, v.Vers)
+			}
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package qry
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Batcher is an optional backend interface for backends that support batched sub queries.
+//
+// Sub queries that are correlated with the parent query by a whr term like (eq .cat ..id) are
+// executed only once for all parent rows instead of once for each parent row. The batch job
+// selects all subject fields for all parent keys using an additional whr term (in .cat [keys])
+// and has no order, group, cursor, limit or offset. The results are then grouped by key, selected,
+// ordered and limited for each parent row.
+type Batcher interface {
+	// Batch returns whether the backend can execute batch jobs for sub job j.
+	Batch(j *Job) bool
+}
+
+// corr describes a sub job that is correlated with its parent on a key.
+type corr struct {
+	key  string    // sub subject field key
+	pkey string    // parent subject field key
+	whr  []exp.Exp // remaining whr without the correlated term
+	typ  typ.Type  // the key field type
+	sub  *Job      // the sub job
+	keys []lit.Val // the distinct parent keys in the key field type
+	rows []string  // the parent key string for each parent row
+}
+
+// batchSubs returns the values of all batched sub query fields of job j for the subject values in
+// vals by field key. Fields that cannot be batched are omitted.
+func batchSubs(p *exp.Prog, j *Job, vals lit.Vals) (map[string]lit.Vals, error) {
+	if len(vals) < 2 {
+		return nil, nil
+	}
+	var res map[string]lit.Vals
+	for _, f := range j.Sel.Fields {
+		if f.Sub == nil {
+			continue
+		}
+		if b, ok := f.Sub.Bend.(Batcher); !ok || !b.Batch(f.Sub) {
+			continue
+		}
+		c := correlate(f.Sub)
+		if c == nil || !c.parentKeys(p, vals) {
+			continue
+		}
+		vs, err := c.exec(p)
+		if err != nil {
+			return nil, err
+		}
+		if vs == nil {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]lit.Vals)
+		}
+		res[f.Key] = vs
+	}
+	return res, nil
+}
+
+// correlate returns the correlation of sub job j or nil. The sub job must have exactly one whr
+// term comparing a subject field with a parent field and no other references to parent fields.
+func correlate(j *Job) *corr {
+	var c *corr
+	whr := make([]exp.Exp, 0, len(j.Whr))
+	for _, w := range j.Whr {
+		if key, pkey, ok := corrTerm(w); ok && c == nil {
+			f := j.Subj.Field(key)
+			if f == nil || !exactType(f.Type) {
+				return nil
+			}
+			c = &corr{key: key, pkey: pkey, typ: f.Type, sub: j}
+			continue
+		}
+		if hasParent(w) {
+			return nil
+		}
+		whr = append(whr, w)
+	}
+	if c == nil {
+		return nil
+	}
+	for _, f := range j.Sel.Fields {
+		if f.Exp != nil && hasParent(f.Exp) {
+			return nil
+		}
+	}
+	c.whr = whr
+	return c
+}
+
+// corrTerm returns the subject and parent field keys of a whr term like (eq .cat ..id).
+func corrTerm(x exp.Exp) (key, pkey string, ok bool) {
+	c, ok := x.(*exp.Call)
+	if !ok || c.Spec == nil || c.Sig.Ref != "eq" {
+		return "", "", false
+	}
+	args := flatArgs(c.Args)
+	if len(args) != 2 {
+		return "", "", false
+	}
+	a, b := args[0], args[1]
+	if _, ok := fieldKey(a); !ok {
+		a, b = b, a
+	}
+	key, ok = fieldKey(a)
+	if !ok {
+		return "", "", false
+	}
+	pkey, ok = parentKey(b)
+	return key, pkey, ok
+}
+
+// parentKeys collects the distinct parent keys for the subject values in vals. It returns false
+// if a key is null or cannot be converted to the sub field type.
+func (c *corr) parentKeys(p *exp.Prog, vals lit.Vals) bool {
+	c.rows = make([]string, 0, len(vals))
+	idx := make(map[string]int, len(vals))
+	for _, v := range vals {
+		pv, err := lit.Select(v, c.pkey)
+		if err != nil || pv == nil || pv.Nil() {
+			return false
+		}
+		mut := p.Reg.Zero(c.typ)
+		if err = mut.Assign(pv); err != nil {
+			return false
+		}
+		k := mut.String()
+		if _, ok := idx[k]; !ok {
+			idx[k] = len(c.keys)
+			c.keys = append(c.keys, mut)
+		}
+		c.rows = append(c.rows, k)
+	}
+	return true
+}
+
+// maxBatchKeys is the maximum number of parent keys used in one batch job. It keeps the in list
+// below the bind parameter limits of sql databases, the lowest being 999 in older sqlite versions.
+var maxBatchKeys = 999
+
+// exec executes the batch job and returns the sub query result for each parent row. It returns
+// nil if the batch job cannot be resolved. The parent keys are split into chunks of at most
+// maxBatchKeys, each executed as its own batch job.
+func (c *corr) exec(p *exp.Prog) (lit.Vals, error) {
+	var vals lit.Vals
+	for keys := c.keys; len(keys) > 0; {
+		n := len(keys)
+		if n > maxBatchKeys {
+			n = maxBatchKeys
+		}
+		vs, ok, err := c.chunk(p, keys[:n])
+		if err != nil || !ok {
+			return nil, err
+		}
+		vals = append(vals, vs...)
+		keys = keys[n:]
+	}
+	// group the subject values by key in batch result order
+	groups := make(map[string]lit.Vals, len(c.keys))
+	for _, v := range vals {
+		kv, err := lit.Select(v, c.key)
+		if err != nil {
+			return nil, err
+		}
+		k := kv.String()
+		groups[k] = append(groups[k], v)
+	}
+	res := make(lit.Vals, 0, len(c.rows))
+	cache := make(map[string]lit.Val, len(c.keys))
+	for _, k := range c.rows {
+		if v, ok := cache[k]; ok {
+			// every parent row needs its own result value
+			cv, err := lit.Clone(v)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, cv)
+			continue
+		}
+		v, err := c.result(p, groups[k])
+		if err != nil {
+			return nil, err
+		}
+		cache[k] = v
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// chunk executes the batch job for the parent keys and returns the subject values. It returns
+// false if the result has an unexpected type.
+func (c *corr) chunk(p *exp.Prog, keys []lit.Val) (lit.Vals, bool, error) {
+	sub := c.sub
+	t := *sub.Task
+	t.Kind, t.Sel, t.Res = KindMany, &sub.Subj.Sel, typ.ListOf(sub.Subj.Type)
+	t.Ord, t.Grp, t.Aft, t.Lim, t.Off = nil, nil, nil, 0, 0
+	bj := *sub
+	bj.Task = &t
+	in, err := p.Resl(&bj, &exp.Call{Args: []exp.Exp{
+		&exp.Sym{Sym: "in"},
+		&exp.Sym{Sym: "." + c.key},
+		exp.LitVal(lit.NewList(c.typ, keys...)),
+	}}, typ.Bool)
+	if err != nil {
+		return nil, false, fmt.Errorf("batch %s by %s: %w", sub.Ref, c.key, err)
+	}
+	t.Whr = append(c.whr[:len(c.whr):len(c.whr)], in)
+	a, err := sub.Bend.Exec(p, &bj)
+	if err != nil {
+		return nil, false, err
+	}
+	switch v := a.Value().(type) {
+	case *lit.List:
+		return v.Vals, true, nil
+	case *lit.Vals:
+		return *v, true, nil
+	}
+	return nil, false, nil
+}
+
+// result returns the sub query result for the subject values of one parent key.
+func (c *corr) result(p *exp.Prog, vals lit.Vals) (lit.Val, error) {
+	sub := c.sub
+	if sub.Kind == KindCount {
+		n := int64(len(vals))
+		if len(sub.Grp) > 0 {
+			gs, err := groupVals(sub, vals)
+			if err != nil {
+				return nil, err
+			}
+			n = int64(len(gs))
+		}
+		return lit.Int(limitCount(sub, n)), nil
+	}
+	// the sub job is shared by all parent rows, so no next cursor is computed
+	res, _, err := selectOrg(p, sub, vals, false)
+	if err != nil {
+		return nil, err
+	}
+	return listResult(sub, res)
+}
+
+func flatArgs(args []exp.Exp) []exp.Exp {
+	res := make([]exp.Exp, 0, len(args))
+	for _, arg := range args {
+		if t, ok := arg.(*exp.Tupl); ok {
+			res = append(res, t.Els...)
+		} else if arg != nil {
+			res = append(res, arg)
+		}
+	}
+	return res
+}
+
+// parentKey returns the key of a simple parent field reference like '..id'.
+func parentKey(x exp.Exp) (string, bool) {
+	s, ok := x.(*exp.Sym)
+	if !ok || len(s.Sym) < 3 || !strings.HasPrefix(s.Sym, "..") || s.Sym[2] == '.' ||
+		strings.ContainsAny(s.Sym[2:], "./") {
+		return "", false
+	}
+	return strings.ToLower(s.Sym[2:]), true
+}
+
+// hasParent returns whether x contains references to parent fields.
+func hasParent(x exp.Exp) bool {
+	switch v := x.(type) {
+	case *exp.Sym:
+		return strings.HasPrefix(v.Sym, "..")
+	case *exp.Call:
+		for _, arg := range v.Args {
+			if hasParent(arg) {
+				return true
+			}
+		}
+	case *exp.Tupl:
+		for _, el := range v.Els {
+			if hasParent(el) {
+				return true
+			}
+		}
+	}
+	return false
+}
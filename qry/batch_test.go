@@ -0,0 +1,93 @@
+package qry_test
+
+import (
+	"testing"
+
+	. "xelf.org/daql/qry"
+
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+// countBackend counts executed jobs and only batches if batch is set.
+type countBackend struct {
+	*MemBackend
+	batch bool
+	n     int
+}
+
+func (b *countBackend) Batch(j *Job) bool { return b.batch && b.MemBackend.Batch(j) }
+func (b *countBackend) Exec(p *exp.Prog, j *Job) (*exp.Lit, error) {
+	b.n++
+	return b.MemBackend.Exec(p, j)
+}
+
+func TestBatch(t *testing.T) {
+	reg := lit.NewRegs()
+	mem := getBackend(reg).(*MemBackend)
+	tests := []struct {
+		raw   string
+		want  string
+		execs int
+	}{
+		{`(*prod.cat (or (eq .name 'b') (eq .name 'c')) asc:name +
+			prods:(*prod.prod (eq .cat ..id) asc:name _ id; name;)
+		)`, `[{id:2 name:'b' prods:[{id:2 name:'B'} {id:4 name:'D'}]} ` +
+			`{id:3 name:'c' prods:[{id:1 name:'A'} {id:3 name:'C'}]}]`, 2},
+		{`(*prod.cat (lt .id 4) asc:id _ id; n:(#prod.prod (eq .cat ..id)))`,
+			`[{id:1 n:2} {id:2 n:2} {id:3 n:2}]`, 2},
+		{`(*prod.cat (lt .id 4) asc:id _ id; n:(#prod.prod (eq .cat ..id) lim:1))`,
+			`[{id:1 n:1} {id:2 n:1} {id:3 n:1}]`, 2},
+		{`(*prod.cat (lt .id 4) asc:id _ id;
+			p:(?prod.prod (eq .cat ..id) desc:name _:name)
+		)`, `[{id:1 p:'Z'} {id:2 p:'D'} {id:3 p:'C'}]`, 2},
+		{`(*prod.cat (lt .id 4) asc:id _ id;
+			p:(*prod.prod (eq .cat ..id) (ne .name 'Y') asc:name off:1 _:name)
+		)`, `[{id:1 p:[]} {id:2 p:['D']} {id:3 p:['C']}]`, 2},
+		{`(*prod.cat (lt .id 4) asc:id _ id; s:(?prod.prod (eq .cat ..id) _:(sum .id)))`,
+			`[{id:1 s:51} {id:2 s:6} {id:3 s:4}]`, 2},
+		{`(*prod.prod (ge .id 25) + catn:(?prod.cat (eq .id ..cat) _:name))`,
+			`[{id:25 name:'Y' cat:1 catn:'a'} {id:26 name:'Z' cat:1 catn:'a'}]`, 2},
+		{`(*prod.cat (lt .id 3) asc:id _ id;
+			n:(#prod.prod (eq .cat ..id) (gt .id ..id))
+		)`, `[{id:1 n:2} {id:2 n:1}]`, 3},
+	}
+	for _, test := range tests {
+		for _, batch := range []bool{false, true} {
+			b := &countBackend{MemBackend: mem, batch: batch}
+			el, err := exp.NewProg(NewDoc(extlib.Std, b), reg).RunStr(test.raw, nil)
+			if err != nil {
+				t.Errorf("qry %s batch %v failed: %v", test.raw, batch, err)
+				continue
+			}
+			if got := bfr.String(el); got != test.want {
+				t.Errorf("want for %s batch %v\n\t%s got %s", test.raw, batch,
+					test.want, got)
+			}
+			if batch && b.n != test.execs {
+				t.Errorf("qry %s want %d execs got %d", test.raw, test.execs, b.n)
+			}
+		}
+	}
+}
+
+func TestBatchChunks(t *testing.T) {
+	reg := lit.NewRegs()
+	defer SetMaxBatchKeys(2)()
+	b := &countBackend{MemBackend: getBackend(reg).(*MemBackend), batch: true}
+	raw := `(*prod.cat (lt .id 4) asc:id _ id; n:(#prod.prod (eq .cat ..id)))`
+	el, err := exp.NewProg(NewDoc(extlib.Std, b), reg).RunStr(raw, nil)
+	if err != nil {
+		t.Fatalf("qry %s failed: %v", raw, err)
+	}
+	want := `[{id:1 n:2} {id:2 n:2} {id:3 n:2}]`
+	if got := bfr.String(el); got != want {
+		t.Errorf("want for %s\n\t%s got %s", raw, want, got)
+	}
+	// one parent job and two batch jobs for three parent keys
+	if b.n != 3 {
+		t.Errorf("qry %s want 3 execs got %d", raw, b.n)
+	}
+}
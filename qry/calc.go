@@ -0,0 +1,192 @@
+package qry
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// calcVals returns copies of vals with the computed elems of the job model evaluated or vals
+// itself if the job references none of them. The computed expressions are resolved once and then
+// evaluated in the job environment for each value.
+func calcVals(p *exp.Prog, j *Job, vals lit.Vals) (lit.Vals, error) {
+	if j.Model == nil {
+		return vals, nil
+	}
+	calcs, xs, err := usedCalcs(j)
+	if err != nil || len(calcs) == 0 {
+		return vals, err
+	}
+	for i, x := range xs {
+		xs[i], err = p.Resl(j, x, typ.Void)
+		if err != nil {
+			return nil, fmt.Errorf("calc %s: %w", calcs[i].Name, err)
+		}
+	}
+	res := make(lit.Vals, 0, len(vals))
+	for _, v := range vals {
+		c, err := lit.Clone(v)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := c.Value().(lit.Keyr)
+		if !ok {
+			return nil, fmt.Errorf("calc %s: expect keyer got %T", j.Model.Qualified(), c)
+		}
+		j.Cur = c
+		for i, x := range xs {
+			a, err := p.Eval(j, x)
+			if err != nil {
+				return nil, fmt.Errorf("calc %s: %w", calcs[i].Name, err)
+			}
+			err = k.SetKey(calcKey(calcs[i]), a)
+			if err != nil {
+				return nil, fmt.Errorf("calc %s: %w", calcs[i].Name, err)
+			}
+		}
+		res = append(res, c)
+	}
+	j.Cur = nil
+	return res, nil
+}
+
+// usedCalcs returns the computed elems of the job model that job j references in its selection,
+// filter, order or group keys, and their expressions. Computed elems used by other used computed
+// elems are included as well.
+func usedCalcs(j *Job) ([]*dom.Elem, []exp.Exp, error) {
+	all := j.Model.Calcs()
+	if len(all) == 0 {
+		return nil, nil, nil
+	}
+	r := &calcRefs{keys: make(map[string]bool)}
+	r.task(j.Task)
+	keys := r.keys
+	xs := make([]exp.Exp, len(all))
+	used := make([]bool, len(all))
+	for found := true; found; {
+		found = false
+		for i, el := range all {
+			if used[i] || !keys[calcKey(el)] {
+				continue
+			}
+			x, err := el.CalcExp()
+			if err != nil {
+				return nil, nil, err
+			}
+			r.exp(x)
+			xs[i], used[i], found = x, true, true
+		}
+	}
+	var calcs []*dom.Elem
+	var res []exp.Exp
+	for i, el := range all {
+		if used[i] {
+			calcs = append(calcs, el)
+			res = append(res, xs[i])
+		}
+	}
+	return calcs, res, nil
+}
+
+// calcRefs collects all keys that a task and its sub queries may refer to. It errs on the side of
+// caution, because a missing key results in an unevaluated computed elem.
+type calcRefs struct {
+	keys map[string]bool
+	seen []*Task
+}
+
+func (r *calcRefs) task(t *Task) {
+	for _, s := range r.seen {
+		if s == t {
+			return
+		}
+	}
+	r.seen = append(r.seen, t)
+	if t.Kind != KindCount && t.Sel != nil {
+		for _, f := range t.Sel.Fields {
+			if f.Exp == nil && f.Agg == "" {
+				r.keys[f.Key] = true
+			}
+			if f.Exp != nil {
+				r.exp(f.Exp)
+			}
+			if f.Sub != nil && f.Sub.Task != nil {
+				r.task(f.Sub.Task)
+			}
+		}
+	}
+	for _, x := range t.Whr {
+		r.exp(x)
+	}
+	for _, o := range t.Ord {
+		r.keys[o.Key] = true
+	}
+	for _, g := range t.Grp {
+		r.keys[g] = true
+	}
+}
+
+func (r *calcRefs) exp(x exp.Exp) {
+	switch v := x.(type) {
+	case *exp.Sym:
+		s := strings.TrimLeft(v.Sym, ".")
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			s = s[:i]
+		}
+		r.keys[strings.ToLower(s)] = true
+	case *exp.Call:
+		for _, a := range v.Args {
+			r.exp(a)
+		}
+		if sub, ok := v.Env.(*Job); ok && sub.Task != nil {
+			r.task(sub.Task)
+		}
+	case *exp.Tupl:
+		for _, e := range v.Els {
+			r.exp(e)
+		}
+	case *exp.Tag:
+		if v.Exp != nil {
+			r.exp(v.Exp)
+		}
+	}
+}
+
+// calcKey returns the value key of the computed elem el.
+func calcKey(el *dom.Elem) string { return strings.TrimSuffix(el.Key(), "?") }
+
+// storedList returns list or, if model m has computed elems, a list of values without them.
+func storedList(m *dom.Model, list *lit.List) (*lit.List, error) {
+	calcs := m.Calcs()
+	if len(calcs) == 0 {
+		return list, nil
+	}
+	ps := make([]typ.Param, 0, len(m.Elems))
+	for _, el := range m.Elems {
+		if el.CalcSrc() == "" {
+			ps = append(ps, typ.P(el.Name, el.Type))
+		}
+	}
+	st := typ.Obj(m.Qualified(), ps...)
+	res := lit.NewList(st)
+	for _, v := range list.Vals {
+		k, ok := v.Value().(lit.Keyr)
+		if !ok {
+			return nil, fmt.Errorf("stream %s: expect keyer got %T", m.Qualified(), v)
+		}
+		vals := make(lit.Vals, 0, len(ps))
+		for _, p := range ps {
+			pv, err := k.Key(p.Key)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, pv)
+		}
+		res.Vals = append(res.Vals, &lit.Obj{Typ: st, Vals: vals})
+	}
+	return res, nil
+}
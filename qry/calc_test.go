@@ -0,0 +1,64 @@
+package qry_test
+
+import (
+	"strings"
+	"testing"
+
+	. "xelf.org/daql/qry"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+func TestCalc(t *testing.T) {
+	reg := lit.NewRegs()
+	s, err := dom.ReadSchema(reg, strings.NewReader(`(schema test
+		(Person; ID:int First:str Last:str FullName:(cat .first ' ' .last)
+			(Nick?:str default:'anon'))
+	)`), "test.xelf")
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	b := NewMemBackend(&dom.Project{Name: "test", Schemas: []*dom.Schema{s}}, nil)
+	err = b.Add(s.Model("person"), &lit.Vals{
+		&lit.Vals{lit.Int(1), lit.Str("Alan"), lit.Str("Turing")},
+		&lit.Vals{lit.Int(2), lit.Str("Ada"), lit.Str("Lovelace"), lit.Str("ada")},
+	})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	tests := []struct {
+		Raw  string
+		Want string
+	}{
+		{`(?test.person (eq .id 1) _:fullname)`, `'Alan Turing'`},
+		{`(?test.person (eq .fullname 'Ada Lovelace') _:id)`, `2`},
+		{`(*test.person asc:fullname _:id)`, `[2 1]`},
+		{`(*test.person asc:id _:nick)`, `['anon' 'ada']`},
+		{`(?test.person (eq .id 2) _ fullname; nick;)`, `{fullname:'Ada Lovelace' nick:'ada'}`},
+	}
+	for _, test := range tests {
+		el, err := exp.NewProg(NewDoc(extlib.Std, b)).RunStr(test.Raw, nil)
+		if err != nil {
+			t.Errorf("qry %s failed: %v", test.Raw, err)
+			continue
+		}
+		if got := bfr.String(el); got != test.Want {
+			t.Errorf("want for %s\n\t%s got %s", test.Raw, test.Want, got)
+		}
+	}
+	it, err := b.Stream("test.person")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	v, err := it.Scan()
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got, want := bfr.String(v), `{id:1 first:'Alan' last:'Turing' nick:'anon'}`; got != want {
+		t.Errorf("stream want %s got %s", want, got)
+	}
+}
@@ -0,0 +1,121 @@
+package qry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+)
+
+// Paged returns whether the results of task t can be continued with a cursor. Paged tasks are
+// many queries with order and limit that do not group or order by optional fields.
+func (t *Task) Paged() bool {
+	return t.Kind == KindMany && t.Lim > 0 && len(t.Ord) > 0 && !t.Grouped() && !t.optOrd()
+}
+
+// optOrd returns whether task t is ordered by an optional field. Backends sort nulls differently
+// and keyset conditions cannot compare them, so these tasks cannot use cursors.
+func (t *Task) optOrd() bool {
+	for _, o := range t.Ord {
+		f := t.Sel.Field(o.Key)
+		if o.Subj {
+			f = t.Subj.Field(o.Key)
+		}
+		if f != nil && f.Type.Kind&knd.None != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Cursor returns an opaque cursor for the ord field values of the result sel with subject subj of
+// job j. The subject is only used for ord fields that are not selected.
+//
+// The cursor is the base64 encoded xelf list literal of the ord values and therefore independent
+// of the backend. It can be passed to the aft tag of the same query to select the next page.
+func Cursor(j *Job, sel, subj lit.Val) (string, error) {
+	vals := make(lit.Vals, 0, len(j.Ord))
+	for _, o := range j.Ord {
+		v, err := ordVal(o, sel, subj)
+		if err != nil {
+			return "", fmt.Errorf("cursor %s: %w", j.Ref, err)
+		}
+		vals = append(vals, v)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(vals.String())), nil
+}
+
+// parseCursor returns the ord field values of task t decoded from the cursor str.
+func parseCursor(p *exp.Prog, t *Task, str string) (lit.Vals, error) {
+	b, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	v, err := lit.Read(strings.NewReader(string(b)), "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	vals, ok := v.(*lit.Vals)
+	if !ok || len(*vals) != len(t.Ord) {
+		return nil, fmt.Errorf("invalid cursor for %d ord fields", len(t.Ord))
+	}
+	res := make(lit.Vals, 0, len(t.Ord))
+	for i, o := range t.Ord {
+		f := t.Sel.Field(o.Key)
+		if o.Subj {
+			f = t.Subj.Field(o.Key)
+		}
+		mut := p.Reg.Zero(f.Type)
+		if err = mut.Assign((*vals)[i]); err != nil {
+			return nil, fmt.Errorf("invalid cursor value for %s: %v", o.Key, err)
+		}
+		res = append(res, mut)
+	}
+	return res, nil
+}
+
+// after returns the ordered results res and subject values org that follow the cursor of job j.
+func after(j *Job, res, org lit.Vals) (_, _ lit.Vals, err error) {
+	sel := make(lit.Vals, 0, len(res))
+	subj := make(lit.Vals, 0, len(org))
+	for i := range res {
+		ok, err := isAfter(j, res[i], org[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			sel = append(sel, res[i])
+			subj = append(subj, org[i])
+		}
+	}
+	return sel, subj, nil
+}
+
+// isAfter returns whether the ordered result sel with subject subj follows the cursor of job j.
+// Optional ord fields are rejected with aft cursors, so the values are never null.
+func isAfter(j *Job, sel, subj lit.Val) (bool, error) {
+	for i, o := range j.Ord {
+		v, err := ordVal(o, sel, subj)
+		if err != nil {
+			return false, err
+		}
+		cmp, err := lit.Compare(v, j.Aft[i])
+		if err != nil {
+			return false, err
+		}
+		if cmp != 0 {
+			return (cmp > 0) != o.Desc, nil
+		}
+	}
+	return false, nil
+}
+
+func ordVal(o Ord, sel, subj lit.Val) (lit.Val, error) {
+	if o.Subj {
+		return lit.Select(subj, o.Key)
+	}
+	return lit.Select(sel, o.Key)
+}
@@ -0,0 +1,84 @@
+package qry_test
+
+import (
+	"strings"
+	"testing"
+
+	. "xelf.org/daql/qry"
+
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+func TestCursor(t *testing.T) {
+	reg := lit.NewRegs()
+	b := getBackend(reg).(*MemBackend)
+	page := func(raw, aft string) (string, string, error) {
+		doc := NewDoc(extlib.Std, b)
+		param := lit.MakeObj(lit.Keyed{{Key: "aft", Val: lit.Str(aft)}})
+		el, err := exp.NewProg(doc, reg).RunStr(raw, param)
+		if err != nil {
+			return "", "", err
+		}
+		return bfr.String(el), doc.Root[0].Next, nil
+	}
+	tests := []struct {
+		raw   string
+		pages []string
+	}{
+		{`(*prod.cat asc:name lim:3 aft:$aft _:id)`,
+			[]string{`[1 2 3]`, `[4 24 25]`, `[26]`}},
+		{`(*prod.prod desc:cat asc:id lim:2 aft:$aft _ id;)`, []string{
+			`[{id:1} {id:3}]`, `[{id:2} {id:4}]`, `[{id:25} {id:26}]`, `[]`,
+		}},
+		{`(*prod.cat (gt .id 2) desc:id lim:4 aft:$aft _:name)`,
+			[]string{`['z' 'y' 'x' 'd']`, `['c']`}},
+	}
+	for _, test := range tests {
+		var got []string
+		var aft string
+		for len(got) < 10 {
+			res, next, err := page(test.raw, aft)
+			if err != nil {
+				t.Fatalf("qry %s aft %q failed: %v", test.raw, aft, err)
+			}
+			got = append(got, res)
+			if aft = next; aft == "" {
+				break
+			}
+		}
+		if g, w := strings.Join(got, " | "), strings.Join(test.pages, " | "); g != w {
+			t.Errorf("want pages for %s\n\t%s got %s", test.raw, w, g)
+		}
+	}
+	// the next page is stable while values are inserted before the cursor
+	raw := `(*prod.cat asc:name lim:3 aft:$aft _:id)`
+	_, next, err := page(raw, "")
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	m := b.Project.Model("prod.cat")
+	v := reg.Zero(m.Type())
+	v.(lit.Keyr).SetKey("id", lit.Int(5))
+	v.(lit.Keyr).SetKey("name", lit.Str("aa"))
+	if err := b.Insert(m.Qualified(), m, 0, v); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	got, _, err := page(raw, next)
+	if err != nil || got != `[4 24 25]` {
+		t.Errorf("want next page [4 24 25] got %s %v", got, err)
+	}
+	if _, _, err := page(raw, "!"); err == nil || !strings.Contains(err.Error(), "invalid cursor") {
+		t.Errorf("want invalid cursor error got %v", err)
+	}
+	// optional ord fields have no cursor, because nulls cannot be compared
+	opt := `(*prod.cat asc:p lim:2 aft:$aft _ id; p:(?prod.prod (eq .cat ..id) _:name))`
+	if _, next, err := page(opt, ""); err != nil || next != "" {
+		t.Errorf("want no cursor for optional ord got %q %v", next, err)
+	}
+	if _, _, err := page(opt, "x"); err == nil || !strings.Contains(err.Error(), "not optional") {
+		t.Errorf("want optional ord error got %v", err)
+	}
+}
@@ -13,6 +13,9 @@ type DomBackend struct {
 }
 
 func (b *DomBackend) Proj() *dom.Project { return b.Project }
+
+// SetProj replaces the project of the backend, usually with a reloaded version.
+func (b *DomBackend) SetProj(pr *dom.Project) { b.Project = pr }
 func (b *DomBackend) Exec(p *exp.Prog, j *Job) (*exp.Lit, error) {
 	var vals lit.Vals
 	switch j.Ref {
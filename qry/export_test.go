@@ -0,0 +1,9 @@
+package qry
+
+// SetMaxBatchKeys sets the maximum number of parent keys per batch job and returns a function
+// that restores the previous value.
+func SetMaxBatchKeys(n int) func() {
+	old := maxBatchKeys
+	maxBatchKeys = n
+	return func() { maxBatchKeys = old }
+}
@@ -0,0 +1,152 @@
+package qry
+
+import (
+	"fmt"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+)
+
+// groupRows returns the aggregated selection of job j for each group of the subject values vals
+// and the first subject value of each group, that is used to order by subject fields.
+func groupRows(p *exp.Prog, j *Job, vals lit.Vals) (res, org lit.Vals, _ error) {
+	gs, err := groupVals(j, vals)
+	if err != nil {
+		return nil, nil, err
+	}
+	res = make(lit.Vals, 0, len(gs))
+	org = make(lit.Vals, 0, len(gs))
+	for _, g := range gs {
+		px := p.Reg.Zero(j.Sel.Type)
+		z, ok := px.(lit.Keyr)
+		for _, f := range j.Sel.Fields {
+			var val lit.Val
+			var err error
+			if f.Agg != "" {
+				val, err = aggregate(p, j, f, g)
+			} else if len(g) > 0 {
+				val, err = selectField(p, j, f, g[0])
+			} else {
+				val = p.Reg.Zero(f.Type)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				err = z.SetKey(f.Key, val)
+			} else {
+				err = px.Assign(val)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		res = append(res, px)
+		if len(g) > 0 {
+			org = append(org, g[0])
+		} else {
+			org = append(org, p.Reg.Zero(j.Subj.Type))
+		}
+	}
+	return res, org, nil
+}
+
+// groupVals returns the subject values vals grouped by the group keys of job j in order of first
+// appearance. Without group keys it returns all values as one group, even if vals is empty.
+func groupVals(j *Job, vals lit.Vals) ([]lit.Vals, error) {
+	if len(j.Grp) == 0 {
+		return []lit.Vals{vals}, nil
+	}
+	var res []lit.Vals
+	idx := make(map[string]int)
+	row := make([]lit.Val, len(j.Grp))
+	for _, v := range vals {
+		for i, key := range j.Grp {
+			kv, err := lit.Select(v, key)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", j.Ref, err)
+			}
+			row[i] = kv
+		}
+		k := hashKey(row)
+		i, ok := idx[k]
+		if !ok {
+			i = len(res)
+			idx[k] = i
+			res = append(res, nil)
+		}
+		res[i] = append(res[i], v)
+	}
+	return res, nil
+}
+
+// aggregate returns the result of the aggregate field f for the subject values vals. Null values
+// are ignored. The average, minimum and maximum of no values are null.
+func aggregate(p *exp.Prog, j *Job, f *Field, vals lit.Vals) (lit.Val, error) {
+	if f.Exp == nil {
+		return lit.Int(len(vals)), nil
+	}
+	var n, isum int64
+	var rsum float64
+	var best lit.Val
+	for _, v := range vals {
+		j.Cur = v
+		x, err := p.Eval(j, f.Exp)
+		if err != nil {
+			return nil, err
+		}
+		if x == nil || x.Nil() {
+			continue
+		}
+		n++
+		switch f.Agg {
+		case "sum", "avg":
+			if f.Type.Kind&knd.Data == knd.Int {
+				i, err := lit.ToInt(x)
+				if err != nil {
+					return nil, err
+				}
+				isum += int64(i)
+			} else {
+				r, err := lit.ToReal(x)
+				if err != nil {
+					return nil, err
+				}
+				rsum += float64(r)
+			}
+		case "min", "max":
+			if best == nil {
+				best = x
+				continue
+			}
+			cmp, err := lit.Compare(x, best)
+			if err != nil {
+				return nil, err
+			}
+			if f.Agg == "min" && cmp < 0 || f.Agg == "max" && cmp > 0 {
+				best = x
+			}
+		}
+	}
+	switch f.Agg {
+	case "count":
+		return lit.Int(n), nil
+	case "sum":
+		if f.Type.Kind&knd.Data == knd.Int {
+			return lit.Int(isum), nil
+		}
+		return lit.Real(rsum), nil
+	case "avg":
+		if n > 0 {
+			return lit.Real(rsum / float64(n)), nil
+		}
+	case "min", "max":
+		if best != nil {
+			return best, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregate %s for field %s", f.Agg, f.Key)
+	}
+	return p.Reg.Zero(f.Type), nil
+}
@@ -0,0 +1,72 @@
+package qry_test
+
+import (
+	"strings"
+	"testing"
+
+	. "xelf.org/daql/qry"
+
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+func TestGroup(t *testing.T) {
+	reg := lit.NewRegs()
+	b := getBackend(reg)
+	tests := []struct {
+		Raw  string
+		Want string
+	}{
+		{`(?prod.prod _ n:(count) s:(sum .id))`, `{n:6 s:61}`},
+		{`(?prod.prod (le .id 4) _:(avg .id))`, `2.5`},
+		{`(?prod.prod (gt .id 100) _ n:(count) a:(avg .id) m:(max .name))`,
+			`{n:0 a:null m:null}`},
+		{`(*prod.prod grp:cat asc:cat _ cat; n:(count) min:(min .name) max:(max .id))`,
+			`[{cat:1 n:2 min:'Y' max:26} {cat:2 n:2 min:'B' max:4} {cat:3 n:2 min:'A' max:3}]`},
+		{`(*prod.prod grp:cat desc:s _ cat; s:(sum .id))`,
+			`[{cat:1 s:51} {cat:2 s:6} {cat:3 s:4}]`},
+		{`(*prod.prod (eq .cat 1) grp:cat grp:name asc:name _ cat; name; n:(count))`,
+			`[{cat:1 name:'Y' n:1} {cat:1 name:'Z' n:1}]`},
+		{`(*prod.prod grp:cat asc:cat off:1 lim:1 _:cat)`, `[2]`},
+		{`(#prod.prod grp:cat)`, `3`},
+		{`(#prod.prod (lt .id 3) grp:cat)`, `2`},
+		{`(?$list _:(count))`, `3`},
+		{`(*dom.model (eq .schema 'prod') grp:schema _ schema; n:(count))`,
+			`[{schema:'prod' n:3}]`},
+	}
+	param := lit.MakeObj(lit.Keyed{
+		{Key: "list", Val: lit.NewList(typ.Str,
+			lit.Str("a"),
+			lit.Str("c"),
+			lit.Str("b"),
+		)},
+	})
+	for _, test := range tests {
+		el, err := exp.NewProg(NewDoc(extlib.Std, b), reg).RunStr(test.Raw, param)
+		if err != nil {
+			t.Errorf("qry %s failed: %v", test.Raw, err)
+			continue
+		}
+		if got := bfr.String(el); got != test.Want {
+			t.Errorf("want for %s\n\t%s got %s", test.Raw, test.Want, got)
+		}
+	}
+	errs := []struct {
+		Raw  string
+		Want string
+	}{
+		{`(*prod.prod grp:cat _ cat; name;)`, "neither grouped nor aggregated"},
+		{`(*prod.prod grp:cat _ cat; n:(cat .name '!'))`, "uses field name"},
+		{`(*prod.prod grp:foo)`, "field foo not found"},
+		{`(?prod.prod _:(sum .name))`, "expects number"},
+	}
+	for _, test := range errs {
+		_, err := exp.NewProg(NewDoc(extlib.Std, b), reg).RunStr(test.Raw, nil)
+		if err == nil || !strings.Contains(err.Error(), test.Want) {
+			t.Errorf("qry %s want error %q got %v", test.Raw, test.Want, err)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package qry
+
+import (
+	"sort"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// memIndex is a hash and sorted index over the key fields of a model data list. All positions
+// refer to the data list and must be adjusted whenever values are inserted or deleted.
+type memIndex struct {
+	keys []string
+	pk   bool
+	// exact indicates that all key values can be matched by their string representation
+	exact bool
+	// opt indicates that a key field is optional and may be null
+	opt  bool
+	rows [][]lit.Val      // key values by position
+	hash map[string][]int // ascending positions by joined key
+	ord  []int            // positions sorted by key values and then position
+}
+
+func newMemIndex(d idxDef, vals lit.Vals) (*memIndex, error) {
+	x := &memIndex{keys: d.keys, pk: d.pk, exact: d.exact, opt: d.opt,
+		rows: make([][]lit.Val, 0, len(vals)),
+		hash: make(map[string][]int, len(vals)),
+		ord:  make([]int, len(vals)),
+	}
+	for i, v := range vals {
+		row, err := x.row(v)
+		if err != nil {
+			return nil, err
+		}
+		x.rows = append(x.rows, row)
+		k := hashKey(row)
+		x.hash[k] = append(x.hash[k], i)
+		x.ord[i] = i
+	}
+	sort.SliceStable(x.ord, func(a, b int) bool {
+		return cmpRows(x.rows[x.ord[a]], x.rows[x.ord[b]]) < 0
+	})
+	return x, nil
+}
+
+// row returns the key values of v.
+func (x *memIndex) row(v lit.Val) ([]lit.Val, error) {
+	row := make([]lit.Val, 0, len(x.keys))
+	for _, k := range x.keys {
+		kv, err := lit.Select(v, k)
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, kv)
+	}
+	return row, nil
+}
+
+// cmp compares the entries at position a and b by key values and then position.
+func (x *memIndex) cmp(a, b int) int {
+	if c := cmpRows(x.rows[a], x.rows[b]); c != 0 {
+		return c
+	}
+	return a - b
+}
+
+// insert adds the new value at position i of vals and moves all following positions.
+func (x *memIndex) insert(vals lit.Vals, i int) error {
+	row, err := x.row(vals[i])
+	if err != nil {
+		return err
+	}
+	x.rows = append(x.rows, nil)
+	copy(x.rows[i+1:], x.rows[i:])
+	x.rows[i] = row
+	x.shift(i, 1)
+	x.add(i)
+	return nil
+}
+
+// remove removes position i and moves all following positions.
+func (x *memIndex) remove(i int) {
+	x.drop(i)
+	x.rows = append(x.rows[:i], x.rows[i+1:]...)
+	x.shift(i+1, -1)
+}
+
+// reset updates the key values of the modified value at position i after a drop.
+func (x *memIndex) reset(vals lit.Vals, i int) error {
+	row, err := x.row(vals[i])
+	if err != nil {
+		return err
+	}
+	x.rows[i] = row
+	x.add(i)
+	return nil
+}
+
+func (x *memIndex) add(i int) {
+	k := hashKey(x.rows[i])
+	ps := x.hash[k]
+	n := sort.SearchInts(ps, i)
+	ps = append(ps, 0)
+	copy(ps[n+1:], ps[n:])
+	ps[n] = i
+	x.hash[k] = ps
+	n = sort.Search(len(x.ord), func(a int) bool { return x.cmp(x.ord[a], i) > 0 })
+	x.ord = append(x.ord, 0)
+	copy(x.ord[n+1:], x.ord[n:])
+	x.ord[n] = i
+}
+
+func (x *memIndex) drop(i int) {
+	k := hashKey(x.rows[i])
+	ps := x.hash[k]
+	if n := sort.SearchInts(ps, i); n < len(ps) && ps[n] == i {
+		ps = append(ps[:n], ps[n+1:]...)
+	}
+	if len(ps) == 0 {
+		delete(x.hash, k)
+	} else {
+		x.hash[k] = ps
+	}
+	n := sort.Search(len(x.ord), func(a int) bool { return x.cmp(x.ord[a], i) >= 0 })
+	if n < len(x.ord) && x.ord[n] == i {
+		x.ord = append(x.ord[:n], x.ord[n+1:]...)
+	}
+}
+
+// shift moves all positions starting at from by d.
+func (x *memIndex) shift(from, d int) {
+	for _, ps := range x.hash {
+		for n := sort.SearchInts(ps, from); n < len(ps); n++ {
+			ps[n] += d
+		}
+	}
+	for n, p := range x.ord {
+		if p >= from {
+			x.ord[n] = p + d
+		}
+	}
+}
+
+type bound struct {
+	val  lit.Val
+	incl bool
+}
+
+// rng returns the ascending positions with a first key value within lo and hi.
+func (x *memIndex) rng(lo, hi *bound) []int {
+	start, end := 0, len(x.ord)
+	if lo != nil {
+		start = sort.Search(len(x.ord), func(a int) bool {
+			c := cmpVal(x.rows[x.ord[a]][0], lo.val)
+			return c > 0 || c == 0 && lo.incl
+		})
+	}
+	if hi != nil {
+		end = sort.Search(len(x.ord), func(a int) bool {
+			c := cmpVal(x.rows[x.ord[a]][0], hi.val)
+			return c > 0 || c == 0 && !hi.incl
+		})
+	}
+	if start >= end {
+		return nil
+	}
+	res := append([]int(nil), x.ord[start:end]...)
+	sort.Ints(res)
+	return res
+}
+
+// sorted returns all positions in key order. Equal keys are kept in position order even when
+// sorted in descending order, like a stable sort of the data list would.
+func (x *memIndex) sorted(desc bool) []int {
+	if !desc {
+		return x.ord
+	}
+	res := make([]int, 0, len(x.ord))
+	for end := len(x.ord); end > 0; {
+		start := end - 1
+		for start > 0 && cmpRows(x.rows[x.ord[start-1]], x.rows[x.ord[end-1]]) == 0 {
+			start--
+		}
+		res = append(res, x.ord[start:end]...)
+		end = start
+	}
+	return res
+}
+
+func hashKey(row []lit.Val) string {
+	parts := make([]string, 0, len(row))
+	for _, v := range row {
+		parts = append(parts, v.String())
+	}
+	return dom.JoinKey(parts...)
+}
+
+func cmpRows(a, b []lit.Val) int {
+	for i := range a {
+		if c := cmpVal(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// cmpVal compares a and b and sorts null values first.
+func cmpVal(a, b lit.Val) int {
+	an, bn := a == nil || a.Nil(), b == nil || b.Nil()
+	switch {
+	case an && bn:
+		return 0
+	case an:
+		return -1
+	case bn:
+		return 1
+	}
+	c, err := lit.Compare(a, b)
+	if err != nil {
+		return 0
+	}
+	return c
+}
+
+// listIdx holds the indexes of one data list and the list state they were built for.
+type listIdx struct {
+	list *lit.List
+	n    int
+	idxs []*memIndex
+}
+
+type idxDef struct {
+	keys  []string
+	pk    bool
+	exact bool
+	opt   bool
+}
+
+// indexDefs returns the index definitions for the primary key, uniq and idx elems and the model
+// indices of m. Indices with computed or unknown elems are skipped.
+func indexDefs(m *dom.Model) (res []idxDef) {
+	add := func(pk bool, keys ...string) {
+		d := idxDef{keys: make([]string, 0, len(keys)), pk: pk, exact: true}
+		for _, k := range keys {
+			k = strings.ToLower(k)
+			el := storedElem(m, k)
+			if el == nil {
+				return
+			}
+			d.keys = append(d.keys, k)
+			d.exact = d.exact && exactType(el.Type)
+			d.opt = d.opt || el.Bits&dom.BitOpt != 0 || el.Type.Kind&knd.None != 0
+		}
+		if len(d.keys) == 0 {
+			return
+		}
+		for _, o := range res {
+			if strings.Join(o.keys, ",") == strings.Join(d.keys, ",") {
+				return
+			}
+		}
+		res = append(res, d)
+	}
+	if pks := m.PK(); len(pks) > 0 {
+		add(true, elemKeys(pks)...)
+	}
+	for _, el := range m.Elems {
+		if el.Bits&(dom.BitUniq|dom.BitIdx) != 0 {
+			add(false, elemKeys([]*dom.Elem{el})...)
+		}
+	}
+	if m.Object != nil {
+		for _, idx := range m.Object.Indices {
+			add(false, idx.Keys...)
+		}
+	}
+	return res
+}
+
+func elemKeys(els []*dom.Elem) []string {
+	res := make([]string, 0, len(els))
+	for _, el := range els {
+		res = append(res, strings.TrimSuffix(el.Key(), "?"))
+	}
+	return res
+}
+
+func storedElem(m *dom.Model, key string) *dom.Elem {
+	for _, el := range m.Elems {
+		if el.Name != "" && strings.TrimSuffix(el.Key(), "?") == key {
+			if el.CalcSrc() != "" {
+				return nil
+			}
+			return el
+		}
+	}
+	return nil
+}
+
+// exactType returns whether equal values of type t always have the same string representation.
+func exactType(t typ.Type) bool {
+	switch t.Kind & knd.Data {
+	case knd.Bool, knd.Int, knd.Bits, knd.Str, knd.Char, knd.Enum, knd.UUID:
+		return true
+	}
+	return false
+}
+
+// term is a simple whr comparison of a subject field with a value or list of values.
+type term struct {
+	key  string
+	op   string
+	val  lit.Val
+	vals []lit.Val
+}
+
+var flipOps = map[string]string{"eq": "eq", "lt": "gt", "le": "ge", "gt": "lt", "ge": "le"}
+
+// whrTerms returns the top level whr comparisons of job j between a subject field and an
+// expression without field references. The values are converted to the field type.
+func whrTerms(p *exp.Prog, j *Job) (res []term) {
+	for _, w := range j.Whr {
+		c, ok := w.(*exp.Call)
+		if !ok || c.Spec == nil || c.Sig.Ref == "" {
+			continue
+		}
+		op := c.Sig.Ref
+		if _, ok := flipOps[op]; !ok && op != "in" {
+			continue
+		}
+		args := flatArgs(c.Args)
+		if len(args) != 2 {
+			continue
+		}
+		key, ok := fieldKey(args[0])
+		other := args[1]
+		if !ok && op != "in" {
+			key, ok = fieldKey(args[1])
+			other, op = args[0], flipOps[op]
+		}
+		if !ok || hasField(other) {
+			continue
+		}
+		f := j.Subj.Field(key)
+		if f == nil {
+			continue
+		}
+		v, err := p.Eval(j, other)
+		if err != nil {
+			continue
+		}
+		if op == "in" {
+			if vals, ok := inVals(p, f.Type, v); ok {
+				res = append(res, term{key: key, op: op, vals: vals})
+			}
+			continue
+		}
+		mut := p.Reg.Zero(f.Type)
+		if err = mut.Assign(v); err != nil {
+			continue
+		}
+		res = append(res, term{key: key, op: op, val: mut})
+	}
+	return res
+}
+
+// inVals returns the elements of list v converted to type t.
+func inVals(p *exp.Prog, t typ.Type, v lit.Val) (res []lit.Val, _ bool) {
+	idx, ok := v.Value().(lit.Idxr)
+	if !ok {
+		return nil, false
+	}
+	err := idx.IterIdx(func(i int, el lit.Val) error {
+		mut := p.Reg.Zero(t)
+		if err := mut.Assign(el); err != nil {
+			return err
+		}
+		res = append(res, mut)
+		return nil
+	})
+	return res, err == nil
+}
+
+// plan returns the candidate values of job j using the first matching index and whether they
+// already are in job order. Candidates filtered by whr terms are a superset of the matching
+// values and in list order, so that the result does not depend on the index use.
+func plan(p *exp.Prog, j *Job, idxs []*memIndex, vals lit.Vals) (lit.Vals, bool) {
+	if len(idxs) == 0 {
+		return vals, false
+	}
+	if len(j.Whr) > 0 {
+		if ps, ok := planWhr(idxs, whrTerms(p, j)); ok {
+			return pick(vals, ps), false
+		}
+	}
+	if ps, ok := planOrd(j, idxs); ok {
+		return pick(vals, ps), true
+	}
+	return vals, false
+}
+
+func planWhr(idxs []*memIndex, terms []term) ([]int, bool) {
+	if len(terms) == 0 {
+		return nil, false
+	}
+	eqs := make(map[string]lit.Val, len(terms))
+	for _, t := range terms {
+		if _, ok := eqs[t.key]; !ok && t.op == "eq" {
+			eqs[t.key] = t.val
+		}
+	}
+	for _, x := range idxs {
+		if !x.exact || len(x.keys) != 1 {
+			continue
+		}
+		for _, t := range terms {
+			if t.op == "in" && t.key == x.keys[0] {
+				var res []int
+				seen := make(map[string]bool, len(t.vals))
+				for _, v := range t.vals {
+					k := hashKey([]lit.Val{v})
+					if seen[k] {
+						continue
+					}
+					seen[k] = true
+					res = append(res, x.hash[k]...)
+				}
+				sort.Ints(res)
+				return res, true
+			}
+		}
+	}
+	for _, x := range idxs {
+		if !x.exact {
+			continue
+		}
+		row := make([]lit.Val, 0, len(x.keys))
+		for _, k := range x.keys {
+			if v := eqs[k]; v != nil {
+				row = append(row, v)
+			}
+		}
+		if len(row) == len(x.keys) {
+			return x.hash[hashKey(row)], true
+		}
+	}
+	for _, x := range idxs {
+		if !x.exact {
+			continue
+		}
+		var lo, hi *bound
+		for _, t := range terms {
+			if t.key != x.keys[0] {
+				continue
+			}
+			b := &bound{val: t.val, incl: t.op != "lt" && t.op != "gt"}
+			switch t.op {
+			case "eq":
+				lo, hi = b, b
+			case "gt", "ge":
+				if lo == nil {
+					lo = b
+				}
+			case "lt", "le":
+				if hi == nil {
+					hi = b
+				}
+			}
+		}
+		if lo != nil || hi != nil {
+			return x.rng(lo, hi), true
+		}
+	}
+	return nil, false
+}
+
+// planOrd returns positions in job order if the order keys of j match all keys of an index in
+// one direction. Indexes with optional keys are not used, because nulls may sort differently.
+func planOrd(j *Job, idxs []*memIndex) ([]int, bool) {
+	if len(j.Ord) == 0 {
+		return nil, false
+	}
+	desc := j.Ord[0].Desc
+Next:
+	for _, x := range idxs {
+		if x.opt || len(x.keys) != len(j.Ord) {
+			continue
+		}
+		for i, o := range j.Ord {
+			key := strings.ToLower(o.Key)
+			if o.Desc != desc || key != x.keys[i] {
+				continue Next
+			}
+			if !o.Subj {
+				if f := j.Sel.Field(key); f == nil || f.Exp != nil {
+					continue Next
+				}
+			}
+		}
+		return x.sorted(desc), true
+	}
+	return nil, false
+}
+
+func pick(vals lit.Vals, ps []int) lit.Vals {
+	res := make(lit.Vals, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, vals[p])
+	}
+	return res
+}
+
+// fieldKey returns the key of a simple subject field reference like '.name'.
+func fieldKey(x exp.Exp) (string, bool) {
+	s, ok := x.(*exp.Sym)
+	if !ok || len(s.Sym) < 2 || s.Sym[0] != '.' || s.Sym[1] == '.' ||
+		strings.ContainsAny(s.Sym[1:], "./") {
+		return "", false
+	}
+	return strings.ToLower(s.Sym[1:]), true
+}
+
+// hasField returns whether x contains subject field references.
+func hasField(x exp.Exp) bool {
+	switch v := x.(type) {
+	case *exp.Sym:
+		return len(v.Sym) > 1 && v.Sym[0] == '.' && v.Sym[1] != '.'
+	case *exp.Call:
+		for _, arg := range v.Args {
+			if hasField(arg) {
+				return true
+			}
+		}
+	case *exp.Tupl:
+		for _, el := range v.Els {
+			if hasField(el) {
+				return true
+			}
+		}
+	}
+	return false
+}
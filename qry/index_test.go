@@ -0,0 +1,71 @@
+package qry_test
+
+import (
+	"testing"
+
+	. "xelf.org/daql/qry"
+
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+func TestMemIndex(t *testing.T) {
+	reg := lit.NewRegs()
+	b := getBackend(reg).(*MemBackend)
+	m := b.Project.Model("prod.cat")
+	key := m.Qualified()
+	check := func(name string, tests [][2]string) {
+		for _, test := range tests {
+			el, err := exp.NewProg(NewDoc(extlib.Std, b)).RunStr(test[0], nil)
+			if err != nil {
+				t.Errorf("%s qry %s failed: %v", name, test[0], err)
+				continue
+			}
+			if got := bfr.String(el); got != test[1] {
+				t.Errorf("%s want for %s\n\t%s got %s", name, test[0], test[1], got)
+			}
+		}
+	}
+	check("initial", [][2]string{
+		{`(*prod.cat (gt .id 3) _:id)`, `[25 4 26 24]`},
+		{`(*prod.cat (le 3 .id) (lt .id 25) _:id)`, `[3 4 24]`},
+		{`(*prod.cat asc:id _:id)`, `[1 2 3 4 24 25 26]`},
+		{`(*prod.cat desc:id lim:2 _:id)`, `[26 25]`},
+		{`(?prod.cat (eq .id 4) _:name)`, `'d'`},
+		{`(#prod.cat (eq .id 2))`, `1`},
+		{`(*prod.cat (in .id [4 3 4]) _:id)`, `[3 4]`},
+	})
+	if idx, err := b.Find(key, m, "2"); err != nil || idx != 1 {
+		t.Fatalf("find 2 want 1 got %d %v", idx, err)
+	}
+	if _, err := b.Delete(key, m, 1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	v := reg.Zero(m.Type())
+	v.(lit.Keyr).SetKey("id", lit.Int(5))
+	v.(lit.Keyr).SetKey("name", lit.Str("e"))
+	if err := b.Insert(key, m, 0, v); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	err := b.Update(key, m, 1, func(v lit.Val) error {
+		return v.(lit.Keyr).SetKey("id", lit.Int(7))
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	check("modified", [][2]string{
+		{`(*prod.cat (gt .id 3) _:id)`, `[5 7 4 26 24]`},
+		{`(*prod.cat (le 3 .id) (lt .id 25) _:id)`, `[5 7 3 4 24]`},
+		{`(*prod.cat asc:id _:id)`, `[1 3 4 5 7 24 26]`},
+		{`(*prod.cat desc:id lim:2 _:id)`, `[26 24]`},
+		{`(?prod.cat (eq .id 7) _:name)`, `'y'`},
+		{`(#prod.cat (eq .id 2))`, `0`},
+	})
+	for pk, want := range map[string]int{"5": 0, "7": 1, "24": 6, "25": -1} {
+		if idx, err := b.Find(key, m, pk); err != nil || idx != want {
+			t.Errorf("find %s want %d got %d %v", pk, want, idx, err)
+		}
+	}
+}
@@ -44,6 +44,25 @@ type Task struct {
 	Lim int64
 	Off int64
 	Ord []Ord
+	Grp []string
+	Aft lit.Vals
+}
+
+// Grouped returns whether the task groups the subject or selects aggregate fields. Grouped tasks
+// select one result for each group or one result for all subject values if no group is given.
+// Count tasks ignore the selection and count the groups.
+func (t *Task) Grouped() bool {
+	if len(t.Grp) > 0 {
+		return true
+	}
+	if t.Sel != nil {
+		for _, f := range t.Sel.Fields {
+			if f.Agg != "" {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (t *Task) Field(k string) (*Field, error) {
@@ -58,12 +77,14 @@ func (t *Task) Field(k string) (*Field, error) {
 }
 
 // Job is the environment for a query spec and holds the task and its eventual result.
+// Next holds the cursor for the next page of paged tasks if the last result was a full page.
 type Job struct {
 	*Doc
 	Env exp.Env
 	*Task
-	Val *exp.Lit
-	Cur lit.Val
+	Val  *exp.Lit
+	Cur  lit.Val
+	Next string
 }
 
 // FindJob returns a job environment that is env or one of its ancestors.
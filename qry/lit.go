@@ -3,6 +3,9 @@ package qry
 import (
 	"fmt"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"xelf.org/daql/dom"
 	"xelf.org/daql/mig"
@@ -40,18 +43,32 @@ func (LitBackend) Exec(p *exp.Prog, j *Job) (*exp.Lit, error) {
 }
 
 // MemBackend is a query backend that evaluates queries using in-memory literal values.
+//
+// The backend maintains hash and sorted indexes for primary keys, uniq and idx elems and model
+// indices. Indexes are built on first use and used to select candidates for simple whr
+// comparisons and to avoid sorting for matching ord clauses. Data lists should be modified using
+// Insert, Delete and Update to keep the indexes consistent. Lists that were replaced or resized
+// otherwise are reindexed on next use. Mutating or replacing list values in place is not
+// supported, because it is not detected and leaves the indexes stale.
 type MemBackend struct {
 	*dom.Project
 	*mig.Version
+	// Data holds the model lists by qualified key. Use Insert, Delete and Update to modify them.
 	Data map[string]*lit.List
+	idx  map[string]*listIdx
+	mu   sync.Mutex // guards the project and index map
 }
 
 // NewMemBackend returns a new memory backend for the given project.
 func NewMemBackend(pr *dom.Project, v *mig.Version) *MemBackend {
-	return &MemBackend{pr, v, make(map[string]*lit.List)}
+	return &MemBackend{Project: pr, Version: v, Data: make(map[string]*lit.List)}
 }
 
-func (b *MemBackend) Proj() *dom.Project { return b.Project }
+func (b *MemBackend) Proj() *dom.Project {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Project
+}
 func (b *MemBackend) Vers() *mig.Version { return b.Version }
 func (b *MemBackend) Keys() (res []string) {
 	for key := range b.Data {
@@ -60,15 +77,174 @@ func (b *MemBackend) Keys() (res []string) {
 	return res
 }
 func (b *MemBackend) Close() error { return nil }
+
+// SetProj replaces the project of the backend, usually with a reloaded version. The data is kept
+// and must be compatible with the new project. It can be called while queries are executed, but
+// like the backend itself it is not safe to call concurrently with data modifications.
+func (b *MemBackend) SetProj(pr *dom.Project) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Project, b.idx = pr, nil
+}
+
 func (b *MemBackend) Stream(key string) (mig.Stream, error) {
-	m := b.Project.Model(key)
+	m := b.Proj().Model(key)
 	if m == nil {
 		return nil, fmt.Errorf("stream %s not found", key)
 	}
-	return mig.NewLitStream(b.list(m)), nil
+	list, err := storedList(m, b.list(m))
+	if err != nil {
+		return nil, err
+	}
+	return mig.NewLitStream(list), nil
 }
 func (b *MemBackend) Exec(p *exp.Prog, j *Job) (*exp.Lit, error) {
-	return execListQry(p, j, b.list(j.Model).Vals)
+	key := j.Model.Qualified()
+	list := b.list(j.Model)
+	vals, ordered := plan(p, j, b.indexes(key, j.Model, b.Data[key]), list.Vals)
+	vals, err := calcVals(p, j, vals)
+	if err != nil {
+		return nil, err
+	}
+	return execList(p, j, vals, ordered)
+}
+
+// Batch returns true for sub jobs on models, because the backend supports batch jobs.
+func (b *MemBackend) Batch(j *Job) bool { return j.Model != nil }
+
+// Insert inserts v at position i of the data list key of model m and updates the indexes.
+func (b *MemBackend) Insert(key string, m *dom.Model, i int, v lit.Val) error {
+	if b.Data == nil {
+		b.Data = make(map[string]*lit.List)
+	}
+	list := b.Data[key]
+	if list == nil {
+		list = lit.NewList(m.Type())
+		b.Data[key] = list
+	}
+	if i < 0 || i > len(list.Vals) {
+		return fmt.Errorf("insert %s: position %d out of range", key, i)
+	}
+	idxs := b.indexes(key, m, list)
+	list.Vals = append(list.Vals, nil)
+	copy(list.Vals[i+1:], list.Vals[i:])
+	list.Vals[i] = v
+	var err error
+	for _, x := range idxs {
+		if err == nil {
+			err = x.insert(list.Vals, i)
+		}
+	}
+	b.indexed(key, list, err)
+	return nil
+}
+
+// Delete removes and returns the value at position i of the data list key of model m and updates
+// the indexes.
+func (b *MemBackend) Delete(key string, m *dom.Model, i int) (lit.Val, error) {
+	list := b.Data[key]
+	if list == nil || i < 0 || i >= len(list.Vals) {
+		return nil, fmt.Errorf("delete %s: position %d out of range", key, i)
+	}
+	for _, x := range b.indexes(key, m, list) {
+		x.remove(i)
+	}
+	v := list.Vals[i]
+	list.Vals = append(list.Vals[:i], list.Vals[i+1:]...)
+	b.indexed(key, list, nil)
+	return v, nil
+}
+
+// Update calls f to modify the value at position i of the data list key of model m in place and
+// updates the indexes, even if f returns an error.
+func (b *MemBackend) Update(key string, m *dom.Model, i int, f func(lit.Val) error) error {
+	list := b.Data[key]
+	if list == nil || i < 0 || i >= len(list.Vals) {
+		return fmt.Errorf("update %s: position %d out of range", key, i)
+	}
+	idxs := b.indexes(key, m, list)
+	for _, x := range idxs {
+		x.drop(i)
+	}
+	res := f(list.Vals[i])
+	var err error
+	for _, x := range idxs {
+		if err == nil {
+			err = x.reset(list.Vals, i)
+		}
+	}
+	b.indexed(key, list, err)
+	return res
+}
+
+// Find returns the position of the value with the canonical primary key string pk in the data list
+// key of model m or -1.
+func (b *MemBackend) Find(key string, m *dom.Model, pk string) (int, error) {
+	list := b.Data[key]
+	if list == nil {
+		return -1, fmt.Errorf("no data found")
+	}
+	for _, x := range b.indexes(key, m, list) {
+		if x.pk {
+			if ps := x.hash[pk]; len(ps) > 0 {
+				return ps[0], nil
+			}
+			return -1, nil
+		}
+	}
+	// the primary key values could not be indexed
+	pks := elemKeys(m.PK())
+	if len(pks) == 0 {
+		return -1, fmt.Errorf("no pk field for model %s", m.Qualified())
+	}
+	x := &memIndex{keys: pks}
+	for i, v := range list.Vals {
+		row, err := x.row(v)
+		if err != nil {
+			return -1, err
+		}
+		if hashKey(row) == pk {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// indexes returns the indexes of the data list key of model m and builds them if necessary.
+func (b *MemBackend) indexes(key string, m *dom.Model, list *lit.List) []*memIndex {
+	if list == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if li := b.idx[key]; li != nil && li.list == list && li.n == len(list.Vals) {
+		return li.idxs
+	}
+	var idxs []*memIndex
+	for _, d := range indexDefs(m) {
+		// values without all index keys are not indexed
+		if x, err := newMemIndex(d, list.Vals); err == nil {
+			idxs = append(idxs, x)
+		}
+	}
+	if b.idx == nil {
+		b.idx = make(map[string]*listIdx)
+	}
+	b.idx[key] = &listIdx{list: list, n: len(list.Vals), idxs: idxs}
+	return idxs
+}
+
+// indexed records the new list state after a modification or drops the indexes on error.
+func (b *MemBackend) indexed(key string, list *lit.List, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if li := b.idx[key]; li != nil {
+		if err != nil {
+			delete(b.idx, key)
+		} else {
+			li.n = len(list.Vals)
+		}
+	}
 }
 func (b *MemBackend) list(m *dom.Model) (list *lit.List) {
 	if list = b.Data[m.Qualified()]; list == nil {
@@ -77,14 +253,30 @@ func (b *MemBackend) list(m *dom.Model) (list *lit.List) {
 	return list
 }
 
-// Add converts and adds a nested list of values to this backend.
+// Add converts and adds a nested list of values to this backend. Values with one value for each
+// model elem are added as is. Shorter values, like those written by a backend stream or older
+// backups, have no computed fields and get zero placeholders for them. Missing trailing fields are
+// filled with the elem default or zero value.
 func (b *MemBackend) Add(m *dom.Model, list *lit.Vals) error {
 	if b.Data == nil {
 		b.Data = make(map[string]*lit.List)
 	}
 	mt := m.Type()
+	var defs map[string]lit.Val
+	var reg *lit.Regs
 	for i, v := range *list {
 		l := v.(*lit.Vals)
+		if len(*l) < len(m.Elems) {
+			if reg == nil {
+				var err error
+				defs, err = m.Defaults(time.Now())
+				if err != nil {
+					return err
+				}
+				reg = lit.DefaultRegs(nil)
+			}
+			*l = storedVals(m, *l, defs, reg)
+		}
 		s := &lit.Obj{Typ: mt, Vals: *l}
 		(*list)[i] = s
 	}
@@ -92,9 +284,38 @@ func (b *MemBackend) Add(m *dom.Model, list *lit.Vals) error {
 	return nil
 }
 
-var _ mig.Dataset = (*MemBackend)(nil)
+// storedVals returns the values for all elems of model m from the stored values vals without
+// computed fields. Computed and missing trailing fields are filled with defaults or zero values.
+func storedVals(m *dom.Model, vals lit.Vals, defs map[string]lit.Val, reg *lit.Regs) lit.Vals {
+	res := make(lit.Vals, 0, len(m.Elems))
+	for _, el := range m.Elems {
+		var v lit.Val
+		if el.CalcSrc() == "" {
+			if len(vals) > 0 {
+				v, vals = vals[0], vals[1:]
+			} else {
+				v = defs[strings.TrimSuffix(el.Key(), "?")]
+			}
+		}
+		if v == nil {
+			v = reg.Zero(el.Type)
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+var (
+	_ mig.Dataset = (*MemBackend)(nil)
+	_ Batcher     = (*MemBackend)(nil)
+)
 
 func execListQry(p *exp.Prog, j *Job, vals lit.Vals) (*exp.Lit, error) {
+	return execList(p, j, vals, false)
+}
+
+// execList executes job j on vals. Ordered indicates that vals already are in job order.
+func execList(p *exp.Prog, j *Job, vals lit.Vals, ordered bool) (*exp.Lit, error) {
 	var whr exp.Exp
 	if len(j.Whr) > 0 {
 		whr = &exp.Call{Args: append([]exp.Exp{exp.LitVal(exp.NewSpecRef(lib.And))}, j.Whr...)}
@@ -102,64 +323,116 @@ func execListQry(p *exp.Prog, j *Job, vals lit.Vals) (*exp.Lit, error) {
 	if j.Kind == KindCount {
 		return collectCount(p, j, vals, whr)
 	}
-	res, err := collectList(p, j, vals, whr)
+	res, err := collectList(p, j, vals, whr, ordered)
+	if err != nil {
+		return nil, err
+	}
+	v, err := listResult(j, res)
 	if err != nil {
 		return nil, err
 	}
-	var v lit.Mut
+	return exp.LitVal(v), nil
+}
+
+// listResult returns the result of job j for the selected, ordered and limited values res.
+func listResult(j *Job, res lit.Vals) (lit.Mut, error) {
 	switch j.Kind {
 	case KindOne:
 		if len(res) == 0 {
-			v = lit.ZeroWrap(j.Res)
-		} else {
-			v = lit.Wrap(res[0].Mut(), j.Res)
+			return lit.ZeroWrap(j.Res), nil
 		}
+		return lit.Wrap(res[0].Mut(), j.Res), nil
 	case KindMany:
-		v = &lit.List{Typ: j.Res, Vals: res}
-	default:
-		return nil, fmt.Errorf("exec unknown query kind %s", j.Ref)
+		return &lit.List{Typ: j.Res, Vals: res}, nil
 	}
-	return exp.LitVal(v), nil
+	return nil, fmt.Errorf("exec unknown query kind %s", j.Ref)
 }
 
-func collectList(p *exp.Prog, j *Job, vals lit.Vals, whr exp.Exp) (res lit.Vals, _ error) {
-	res = make([]lit.Val, 0, len(vals))
+func collectList(p *exp.Prog, j *Job, vals lit.Vals, whr exp.Exp, ordered bool) (lit.Vals, error) {
 	org := vals
 	if whr != nil {
 		org = make([]lit.Val, 0, len(vals))
-	}
-	for _, l := range vals {
-		j.Cur = l
-		if whr != nil {
+		for _, l := range vals {
+			j.Cur = l
 			ok, err := filter(p, j, l, whr)
 			if err != nil {
 				return nil, err
 			}
-			if !ok {
-				continue
+			if ok {
+				org = append(org, l)
 			}
-			org = append(org, l)
 		}
-		if len(j.Sel.Fields) == 0 {
-			res = append(res, l)
-			continue
+	}
+	return selectList(p, j, org, ordered)
+}
+
+// selectList returns the selected, ordered and limited results of job j for the filtered subject
+// values org and sets the next cursor of paged jobs. Ordered indicates that org already is in job
+// order.
+func selectList(p *exp.Prog, j *Job, org lit.Vals, ordered bool) (lit.Vals, error) {
+	res, org, err := selectOrg(p, j, org, ordered)
+	if err != nil {
+		return nil, err
+	}
+	j.Next = ""
+	if n := len(res); n > 0 && n == int(j.Lim) && j.Paged() {
+		if j.Next, err = Cursor(j, res[n-1], org[n-1]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// selectOrg returns the selected, ordered and limited results of job j and the subject values in
+// the same order for the filtered subject values org. Ordered indicates that org already is in job
+// order.
+func selectOrg(p *exp.Prog, j *Job, org lit.Vals, ordered bool) (res, _ lit.Vals, err error) {
+	if j.Grouped() {
+		res, org, err = groupRows(p, j, org)
+		ordered = false
+	} else {
+		res, err = SelectRows(p, j, org)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(j.Ord) != 0 && !ordered {
+		// copy the subject values to leave the order of the backend data untouched
+		org = append(make([]lit.Val, 0, len(org)), org...)
+		err := orderResult(res, org, j.Ord)
+		if err != nil {
+			return nil, nil, err
 		}
-		rec := l.(lit.Keyr)
+	}
+	if len(j.Aft) != 0 {
+		if res, org, err = after(j, res, org); err != nil {
+			return nil, nil, err
+		}
+	}
+	return limitList(j, res), limitList(j, org), nil
+}
+
+// SelectRows returns the selection of job j for each subject value in vals. Sub query fields are
+// executed as batch if the sub query backend supports it, or otherwise evaluated for each value.
+func SelectRows(p *exp.Prog, j *Job, vals lit.Vals) (lit.Vals, error) {
+	res := make([]lit.Val, 0, len(vals))
+	if len(j.Sel.Fields) == 0 {
+		return append(res, vals...), nil
+	}
+	subs, err := batchSubs(p, j, vals)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range vals {
 		px := p.Reg.Zero(j.Sel.Type)
 		z, ok := px.(lit.Keyr)
 		for _, f := range j.Sel.Fields {
 			var val lit.Val
 			var err error
-			if f.Exp != nil {
-				val, err = p.Eval(j, f.Exp)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				val, err = rec.Key(f.Key)
-				if err != nil {
-					return nil, err
-				}
+			if vs := subs[f.Key]; vs != nil {
+				val = vs[i]
+			} else if val, err = selectField(p, j, f, l); err != nil {
+				return nil, err
 			}
 			if ok {
 				err = z.SetKey(f.Key, val)
@@ -172,12 +445,24 @@ func collectList(p *exp.Prog, j *Job, vals lit.Vals, whr exp.Exp) (res lit.Vals,
 		}
 		res = append(res, px)
 	}
-	if len(j.Ord) != 0 {
-		err := orderResult(res, org, j.Ord)
-		if err != nil {
-			return nil, err
-		}
+	return res, nil
+}
+
+// selectField returns the value of the non-aggregate field f for the subject value l.
+func selectField(p *exp.Prog, j *Job, f *Field, l lit.Val) (lit.Val, error) {
+	j.Cur = l
+	if f.Exp != nil {
+		return p.Eval(j, f.Exp)
 	}
+	rec, ok := l.(lit.Keyr)
+	if !ok {
+		return nil, fmt.Errorf("select %s: expect keyer got %T", j.Ref, l)
+	}
+	return rec.Key(f.Key)
+}
+
+// limitList returns res with the offset and limit of job j applied.
+func limitList(j *Job, res lit.Vals) lit.Vals {
 	if j.Off > 0 {
 		if len(res) > int(j.Off) {
 			res = res[j.Off:]
@@ -188,14 +473,16 @@ func collectList(p *exp.Prog, j *Job, vals lit.Vals, whr exp.Exp) (res lit.Vals,
 	if j.Lim > 0 && len(res) > int(j.Lim) {
 		res = res[:j.Lim]
 	}
-	return res, nil
+	return res
 }
 
 func collectCount(p *exp.Prog, j *Job, vals lit.Vals, whr exp.Exp) (*exp.Lit, error) {
-	// we can ignore order and selection completely
+	// we can ignore order and selection completely, but not the groups
+	grp := len(j.Grp) > 0
 	var res int64
+	var org lit.Vals
 	if whr == nil {
-		res = int64(len(vals))
+		res, org = int64(len(vals)), vals
 	} else {
 		for _, l := range vals {
 			j.Cur = l
@@ -206,20 +493,35 @@ func collectCount(p *exp.Prog, j *Job, vals lit.Vals, whr exp.Exp) (*exp.Lit, er
 			if !ok {
 				continue
 			}
+			if grp {
+				org = append(org, l)
+			}
 			res++
 		}
 	}
+	if grp {
+		gs, err := groupVals(j, org)
+		if err != nil {
+			return nil, err
+		}
+		res = int64(len(gs))
+	}
+	return exp.LitVal(lit.Int(limitCount(j, res))), nil
+}
+
+// limitCount returns the count n with the offset and limit of job j applied.
+func limitCount(j *Job, n int64) int64 {
 	if j.Off > 0 {
-		if res > j.Off {
-			res -= j.Off
+		if n > j.Off {
+			n -= j.Off
 		} else {
-			res = 0
+			n = 0
 		}
 	}
-	if j.Lim > 0 && res > j.Lim {
-		res = j.Lim
+	if j.Lim > 0 && n > j.Lim {
+		n = j.Lim
 	}
-	return exp.LitVal(lit.Int(res)), nil
+	return n
 }
 
 func filter(p *exp.Prog, env exp.Env, v lit.Val, whr exp.Exp) (bool, error) {
@@ -90,5 +90,8 @@ func (q *Doc) Subject(ref string) (*Subj, error) {
 		s.Fields = subjFields(s.Type)
 		return s, nil
 	}
+	if v := pr.Query(ref); v != nil {
+		return viewSubj(q.Backend, ref, v)
+	}
 	return nil, fmt.Errorf("no subj found for %q", ref)
 }
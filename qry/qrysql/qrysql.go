@@ -0,0 +1,266 @@
+// Package qrysql provides a qry backend that compiles query jobs to parameterized sql statements
+// and executes them using database/sql.
+//
+// Jobs are compiled with the select statements of package gensql. Selections with sub queries
+// fetch the subject rows first and evaluate the selection for each row, so that sub queries can
+// refer to any field of the parent subject. Correlated sub queries are executed once for all
+// parent rows, see qry.Batcher.
+package qrysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"xelf.org/daql/dom"
+	"xelf.org/daql/gen/gensql"
+	"xelf.org/daql/qry"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Backend is a query backend that executes query jobs on a sql database.
+type Backend struct {
+	*dom.Project
+	DB      *sql.DB
+	Dialect gensql.Dialect
+	mu      sync.RWMutex // guards the project
+}
+
+// New returns a new backend for project pr using db with dialect d.
+func New(pr *dom.Project, db *sql.DB, d gensql.Dialect) *Backend {
+	return &Backend{Project: pr, DB: db, Dialect: d}
+}
+
+func (b *Backend) Proj() *dom.Project {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.Project
+}
+func (b *Backend) Close() error { return b.DB.Close() }
+
+// SetProj replaces the project of the backend, usually with a reloaded version. It can be called
+// while queries are executed.
+func (b *Backend) SetProj(pr *dom.Project) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Project = pr
+}
+
+// Batch returns true for all sub jobs on models. Batch jobs are compiled with an in clause.
+func (b *Backend) Batch(j *qry.Job) bool { return j.Model != nil }
+
+func (b *Backend) Exec(p *exp.Prog, j *qry.Job) (*exp.Lit, error) {
+	q := j
+	subs := j.Kind != qry.KindCount && (hasSubs(j.Sel.Fields) || subjOrd(j))
+	if subs {
+		if j.Grouped() {
+			return nil, fmt.Errorf("query %s: cannot group with sub queries", j.Ref)
+		}
+		var err error
+		if q, err = subjJob(j); err != nil {
+			return nil, err
+		}
+	}
+	if j.Kind != qry.KindCount && len(q.Sel.Fields) == 0 {
+		return nil, fmt.Errorf("query %s without selected fields", j.Ref)
+	}
+	stmt, args, err := gensql.Select(p, b.Dialect, q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", j.Ref, err)
+	}
+	defer rows.Close()
+	if j.Kind == qry.KindCount {
+		var n int64
+		if rows.Next() {
+			err = rows.Scan(&n)
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", j.Ref, err)
+		}
+		return exp.LitVal(lit.Int(n)), nil
+	}
+	res, err := scanRows(&p.Reg, q, rows)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", j.Ref, err)
+	}
+	org := res
+	if subs {
+		if res, err = qry.SelectRows(p, j, res); err != nil {
+			return nil, err
+		}
+	}
+	j.Next = ""
+	if n := len(res); n > 0 && n == int(j.Lim) && j.Paged() {
+		if j.Next, err = qry.Cursor(j, res[n-1], org[n-1]); err != nil {
+			return nil, err
+		}
+	}
+	var v lit.Mut
+	switch j.Kind {
+	case qry.KindOne:
+		if len(res) == 0 {
+			v = lit.ZeroWrap(j.Res)
+		} else {
+			v = lit.Wrap(res[0].Mut(), j.Res)
+		}
+	case qry.KindMany:
+		v = &lit.List{Typ: j.Res, Vals: res}
+	default:
+		return nil, fmt.Errorf("exec unknown query kind %s", j.Ref)
+	}
+	return exp.LitVal(v), nil
+}
+
+func hasSubs(fs qry.Fields) bool {
+	for _, f := range fs {
+		if f.Sub != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// subjOrd returns whether job j is paged and orders by subject fields that are not selected.
+// The subject values are then needed to create the next cursor.
+func subjOrd(j *qry.Job) bool {
+	if !j.Paged() {
+		return false
+	}
+	for _, o := range j.Ord {
+		if o.Subj {
+			return true
+		}
+	}
+	return false
+}
+
+// subjJob returns a copy of job j that selects all subject fields instead of the selection.
+func subjJob(j *qry.Job) (*qry.Job, error) {
+	for _, o := range j.Ord {
+		if !o.Subj && j.Subj.Field(o.Key) == nil {
+			return nil, fmt.Errorf("query %s: cannot order by selection %s with sub queries",
+				j.Ref, o.Key)
+		}
+	}
+	t := *j.Task
+	t.Sel = &j.Subj.Sel
+	q := *j
+	q.Task = &t
+	return &q, nil
+}
+
+// scanRows returns the selection values of job j for all rows. The columns must match the job
+// selection fields in order.
+func scanRows(reg *lit.Regs, j *qry.Job, rows *sql.Rows) (res lit.Vals, _ error) {
+	fs := j.Sel.Fields
+	dst := make([]interface{}, len(fs))
+	ptr := make([]interface{}, len(fs))
+	for i := range dst {
+		ptr[i] = &dst[i]
+	}
+	for rows.Next() {
+		err := rows.Scan(ptr...)
+		if err != nil {
+			return nil, err
+		}
+		px := reg.Zero(j.Sel.Type)
+		z, isKeyr := px.(lit.Keyr)
+		for i, f := range fs {
+			val, err := scanVal(reg, f.Type, dst[i])
+			if err != nil {
+				return nil, fmt.Errorf("scan field %s: %w", f.Key, err)
+			}
+			if isKeyr {
+				err = z.SetKey(f.Key, val)
+			} else {
+				err = px.Assign(val)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("scan field %s: %w", f.Key, err)
+			}
+		}
+		res = append(res, px)
+	}
+	return res, rows.Err()
+}
+
+// scanVal converts the driver value v to a literal of type t. Container values are expected as
+// json text and number values, like numeric aggregates, as number text. Other text values are
+// assigned as str literals and converted by the target type.
+func scanVal(reg *lit.Regs, t typ.Type, v interface{}) (lit.Val, error) {
+	mut := reg.Zero(t)
+	var val lit.Val
+	switch x := v.(type) {
+	case nil:
+		return mut, nil
+	case bool:
+		val = lit.Bool(x)
+	case int64:
+		val = lit.Int(x)
+	case float64:
+		val = lit.Real(x)
+	case time.Time:
+		val = lit.Time(x)
+	case []byte:
+		if t.Kind&knd.Data == knd.Raw {
+			val = lit.Raw(x)
+			break
+		}
+		return scanStr(mut, t, string(x))
+	case string:
+		return scanStr(mut, t, x)
+	default:
+		return nil, fmt.Errorf("unsupported driver value %T", v)
+	}
+	return mut, mut.Assign(val)
+}
+
+func scanStr(mut lit.Mut, t typ.Type, s string) (lit.Val, error) {
+	switch t.Kind & knd.Data {
+	case knd.List, knd.Dict, knd.Obj, knd.Num, knd.Int, knd.Real:
+		return mut, lit.ParseInto(s, mut)
+	}
+	return mut, mut.Assign(lit.Str(s))
+}
+
+// Provider opens sql backends for uris with the registered schemes.
+type Provider struct {
+	// Driver is the database/sql driver name used to open the database.
+	Driver  string
+	Dialect gensql.Dialect
+}
+
+// Postgres and SQLite are the registered providers. Their driver names can be changed before use.
+var (
+	Postgres = &Provider{Driver: "postgres", Dialect: gensql.Postgres}
+	SQLite   = &Provider{Driver: "sqlite3", Dialect: gensql.SQLite}
+
+	_ = qry.Backends.Register(Postgres, "postgres", "postgresql")
+	_ = qry.Backends.Register(SQLite, "sqlite")
+)
+
+// Provide opens a database for uri and returns a new backend. Postgres uris are passed to the
+// driver as is, sqlite uris are stripped of the scheme.
+func (p *Provider) Provide(uri string, pr *dom.Project) (qry.Backend, error) {
+	dsn := uri
+	if p.Dialect == gensql.SQLite {
+		dsn = strings.TrimPrefix(strings.TrimPrefix(uri, "sqlite:"), "//")
+	}
+	db, err := sql.Open(p.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s database: %w", p.Dialect.Name(), err)
+	}
+	return New(pr, db, p.Dialect), nil
+}
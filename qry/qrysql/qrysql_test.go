@@ -0,0 +1,166 @@
+package qrysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"xelf.org/daql/dom/domtest"
+	"xelf.org/daql/gen/gensql"
+	"xelf.org/daql/qry"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+func TestBackend(t *testing.T) {
+	reg := lit.NewRegs()
+	f, err := domtest.ProdFixture(reg)
+	if err != nil {
+		t.Fatalf("fixture error: %v", err)
+	}
+	db, err := sql.Open("qrysqltest", "")
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	b := New(&f.Project, db, gensql.Postgres)
+	defer b.Close()
+	tests := []struct {
+		raw  string
+		rows map[string][][]driver.Value
+		want string
+	}{
+		{`(#prod.cat)`, map[string][][]driver.Value{
+			`SELECT count(*) FROM prod.cat []`: {{int64(7)}},
+		}, `7`},
+		{`(?prod.cat (eq .name 'a'))`, map[string][][]driver.Value{
+			`SELECT id, name FROM prod.cat WHERE name = $1 LIMIT 1 [a]`: {{int64(1), "a"}},
+		}, `{id:1 name:'a'}`},
+		{`(*prod.cat lim:2 _ id;)`, map[string][][]driver.Value{
+			`SELECT id FROM prod.cat LIMIT 2 []`: {{int64(25)}, {int64(2)}},
+		}, `[{id:25} {id:2}]`},
+		{`(*prod.label asc:name off:1 lim:2 - tmpl;)`, map[string][][]driver.Value{
+			`SELECT id, name FROM prod.label ORDER BY name LIMIT 2 OFFSET 1 []`: {
+				{int64(2), []byte("N")},
+				{int64(3), []byte("O")},
+			},
+		}, `[{id:2 name:'N'} {id:3 name:'O'}]`},
+		{`(?prod.cat (eq .name 'c') +
+			prods:(*prod.prod (eq .cat ..id) asc:name _ id; name;)
+		)`, map[string][][]driver.Value{
+			`SELECT id, name FROM prod.cat WHERE name = $1 LIMIT 1 [c]`: {{int64(3), "c"}},
+			`SELECT id, name FROM prod.prod WHERE cat = $1 ORDER BY name [3]`: {
+				{int64(1), "A"},
+				{int64(3), "C"},
+			},
+		}, `{id:3 name:'c' prods:[{id:1 name:'A'} {id:3 name:'C'}]}`},
+		{`(*prod.cat (lt .id 3) asc:id _ id; n:(#prod.prod (eq .cat ..id)))`,
+			map[string][][]driver.Value{
+				`SELECT id, name FROM prod.cat WHERE id < $1 ORDER BY id [3]`: {
+					{int64(1), "a"},
+					{int64(2), "b"},
+				},
+				`SELECT id, name, cat FROM prod.prod WHERE cat IN ($1, $2) [1 2]`: {
+					{int64(25), "Y", int64(1)},
+					{int64(2), "B", int64(2)},
+					{int64(26), "Z", int64(1)},
+				},
+			}, `[{id:1 n:2} {id:2 n:1}]`},
+		{`(*prod.cat (lt .id 3) asc:id _ id; n:(#prod.prod (eq .cat ..id) (gt .id ..id)))`,
+			map[string][][]driver.Value{
+				`SELECT id, name FROM prod.cat WHERE id < $1 ORDER BY id [3]`: {
+					{int64(1), "a"},
+					{int64(2), "b"},
+				},
+				`SELECT count(*) FROM prod.prod WHERE (cat = $1) AND (id > $2) [1 1]`: {{int64(2)}},
+				`SELECT count(*) FROM prod.prod WHERE (cat = $1) AND (id > $2) [2 2]`: {{int64(1)}},
+			}, `[{id:1 n:2} {id:2 n:1}]`},
+		{`(*prod.prod (le .cat 2) grp:cat asc:cat _ cat; n:(count) a:(avg .id))`,
+			map[string][][]driver.Value{
+				`SELECT cat, count(*) AS n, avg(id) AS a FROM prod.prod` +
+					` WHERE cat <= $1 GROUP BY cat ORDER BY cat [2]`: {
+					{int64(1), int64(2), []byte("25.5")},
+					{int64(2), int64(2), []byte("3.5")},
+				},
+			}, `[{cat:1 n:2 a:25.5} {cat:2 n:2 a:3.5}]`},
+		{`(*prod.cat asc:name lim:2 aft:'WydiJ10' _:id)`, map[string][][]driver.Value{
+			`SELECT id, name FROM prod.cat WHERE name > $1 ORDER BY name LIMIT 2 [b]`: {
+				{int64(3), "c"},
+				{int64(4), "d"},
+			},
+		}, `[3 4]`},
+	}
+	for _, test := range tests {
+		fake.rows, fake.log = test.rows, nil
+		el, err := exp.NewProg(qry.NewDoc(extlib.Std, b), reg).RunStr(test.raw, nil)
+		if err != nil {
+			t.Errorf("qry %s failed: %v", test.raw, err)
+			continue
+		}
+		if got := bfr.String(el); got != test.want {
+			t.Errorf("want for %s\n\t%s got %s", test.raw, test.want, got)
+		}
+		if len(fake.log) != len(test.rows) {
+			t.Errorf("qry %s want %d statements got:\n%s", test.raw,
+				len(test.rows), strings.Join(fake.log, "\n"))
+		}
+	}
+}
+
+var fake = &fakeDB{}
+
+func init() { sql.Register("qrysqltest", fake) }
+
+// fakeDB is a database/sql driver that returns prepared rows for known statements and arguments.
+type fakeDB struct {
+	rows map[string][][]driver.Value
+	log  []string
+}
+
+func (db *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("begin not supported") }
+func (c fakeConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	key := fmt.Sprintf("%s %v", query, args)
+	rows, ok := c.db.rows[key]
+	if !ok {
+		return nil, fmt.Errorf("unexpected query %s", key)
+	}
+	c.db.log = append(c.db.log, key)
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	idx  int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	res := make([]string, len(r.rows[0]))
+	for i := range res {
+		res[i] = fmt.Sprintf("c%d", i)
+	}
+	return res
+}
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dst []driver.Value) error {
+	if r.idx >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dst, r.rows[r.idx])
+	r.idx++
+	return nil
+}
@@ -0,0 +1,103 @@
+package qry
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// ReslQueries resolves the result types of all named queries in project pro or returns an error.
+// Queries are resolved in declaration order and can use previously declared queries as subject.
+func ReslQueries(pro *dom.Project) error {
+	bend := &reslBackend{pro}
+	for _, s := range pro.Schemas {
+		for _, q := range s.Queries {
+			err := reslQuery(bend, q)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func reslQuery(bend Backend, q *dom.Query) error {
+	x, err := exp.Read(strings.NewReader(q.Src), q.Qualified())
+	if err != nil {
+		return fmt.Errorf("read query %s: %v", q.Qualified(), err)
+	}
+	doc := NewDoc(extlib.Std, bend)
+	x, err = exp.NewProg(doc).Resl(doc, x, typ.Void)
+	if err != nil {
+		return fmt.Errorf("resolve query %s: %v", q.Qualified(), err)
+	}
+	c, ok := x.(*exp.Call)
+	if ok {
+		var j *Job
+		if j, ok = c.Env.(*Job); ok {
+			q.Res = j.Res
+		}
+	}
+	if !ok {
+		return fmt.Errorf("resolve query %s: expect query call got %s", q.Qualified(), x)
+	}
+	return nil
+}
+
+// reslBackend is used to resolve named queries without data.
+type reslBackend struct{ pro *dom.Project }
+
+func (b *reslBackend) Proj() *dom.Project { return b.pro }
+func (b *reslBackend) Exec(p *exp.Prog, j *Job) (*exp.Lit, error) {
+	return nil, fmt.Errorf("cannot execute %s without data backend", j.Ref)
+}
+
+// viewBackend executes a named query with the underlying backend and uses the result as subject.
+type viewBackend struct {
+	Backend
+	Query *dom.Query
+}
+
+func (b *viewBackend) Exec(p *exp.Prog, j *Job) (*exp.Lit, error) {
+	x, err := exp.Read(strings.NewReader(b.Query.Src), b.Query.Qualified())
+	if err != nil {
+		return nil, err
+	}
+	a, err := exp.NewProg(NewDoc(extlib.Std, b.Backend), &p.Reg).Run(x, nil)
+	if err != nil {
+		return nil, fmt.Errorf("view %s: %w", b.Query.Qualified(), err)
+	}
+	var vals lit.Vals
+	switch v := a.Value().(type) {
+	case *lit.List:
+		vals = v.Vals
+	case *lit.Vals:
+		vals = *v
+	default:
+		return nil, fmt.Errorf("view %s expects list got %T", b.Query.Qualified(), a)
+	}
+	return execListQry(p, j, vals)
+}
+
+func viewSubj(bend Backend, ref string, q *dom.Query) (*Subj, error) {
+	if q.Res == typ.Void {
+		err := reslQuery(bend, q)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if q.Res.Kind&knd.List == 0 {
+		return nil, fmt.Errorf("query %s used as subject must return a list got %s",
+			q.Qualified(), q.Res)
+	}
+	s := &Subj{Ref: ref, Bend: &viewBackend{bend, q}}
+	s.Type = typ.ContEl(q.Res)
+	s.Fields = subjFields(s.Type)
+	return s, nil
+}
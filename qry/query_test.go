@@ -0,0 +1,70 @@
+package qry_test
+
+import (
+	"strings"
+	"testing"
+
+	. "xelf.org/daql/qry"
+
+	"xelf.org/daql/dom"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lib/extlib"
+	"xelf.org/xelf/lit"
+)
+
+func TestNamedQuery(t *testing.T) {
+	reg := lit.NewRegs()
+	b := getBackend(reg)
+	pro := b.Proj()
+	s := pro.Schema("prod")
+	s.Queries = append(s.Queries, &dom.Query{Name: "BigCats", Schema: "prod",
+		Src: `(*prod.cat (gt .id 3) _ id; name;)`,
+	})
+	err := ReslQueries(pro)
+	if err != nil {
+		t.Fatalf("resolve queries: %v", err)
+	}
+	if res := s.Queries[0].Res; res.Kind&knd.List == 0 {
+		t.Fatalf("want list result type got %s", res)
+	}
+	tests := []struct {
+		Raw  string
+		Want string
+	}{
+		{`(#prod.bigCats)`, `4`},
+		{`(*prod.bigCats asc:name _:name)`, `['d' 'x' 'y' 'z']`},
+		{`(?prod.bigCats (eq .id 26) _:name)`, `'z'`},
+		{`(*prod.bigcats (lt .id 25) asc:id)`, `[{id:4 name:'d'} {id:24 name:'x'}]`},
+	}
+	for _, test := range tests {
+		el, err := exp.NewProg(NewDoc(extlib.Std, b)).RunStr(test.Raw, nil)
+		if err != nil {
+			t.Errorf("qry %s failed: %v", test.Raw, err)
+			continue
+		}
+		if got := bfr.String(el); got != test.Want {
+			t.Errorf("want for %s\n\t%s got %s", test.Raw, test.Want, got)
+		}
+	}
+}
+
+func TestReadProjectQueries(t *testing.T) {
+	raw := `(project site (schema shop
+		(Cat; ID:int Name:str)
+		(query Top (*shop.cat lim:3 _ name;))
+	))`
+	pro, err := dom.ReadProject(lit.NewRegs(), strings.NewReader(raw), "site.xelf")
+	if err != nil {
+		t.Fatalf("read project: %v", err)
+	}
+	err = ReslQueries(pro)
+	if err != nil {
+		t.Fatalf("resolve queries: %v", err)
+	}
+	q := pro.Schema("shop").Queries[0]
+	if q.Res.Kind&knd.List == 0 {
+		t.Errorf("want resolved list result type got %s", q.Res)
+	}
+}
@@ -18,13 +18,15 @@ type Sel struct {
 }
 
 // Field represents a selected field that can either map to a subject field or an expression.
-// Expression fields can also contain sub queries.
+// Expression fields can also contain sub queries. Aggregate fields have the name of an aggregate
+// function and use the optional expression as argument.
 type Field struct {
 	Key  string
 	Name string
 	Type typ.Type
 	Exp  exp.Exp
 	Sub  *Job
+	Agg  string
 }
 
 // Fields is a list of fields with a convenient lookup method.
@@ -99,12 +101,18 @@ func reslSel(p *exp.Prog, j *Job, ds []*exp.Tag) (*Sel, error) {
 			} else if len(ds[i:]) > 1 {
 				return nil, fmt.Errorf("unexpected selection arguments %s", d)
 			}
-			el, err := p.Resl(j, simpleExpr(d.Exp), typ.Void)
-			if err != nil && !errors.Is(err, exp.ErrDefer) {
+			f, err := reslAgg(p, j, name, d.Exp)
+			if err != nil {
 				return nil, err
 			}
-			et := typ.Res(el.Type())
-			f := &Field{Key: cor.Keyed(name), Name: cor.Cased(name), Exp: el, Type: et}
+			if f == nil {
+				el, err := p.Resl(j, simpleExpr(d.Exp), typ.Void)
+				if err != nil && !errors.Is(err, exp.ErrDefer) {
+					return nil, err
+				}
+				et := typ.Res(el.Type())
+				f = &Field{Key: cor.Keyed(name), Name: cor.Cased(name), Exp: el, Type: et}
+			}
 			return &Sel{Type: f.Type, Fields: Fields{f}}, nil
 		case "":
 			return nil, fmt.Errorf("unnamed selection %s", d)
@@ -129,6 +137,10 @@ func reslSel(p *exp.Prog, j *Job, ds []*exp.Tag) (*Sel, error) {
 					return nil, fmt.Errorf("no param for key %s", key)
 				}
 				fs, _ = fs.with(paramField(*p))
+			} else if f, err := reslAgg(p, j, name, d.Exp); err != nil {
+				return nil, err
+			} else if f != nil {
+				fs, _ = fs.with(f)
 			} else {
 				name := cor.Cased(name)
 				el, err := p.Resl(j, d.Exp, typ.Void)
@@ -168,6 +180,56 @@ func reslSel(p *exp.Prog, j *Job, ds []*exp.Tag) (*Sel, error) {
 	return &Sel{Type: typ.Obj("", ps...), Fields: fs}, nil
 }
 
+// reslAgg returns an aggregate field for aggregate calls like (sum .price) or nil. Calls with
+// more arguments than the aggregate function accepts are not considered aggregates.
+func reslAgg(p *exp.Prog, j *Job, name string, x exp.Exp) (*Field, error) {
+	c, ok := x.(*exp.Call)
+	if !ok || c.Spec != nil || len(c.Args) == 0 {
+		return nil, nil
+	}
+	s, ok := c.Args[0].(*exp.Sym)
+	if !ok {
+		return nil, nil
+	}
+	args := flatArgs(c.Args[1:])
+	switch s.Sym {
+	case "count":
+		if len(args) > 1 {
+			return nil, nil
+		}
+	case "sum", "avg", "min", "max":
+		if len(args) != 1 {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+	f := &Field{Key: cor.Keyed(name), Name: cor.Cased(name), Agg: s.Sym, Type: typ.Int}
+	if len(args) == 0 {
+		return f, nil
+	}
+	el, err := p.Resl(j, simpleExpr(args[0]), typ.Void)
+	if err != nil {
+		return nil, err
+	}
+	f.Exp = el
+	at := typ.Deopt(typ.Res(el.Type()))
+	switch f.Agg {
+	case "sum", "avg":
+		if at.Kind&knd.Num == 0 {
+			return nil, fmt.Errorf("%s of %s expects number got %s", f.Agg, f.Key, at)
+		}
+		if f.Agg == "avg" {
+			f.Type = typ.Opt(typ.Real)
+		} else if at.Kind&knd.Data != knd.Int {
+			f.Type = typ.Real
+		}
+	case "min", "max":
+		f.Type = typ.Opt(at)
+	}
+	return f, nil
+}
+
 func simpleExpr(el exp.Exp) exp.Exp {
 	s, ok := el.(*exp.Sym)
 	if ok && cor.IsKey(s.Sym) {
@@ -2,6 +2,7 @@ package qry
 
 import (
 	"fmt"
+	"strings"
 
 	"xelf.org/xelf/cor"
 	"xelf.org/xelf/exp"
@@ -63,8 +64,9 @@ func (s *Spec) Resl(p *exp.Prog, par exp.Env, c *exp.Call, h typ.Type) (exp.Exp,
 			t.Res = typ.ListOf(t.Sel.Type)
 		}
 	}
-	t.Ord = nil
-	// resolve arguments for whr ord lim and off
+	t.Ord, t.Grp, t.Aft = nil, nil, nil
+	var aft string
+	// resolve arguments for whr ord grp aft lim and off
 	for _, tag := range tags {
 		var err error
 		switch tag.Tag {
@@ -88,6 +90,21 @@ func (s *Spec) Resl(p *exp.Prog, par exp.Env, c *exp.Call, h typ.Type) (exp.Exp,
 			// takes one or more field references
 			// can be used multiple times to append to order
 			err = evalOrd(p, par, t, tag.Tag == "desc", tag.Exp)
+		case "aft":
+			// takes a cursor string, an empty cursor selects the first page
+			el, err := p.Eval(j, tag.Exp)
+			if err != nil {
+				return c, err
+			}
+			str, err := lit.ToStr(el)
+			if err != nil {
+				return c, err
+			}
+			aft = string(str)
+		case "grp":
+			// takes one subject field reference
+			// can be used multiple times to append to group
+			err = evalGrp(t, tag.Exp)
 		default:
 			return c, fmt.Errorf("unexpected query tag %q", tag.Tag)
 		}
@@ -95,6 +112,34 @@ func (s *Spec) Resl(p *exp.Prog, par exp.Env, c *exp.Call, h typ.Type) (exp.Exp,
 			return c, err
 		}
 	}
+	if aft != "" {
+		if t.Kind == KindCount || len(t.Ord) == 0 || t.Grouped() {
+			return c, fmt.Errorf("query %s: aft cursor requires ord and no count or groups",
+				t.Ref)
+		}
+		if t.optOrd() {
+			return c, fmt.Errorf("query %s: aft cursor requires ord fields that are not optional",
+				t.Ref)
+		}
+		var err error
+		if t.Aft, err = parseCursor(p, t, aft); err != nil {
+			return c, err
+		}
+	}
+	if t.Kind != KindCount && t.Grouped() {
+		for _, f := range t.Sel.Fields {
+			if f.Agg != "" {
+				continue
+			}
+			if f.Exp == nil && !hasGrp(t.Grp, f.Key) {
+				return c, fmt.Errorf("field %s is neither grouped nor aggregated", f.Key)
+			}
+			if key, ok := ungrouped(t.Grp, f.Exp); ok {
+				return c, fmt.Errorf("field %s uses field %s that is neither grouped "+
+					"nor aggregated", f.Key, key)
+			}
+		}
+	}
 	for i, w := range whr {
 		res, err := p.Resl(j, w, typ.Bool)
 		if err != nil {
@@ -168,3 +213,55 @@ func evalOrd(p *exp.Prog, env exp.Env, t *Task, desc bool, arg exp.Exp) error {
 	t.Ord = append(t.Ord, ord)
 	return nil
 }
+
+func evalGrp(t *Task, arg exp.Exp) error {
+	sym, ok := arg.(*exp.Sym)
+	if !ok || sym.Sym == "" {
+		return fmt.Errorf("group want sym got %s", arg)
+	}
+	key := strings.ToLower(sym.Sym)
+	if t.Subj.Field(key) == nil {
+		return fmt.Errorf("field %s not found in %s", sym.Sym, t.Subj.Type)
+	}
+	t.Grp = append(t.Grp, key)
+	return nil
+}
+
+func hasGrp(grp []string, key string) bool {
+	for _, g := range grp {
+		if g == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ungrouped returns the first subject field key referenced in x that is not a group key.
+func ungrouped(grp []string, x exp.Exp) (string, bool) {
+	switch v := x.(type) {
+	case *exp.Sym:
+		if len(v.Sym) < 2 || v.Sym[0] != '.' || v.Sym[1] == '.' {
+			break
+		}
+		key := strings.ToLower(v.Sym[1:])
+		if i := strings.IndexAny(key, "./"); i >= 0 {
+			key = key[:i]
+		}
+		if !hasGrp(grp, key) {
+			return key, true
+		}
+	case *exp.Call:
+		for _, arg := range v.Args {
+			if key, ok := ungrouped(grp, arg); ok {
+				return key, true
+			}
+		}
+	case *exp.Tupl:
+		for _, el := range v.Els {
+			if key, ok := ungrouped(grp, el); ok {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
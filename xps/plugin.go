@@ -2,21 +2,29 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"xelf.org/daql"
+	"xelf.org/daql/dom"
+	"xelf.org/daql/dom/domfmt"
 	_ "xelf.org/daql/evt"
 	"xelf.org/daql/gen"
+	"xelf.org/daql/gen/gendoc"
 	"xelf.org/daql/gen/gengo"
+	"xelf.org/daql/gen/genjson"
+	"xelf.org/daql/gen/gensql"
 	"xelf.org/daql/mig"
 	"xelf.org/daql/qry"
 	"xelf.org/daql/xps/prov"
 	"xelf.org/xelf/bfr"
 	"xelf.org/xelf/exp"
+	"xelf.org/xelf/mod"
 	"xelf.org/xelf/xps"
 )
 
@@ -29,9 +37,21 @@ func Cmd(ctx *xps.CmdCtx) error {
 	case "graph":
 		return graph(ctx)
 	case "gen":
-		return genGo(ctx)
+		return genCmd(ctx)
 	case "repl":
 		return repl(ctx)
+	case "l10n":
+		return l10n(ctx)
+	case "lint":
+		return lint(ctx)
+	case "import-ddl":
+		return importDDL(ctx)
+	case "diff":
+		return diff(ctx)
+	case "docs":
+		return docs(ctx)
+	case "fmt":
+		return fmtCmd(ctx)
 	}
 	return nil
 }
@@ -96,6 +116,23 @@ func graph(ctx *xps.CmdCtx) error {
 	return b.Flush()
 }
 
+func genCmd(ctx *xps.CmdCtx) error {
+	if len(ctx.Args) > 0 {
+		switch ctx.Args[0] {
+		case "go":
+			ctx.Args = ctx.Args[1:]
+		case "jsonschema":
+			ctx.Args = ctx.Args[1:]
+			return genJSONSchema(ctx)
+		case "postgres", "sqlite":
+			d := gensql.DialectFor(ctx.Args[0])
+			ctx.Args = ctx.Args[1:]
+			return genSQL(ctx, d)
+		}
+	}
+	return genGo(ctx)
+}
+
 func genGo(ctx *xps.CmdCtx) error {
 	pr, ss, err := daql.LoadProjectSchemas(ctx.Dir, ctx.Args)
 	if err != nil {
@@ -120,3 +157,284 @@ func genGo(ctx *xps.CmdCtx) error {
 	}
 	return nil
 }
+
+func genJSONSchema(ctx *xps.CmdCtx) error {
+	pr, ss, err := daql.LoadProjectSchemas(ctx.Dir, ctx.Args)
+	if err != nil {
+		return err
+	}
+	lp, ss, err := pr.Labeled(ss)
+	if err != nil {
+		return err
+	}
+	for _, s := range ss {
+		if gen.Nogen(s) {
+			continue
+		}
+		out := filepath.Join(daql.SchemaPath(pr, s), genjson.FileName(s))
+		err := genjson.WriteSchemaFile(genjson.NewGen(lp), out, s)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+	}
+	return nil
+}
+
+func genSQL(ctx *xps.CmdCtx, d gensql.Dialect) error {
+	pr, ss, err := daql.LoadProjectSchemas(ctx.Dir, ctx.Args)
+	if err != nil {
+		return err
+	}
+	for _, s := range ss {
+		if gen.Nogen(s) {
+			continue
+		}
+		out := filepath.Join(daql.SchemaPath(pr, s), gensql.FileName(d, s))
+		err := gensql.WriteSchemaFile(gensql.NewGen(pr.Project, d), out, s)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+	}
+	return nil
+}
+
+func l10n(ctx *xps.CmdCtx) error {
+	pr, err := daql.LoadProject(ctx.Dir)
+	if err != nil {
+		return err
+	}
+	langs := ctx.Args
+	if len(langs) == 0 {
+		langs, err = daql.CatalogLangs(pr)
+		if err != nil {
+			return err
+		}
+		if len(langs) == 0 {
+			return fmt.Errorf("requires list of languages or existing catalog files")
+		}
+	}
+	src := dom.NewCatalog(pr.Project)
+	for _, lang := range langs {
+		c, err := pr.ReadCatalog(lang)
+		if err != nil {
+			return err
+		}
+		added, removed := c.Sync(src)
+		out, err := pr.WriteCatalog(lang, c)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s +%d -%d\n", out, len(added), len(removed))
+		for _, k := range added {
+			fmt.Printf("  + %s\n", k)
+		}
+		for _, k := range removed {
+			fmt.Printf("  - %s\n", k)
+		}
+	}
+	return nil
+}
+
+func lint(ctx *xps.CmdCtx) error {
+	pr, err := daql.LoadProject(ctx.Dir)
+	if err != nil {
+		return err
+	}
+	var errs int
+	ds := dom.Validate(pr.Project)
+	for _, d := range ds {
+		if d.Severity == dom.SevError {
+			errs++
+		}
+		fmt.Println(d)
+	}
+	if errs > 0 {
+		return fmt.Errorf("lint found %d errors and %d warnings", errs, len(ds)-errs)
+	}
+	return nil
+}
+
+func importDDL(ctx *xps.CmdCtx) error {
+	if len(ctx.Args) == 0 {
+		return fmt.Errorf("requires a sql file")
+	}
+	f, err := os.Open(ctx.Args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	name := filepath.Base(ctx.Args[0])
+	if idx := strings.IndexByte(name, '.'); idx > 0 {
+		name = name[:idx]
+	}
+	src, err := gensql.ImportDDL(f, name)
+	if err != nil {
+		return fmt.Errorf("import %s: %v", ctx.Args[0], err)
+	}
+	out := filepath.Join(ctx.Dir, name+".xelf")
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("file %s already exists", out)
+	}
+	err = ioutil.WriteFile(out, []byte(src), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
+
+func diff(ctx *xps.CmdCtx) error {
+	pr, err := daql.LoadProject(ctx.Dir)
+	if err != nil {
+		return err
+	}
+	var asJSON bool
+	var vers []string
+	for _, arg := range ctx.Args {
+		if arg == "-json" {
+			asJSON = true
+		} else {
+			vers = append(vers, arg)
+		}
+	}
+	old, cur := pr.Project, pr.Project
+	switch len(vers) {
+	case 0:
+		lv := pr.Last().First()
+		if lv == nil {
+			return fmt.Errorf("no recorded project version")
+		}
+		old, err = recordProject(pr, lv.Vers)
+	case 1:
+		old, err = recordProject(pr, vers[0])
+	case 2:
+		old, err = recordProject(pr, vers[0])
+		if err == nil {
+			cur, err = recordProject(pr, vers[1])
+		}
+	default:
+		return fmt.Errorf("expects at most two versions")
+	}
+	if err != nil {
+		return err
+	}
+	cs := mig.DiffProjects(old, cur)
+	if asJSON {
+		if cs == nil {
+			cs = []mig.Change{}
+		}
+		raw, err := json.MarshalIndent(cs, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(raw))
+		return nil
+	}
+	for _, c := range cs {
+		fmt.Println(c)
+	}
+	return nil
+}
+
+func recordProject(pr *daql.Project, vers string) (*dom.Project, error) {
+	if !strings.HasPrefix(vers, "v") {
+		vers = "v" + vers
+	}
+	r, err := pr.History.Record(vers)
+	if err != nil {
+		return nil, fmt.Errorf("record %s: %v", vers, err)
+	}
+	return r.Project, nil
+}
+
+func docs(ctx *xps.CmdCtx) error {
+	pr, ss, err := daql.LoadProjectSchemas(ctx.Dir, ctx.Args)
+	if err != nil {
+		return err
+	}
+	lp, ss, err := pr.Labeled(ss)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(pr.Dir, "docs")
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	mf := pr.Record.Manifest
+	out := filepath.Join(dir, gendoc.IndexName)
+	err = gendoc.WriteIndexFile(gendoc.NewGen(lp), out, mf, ss)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	for _, s := range ss {
+		out = filepath.Join(dir, gendoc.FileName(s))
+		err = gendoc.WriteSchemaFile(gendoc.NewGen(lp), out, mf, s)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+	}
+	return nil
+}
+
+func fmtCmd(ctx *xps.CmdCtx) error {
+	// load the project first to only format files that pass the dom specs
+	pr, err := daql.LoadProject(ctx.Dir)
+	if err != nil {
+		return err
+	}
+	check := len(ctx.Args) > 0 && ctx.Args[0] == "-check"
+	file, err := dom.DiscoverProject(pr.Dir)
+	if err != nil {
+		return err
+	}
+	files := []string{file}
+	for _, s := range pr.Schemas {
+		v, err := s.Extra.Key("file")
+		if err != nil {
+			continue
+		}
+		loc := mod.ParseLoc(v.String())
+		if loc.Proto() != "file" {
+			continue
+		}
+		// skip schema files of imported modules outside the project
+		file := loc.Path()
+		if rel, err := filepath.Rel(pr.Dir, file); err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if !dom.ContainsStr(files, file) {
+			files = append(files, file)
+		}
+	}
+	var changed int
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		res, err := domfmt.Format(raw)
+		if err != nil {
+			return fmt.Errorf("format %s: %v", file, err)
+		}
+		if string(res) == string(raw) {
+			continue
+		}
+		changed++
+		if !check {
+			err = ioutil.WriteFile(file, res, 0644)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Println(file)
+	}
+	if check && changed > 0 {
+		return fmt.Errorf("%d files are not formatted", changed)
+	}
+	return nil
+}